@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -9,6 +10,7 @@ import (
 	"infac/internal/config"
 	"infac/internal/handlers"
 	"infac/internal/services"
+	"infac/internal/storage"
 )
 
 func main() {
@@ -18,11 +20,29 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Initialize services (now using sunatlib internally)
-	documentService := services.NewDocumentService(&cfg.Issuer)
+	// Initialize document storage
+	store, err := storage.Open(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	// Initialize one set of services per issuer (RUC)
+	tenants, err := services.NewTenantRegistry(cfg, store)
+	if err != nil {
+		log.Fatalf("Failed to initialize issuers: %v", err)
+	}
+
+	// Poll pending summary tickets until SUNAT resolves them
+	if cfg.SUNAT.PollInterval > 0 {
+		tenants.StartPolling(context.Background())
+	}
 
 	// Initialize handlers
-	documentHandler := handlers.NewDocumentHandler(documentService)
+	documentHandler := handlers.NewDocumentHandler(tenants)
+	numberingHandler := handlers.NewNumberingHandler(tenants)
+	summaryHandler := handlers.NewSummaryHandler(tenants)
+	issuerHandler := handlers.NewIssuerHandler(tenants)
+	jobHandler := handlers.NewJobHandler(tenants)
 
 	// Setup Gin router
 	r := gin.Default()
@@ -35,7 +55,7 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Issuer-RUC, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -56,6 +76,10 @@ func main() {
 
 	// Register routes
 	documentHandler.RegisterRoutes(r)
+	numberingHandler.RegisterRoutes(r)
+	summaryHandler.RegisterRoutes(r)
+	issuerHandler.RegisterRoutes(r)
+	jobHandler.RegisterRoutes(r)
 
 	// Start server
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
@@ -0,0 +1,50 @@
+// Command fakesunat levanta un billService de SUNAT simulado para desarrollo
+// local. Las respuestas se programan con un archivo JSON de reglas:
+//
+//	[
+//	  {"pattern": "*-01-F001-00000002", "behavior": {"response_code": "2800"}},
+//	  {"pattern": "*-RC-*", "behavior": {"polls": 2}},
+//	  {"pattern": "*-01-F001-00000003", "behavior": {"fault_code": "0109", "delay": "5s"}, "times": 1}
+//	]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"infac/pkg/sunat/fake"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8090", "listen address")
+	script := flag.String("script", "", "JSON file with response rules")
+	username := flag.String("username", "", "accepted SOL username (empty accepts any)")
+	password := flag.String("password", "", "accepted SOL password")
+	flag.Parse()
+
+	server := fake.NewServer()
+	server.Username = *username
+	server.Password = *password
+
+	if *script != "" {
+		data, err := os.ReadFile(*script)
+		if err != nil {
+			log.Fatalf("Failed to read script: %v", err)
+		}
+
+		var rules []fake.Rule
+		if err := json.Unmarshal(data, &rules); err != nil {
+			log.Fatalf("Failed to parse script: %v", err)
+		}
+		for _, rule := range rules {
+			server.AddRule(rule)
+		}
+		log.Printf("Loaded %d rules from %s", len(rules), *script)
+	}
+
+	log.Printf("Fake SUNAT billService listening on http://%s/ol-ti-itcpfegem-beta/billService", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
@@ -1,6 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 	"infac/internal/models"
 )
@@ -10,6 +15,14 @@ type Config struct {
 	SUNAT       SUNATConfig       `mapstructure:"sunat"`
 	Certificate CertificateConfig `mapstructure:"certificate"`
 	Issuer      models.Company    `mapstructure:"issuer"`
+	Storage     StorageConfig     `mapstructure:"storage"`
+	Numbering   NumberingConfig   `mapstructure:"numbering"`
+	Queue       QueueConfig       `mapstructure:"queue"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+
+	// Emisores adicionales. Cada uno usa sus propias credenciales,
+	// certificado y series; el ambiente y el almacenamiento son comunes.
+	Issuers []IssuerConfig `mapstructure:"issuers"`
 }
 
 type ServerConfig struct {
@@ -17,24 +30,289 @@ type ServerConfig struct {
 	Host string `mapstructure:"host"`
 }
 
+// Ambientes de SUNAT
+const (
+	EnvironmentBeta         = "beta"
+	EnvironmentHomologacion = "homologacion"
+	EnvironmentProduction   = "production"
+)
+
+var sunatEndpoints = map[string]string{
+	EnvironmentBeta:         "https://e-beta.sunat.gob.pe/ol-ti-itcpfegem-beta/billService",
+	EnvironmentHomologacion: "https://www.sunat.gob.pe/ol-ti-itcpgem-sqa/billService",
+	EnvironmentProduction:   "https://e-factura.sunat.gob.pe/ol-ti-itcpfegem/billService",
+}
+
+// SUNAT atiende getStatusCdr en un servicio de consulta aparte. Los ambientes
+// sin uno propio lo consultan en el billService.
+var sunatConsultEndpoints = map[string]string{
+	EnvironmentProduction: "https://e-factura.sunat.gob.pe/ol-it-wsconscpegem/billConsultService",
+}
+
 type SUNATConfig struct {
-	URL      string `mapstructure:"url"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
-	OSE      OSEConfig `mapstructure:"ose"`
+	Environment string `mapstructure:"environment"` // beta, homologacion, production
+	URL         string `mapstructure:"url"`         // Opcional: reemplaza el endpoint del ambiente
+	ConsultURL  string `mapstructure:"consult_url"` // Opcional: servicio de consulta de CDR
+	Username    string `mapstructure:"username"`
+	Password    string `mapstructure:"password"`
+	OSE         OSEConfig `mapstructure:"ose"`
+
+	// Reglas para enviar ciertos tipos o series por un canal distinto al
+	// predeterminado. Se aplica la primera que coincida.
+	Routes []RouteConfig `mapstructure:"routes"`
+
+	// Consulta de tickets pendientes (0 desactiva el poller)
+	PollInterval    time.Duration `mapstructure:"poll_interval"`
+	PollMaxInterval time.Duration `mapstructure:"poll_max_interval"`
+}
+
+// Endpoint devuelve el billService del ambiente configurado, o la URL
+// explícita si se indicó una (por ejemplo, el servidor simulado)
+func (c SUNATConfig) Endpoint() (string, error) {
+	endpoint, ok := sunatEndpoints[c.Environment]
+	if !ok {
+		return "", fmt.Errorf("unknown SUNAT environment %q (expected beta, homologacion or production)", c.Environment)
+	}
+	if c.URL != "" {
+		return c.URL, nil
+	}
+	return endpoint, nil
+}
+
+// ConsultEndpoint devuelve el servicio de consulta de CDR (getStatusCdr).
+// Con una URL explícita se usa esa misma, salvo que se indique consult_url.
+func (c SUNATConfig) ConsultEndpoint() string {
+	if c.ConsultURL != "" {
+		return c.ConsultURL
+	}
+	if c.URL == "" {
+		if endpoint, ok := sunatConsultEndpoints[c.Environment]; ok {
+			return endpoint
+		}
+	}
+	return ""
+}
+
+// Validate comprueba que la configuración permita conectarse a SUNAT y, si
+// está habilitado, al OSE
+func (c SUNATConfig) Validate() error {
+	if _, err := c.Endpoint(); err != nil {
+		return err
+	}
+	if c.Username == "" || c.Password == "" {
+		return fmt.Errorf("SUNAT SOL username and password are required")
+	}
+	if c.OSE.Enabled {
+		if c.OSE.Provider == "" {
+			return fmt.Errorf("sunat.ose.provider is required when the OSE is enabled")
+		}
+		if c.OSE.Username == "" || c.OSE.Password == "" {
+			return fmt.Errorf("OSE username and password are required")
+		}
+	}
+	for i, route := range c.Routes {
+		switch route.Channel {
+		case ChannelSUNAT:
+		case ChannelOSE:
+			if !c.OSE.Enabled {
+				return fmt.Errorf("sunat.routes[%d] uses the OSE channel but the OSE is not enabled", i)
+			}
+		default:
+			return fmt.Errorf("sunat.routes[%d]: unknown channel %q (expected sunat or ose)", i, route.Channel)
+		}
+	}
+	return nil
+}
+
+// DefaultChannel es el canal de los documentos que no coinciden con ninguna
+// regla: el OSE si está habilitado, SUNAT en caso contrario
+func (c SUNATConfig) DefaultChannel() string {
+	if c.OSE.Enabled {
+		return ChannelOSE
+	}
+	return ChannelSUNAT
 }
 
+// Canales de envío
+const (
+	ChannelSUNAT = "sunat"
+	ChannelOSE   = "ose"
+)
+
+// OSEConfig configura el Operador de Servicios Electrónicos. Provider
+// selecciona el adaptador (nubefact, efact, bizlinks o generic); URL es la
+// dirección base del proveedor o su billService completo.
 type OSEConfig struct {
-	Enabled  bool   `mapstructure:"enabled"`
-	Provider string `mapstructure:"provider"`
-	URL      string `mapstructure:"url"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
+	Enabled  bool   `mapstructure:"enabled" json:"enabled"`
+	Provider string `mapstructure:"provider" json:"provider"`
+	URL      string `mapstructure:"url" json:"url"`
+	Username string `mapstructure:"username" json:"username"`
+	Password string `mapstructure:"password" json:"password"`
+}
+
+// RouteConfig envía por Channel los documentos de los tipos y series
+// indicados. Una lista vacía coincide con todos. Los resúmenes (RA, RC) van
+// por el canal de los documentos que informan.
+type RouteConfig struct {
+	DocumentTypes []string `mapstructure:"document_types"`
+	Series        []string `mapstructure:"series"`
+	Channel       string   `mapstructure:"channel"`
+}
+
+// Matches indica si la regla aplica al tipo y serie dados
+func (r RouteConfig) Matches(documentType, serie string) bool {
+	return matchesAny(r.DocumentTypes, documentType) && matchesAny(r.Series, serie)
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 type CertificateConfig struct {
-	PFXPath  string `mapstructure:"pfx_path"`
-	Password string `mapstructure:"password"`
+	PFXPath  string `mapstructure:"pfx_path" json:"pfx_path"`
+	Password string `mapstructure:"password" json:"password"`
+	TempDir  string `mapstructure:"temp_dir" json:"temp_dir,omitempty"` // PEM extraídos del PFX para firmar
+}
+
+// IssuerConfig registra un emisor: sus datos, credenciales SOL, certificado,
+// establecimientos y series. Se carga desde issuers en la configuración o
+// desde el almacenamiento.
+type IssuerConfig struct {
+	Company  models.Company `mapstructure:"company" json:"company"`
+	Username string         `mapstructure:"username" json:"username"` // Usuario SOL sin el RUC
+	Password string         `mapstructure:"password" json:"password"`
+	// OSE propio del emisor; si no se indica se usa sunat.ose
+	OSE            *OSEConfig            `mapstructure:"ose" json:"ose,omitempty"`
+	Certificate    CertificateConfig     `mapstructure:"certificate" json:"certificate"`
+	Establishments []EstablishmentConfig `mapstructure:"establishments" json:"establishments,omitempty"`
+	Series         []SeriesConfig        `mapstructure:"series" json:"series,omitempty"`
+}
+
+// RUC identifica al emisor
+func (c IssuerConfig) RUC() string {
+	return c.Company.DocumentNumber
+}
+
+// Validate comprueba los datos mínimos para emitir con este RUC
+func (c IssuerConfig) Validate() error {
+	if len(c.RUC()) != 11 {
+		return fmt.Errorf("issuer RUC must have 11 digits (got %q)", c.RUC())
+	}
+	if c.Company.Name == "" {
+		return fmt.Errorf("issuer %s: company name is required", c.RUC())
+	}
+	if c.Username == "" || c.Password == "" {
+		return fmt.Errorf("issuer %s: SOL username and password are required", c.RUC())
+	}
+	if c.Certificate.PFXPath == "" {
+		return fmt.Errorf("issuer %s: certificate.pfx_path is required", c.RUC())
+	}
+	seen := make(map[string]bool)
+	for _, establishment := range c.Establishments {
+		if establishment.Code == "" {
+			return fmt.Errorf("issuer %s: establishment code is required", c.RUC())
+		}
+		if seen[establishment.Code] {
+			return fmt.Errorf("issuer %s: establishment %s is duplicated", c.RUC(), establishment.Code)
+		}
+		seen[establishment.Code] = true
+	}
+	return nil
+}
+
+// SUNAT devuelve la configuración de envío del emisor: el ambiente y las
+// reglas comunes con sus propias credenciales
+func (c IssuerConfig) SUNAT(base SUNATConfig) SUNATConfig {
+	base.Username = c.Username
+	base.Password = c.Password
+	if c.OSE != nil {
+		base.OSE = *c.OSE
+	}
+	return base
+}
+
+// EstablishmentConfig es un local anexo del emisor (código SUNAT y
+// dirección). Los campos vacíos toman los datos de la empresa.
+type EstablishmentConfig struct {
+	Code       string `mapstructure:"code" json:"code"`
+	Address    string `mapstructure:"address" json:"address,omitempty"`
+	District   string `mapstructure:"district" json:"district,omitempty"`
+	Province   string `mapstructure:"province" json:"province,omitempty"`
+	Department string `mapstructure:"department" json:"department,omitempty"`
+}
+
+type StorageConfig struct {
+	Type   string              `mapstructure:"type"` // json, sqlite
+	JSON   JSONStorageConfig   `mapstructure:"json"`
+	SQLite SQLiteStorageConfig `mapstructure:"sqlite"`
+}
+
+type JSONStorageConfig struct {
+	Path          string `mapstructure:"path"`
+	SequencesPath string `mapstructure:"sequences_path"`
+	TicketsPath   string `mapstructure:"tickets_path"`
+	IssuersPath   string `mapstructure:"issuers_path"`
+	JobsPath      string `mapstructure:"jobs_path"`
+
+	IdempotencyPath string `mapstructure:"idempotency_path"`
+}
+
+type SQLiteStorageConfig struct {
+	Path string `mapstructure:"path"`
+}
+
+// ForIssuer devuelve las rutas del emisor: cada archivo o directorio se
+// ubica en un subdirectorio con el RUC (storage/documents pasa a
+// storage/<RUC>/documents). El registro de emisores es común.
+func (c StorageConfig) ForIssuer(ruc string) StorageConfig {
+	partition := func(path string) string {
+		return filepath.Join(filepath.Dir(path), ruc, filepath.Base(path))
+	}
+
+	c.JSON.Path = partition(c.JSON.Path)
+	c.JSON.SequencesPath = partition(c.JSON.SequencesPath)
+	c.JSON.TicketsPath = partition(c.JSON.TicketsPath)
+	c.JSON.JobsPath = partition(c.JSON.JobsPath)
+	c.JSON.IdempotencyPath = partition(c.JSON.IdempotencyPath)
+	c.SQLite.Path = partition(c.SQLite.Path)
+	return c
+}
+
+// QueueConfig configura el envío de documentos en segundo plano. Los errores
+// de entrega se reintentan con espera exponencial desde InitialBackoff hasta
+// MaxBackoff.
+type QueueConfig struct {
+	Workers        int           `mapstructure:"workers"`
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+}
+
+// IdempotencyConfig configura cuánto tiempo se guarda la respuesta de una
+// request con Idempotency-Key. Pasado ese tiempo la clave se puede reutilizar.
+type IdempotencyConfig struct {
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+type NumberingConfig struct {
+	Series []SeriesConfig `mapstructure:"series"`
+}
+
+// SeriesConfig asigna una serie a un tipo de documento en un establecimiento.
+// La primera serie configurada para un tipo y establecimiento es la que se usa
+// cuando el request no indica serie.
+type SeriesConfig struct {
+	EstablishmentCode string `mapstructure:"establishment_code" json:"establishment_code"`
+	DocumentType      string `mapstructure:"document_type" json:"document_type"`
+	Serie             string `mapstructure:"serie" json:"serie"`
 }
 
 func Load() (*Config, error) {
@@ -46,11 +324,28 @@ func Load() (*Config, error) {
 	// Set defaults
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.host", "localhost")
-	viper.SetDefault("sunat.url", "https://e-beta.sunat.gob.pe/ol-ti-itcpfegem-beta/billService")
+	viper.SetDefault("sunat.environment", EnvironmentBeta)
+	viper.SetDefault("certificate.temp_dir", "pkg/signature/temp")
 	viper.SetDefault("sunat.ose.enabled", false)
+	viper.SetDefault("sunat.poll_interval", "30s")
+	viper.SetDefault("sunat.poll_max_interval", "10m")
+	viper.SetDefault("queue.workers", 4)
+	viper.SetDefault("queue.max_attempts", 5)
+	viper.SetDefault("queue.initial_backoff", "10s")
+	viper.SetDefault("queue.max_backoff", "10m")
+	viper.SetDefault("idempotency.ttl", "24h")
+	viper.SetDefault("storage.type", "json")
+	viper.SetDefault("storage.json.path", "storage/documents")
+	viper.SetDefault("storage.json.sequences_path", "storage/sequences.json")
+	viper.SetDefault("storage.json.tickets_path", "storage/tickets")
+	viper.SetDefault("storage.json.issuers_path", "storage/issuers.json")
+	viper.SetDefault("storage.json.jobs_path", "storage/jobs")
+	viper.SetDefault("storage.json.idempotency_path", "storage/idempotency")
+	viper.SetDefault("storage.sqlite.path", "storage/infac.db")
 	
 	// Environment variables
 	viper.SetEnvPrefix("INFAC")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_")) // INFAC_SUNAT_PASSWORD
 	viper.AutomaticEnv()
 	
 	if err := viper.ReadInConfig(); err != nil {
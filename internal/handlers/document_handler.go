@@ -1,21 +1,33 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	
 	"infac/internal/models"
 	"infac/internal/services"
+	"infac/internal/storage"
+	"infac/pkg/sunat"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
 )
 
 type DocumentHandler struct {
-	documentService *services.DocumentService
+	tenants *services.TenantRegistry
 }
 
-func NewDocumentHandler(documentService *services.DocumentService) *DocumentHandler {
+func NewDocumentHandler(tenants *services.TenantRegistry) *DocumentHandler {
 	return &DocumentHandler{
-		documentService: documentService,
+		tenants: tenants,
 	}
 }
 
@@ -26,25 +38,181 @@ func (h *DocumentHandler) CreateDocument(c *gin.Context) {
 		return
 	}
 
-	doc, err := h.documentService.CreateDocument(&req)
+	doc, err := tenantFrom(c).Documents.CreateDocument(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusBadRequest)
 		return
 	}
 
 	c.JSON(http.StatusCreated, doc)
 }
 
+func (h *DocumentHandler) GetDocument(c *gin.Context) {
+	doc, err := tenantFrom(c).Documents.GetDocument(c.Param("id"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
+
+func (h *DocumentHandler) ListDocuments(c *gin.Context) {
+	filter := storage.DocumentFilter{
+		Status:      models.DocumentStatus(c.Query("status")),
+		Type:        models.DocumentType(c.Query("type")),
+		Serie:       c.Query("serie"),
+		CustomerRUC: c.Query("customer_ruc"),
+		Limit:       defaultPageSize,
+	}
+
+	if value := c.Query("from_date"); value != "" {
+		from, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from_date format, expected YYYY-MM-DD"})
+			return
+		}
+		filter.From = &from
+	}
+
+	if value := c.Query("to_date"); value != "" {
+		to, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to_date format, expected YYYY-MM-DD"})
+			return
+		}
+		// Incluir todo el día indicado
+		to = to.Add(24*time.Hour - time.Nanosecond)
+		filter.To = &to
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := storage.DecodeCursor(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.After = cursor
+	}
+
+	docs, nextCursor, err := tenantFrom(c).Documents.ListDocuments(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if docs == nil {
+		docs = []*models.Document{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"documents":   docs,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
+	var req models.CreateDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc, err := tenantFrom(c).Documents.UpdateDocument(c.Param("id"), &req)
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
+
+func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
+	if err := tenantFrom(c).Documents.DeleteDocument(c.Param("id")); err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SendDocument envía un documento almacenado identificado por la ruta
 func (h *DocumentHandler) SendDocument(c *gin.Context) {
-	var doc models.Document
-	if err := c.ShouldBindJSON(&doc); err != nil {
+	h.sendDocument(c, c.Param("id"))
+}
+
+// SendDocumentByBody mantiene compatibilidad con POST /documents/send.
+// Del cuerpo solo se toma la identificación del documento; los importes
+// siempre salen del documento almacenado.
+func (h *DocumentHandler) SendDocumentByBody(c *gin.Context) {
+	var ref struct {
+		ID     string `json:"id"`
+		Serie  string `json:"serie"`
+		Number string `json:"number"`
+	}
+	if err := c.ShouldBindJSON(&ref); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err := h.documentService.SendDocument(&doc)
+	id := ref.ID
+	if id == "" && ref.Serie != "" && ref.Number != "" {
+		id = models.DocumentID(ref.Serie, ref.Number)
+	}
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id or serie and number are required"})
+		return
+	}
+
+	h.sendDocument(c, id)
+}
+
+// sendDocument encola el envío y responde 202 con el job, que se consulta en
+// /api/v1/jobs/:id. Con ?sync=true se espera la respuesta de SUNAT.
+func (h *DocumentHandler) sendDocument(c *gin.Context, id string) {
+	if sync, _ := strconv.ParseBool(c.Query("sync")); !sync {
+		job, err := tenantFrom(c).Queue.Enqueue(id)
+		if err != nil {
+			respondError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	doc, err := tenantFrom(c).Queue.SendNow(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status, body := errorResponse(err, http.StatusInternalServerError)
+		if doc != nil {
+			body["document"] = doc
+		}
+		c.JSON(status, body)
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
+
+// CheckCDR vuelve a consultar el CDR de un documento enviado cuyo CDR no se
+// pudo leer o no se pudo obtener
+func (h *DocumentHandler) CheckCDR(c *gin.Context) {
+	doc, err := tenantFrom(c).Documents.CheckCDR(c.Param("id"))
+	if err != nil {
+		status, body := errorResponse(err, http.StatusInternalServerError)
+		if doc != nil {
+			body["document"] = doc
+		}
+		c.JSON(status, body)
 		return
 	}
 
@@ -58,40 +226,111 @@ func (h *DocumentHandler) CheckStatus(c *gin.Context) {
 		return
 	}
 
-	cdr, err := h.documentService.CheckStatus(ticket)
+	result, err := tenantFrom(c).Documents.CheckStatus(ticket)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, http.StatusInternalServerError)
 		return
 	}
 
-	c.JSON(http.StatusOK, cdr)
+	c.JSON(http.StatusOK, result)
 }
 
+// VoidDocument envía la comunicación de baja. Acepta un documento o una
+// lista de documentos emitidos el mismo día.
 func (h *DocumentHandler) VoidDocument(c *gin.Context) {
-	var req models.VoidDocumentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	body, err := c.GetRawData()
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err := h.documentService.VoidDocument(&req)
+	var reqs []models.VoidDocumentRequest
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = binding.JSON.BindBody(body, &reqs)
+	} else {
+		var req models.VoidDocumentRequest
+		err = binding.JSON.BindBody(body, &req)
+		reqs = append(reqs, req)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Document voided successfully"})
+	ticket, err := tenantFrom(c).Documents.VoidDocuments(reqs)
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, ticket)
 }
 
 func (h *DocumentHandler) RegisterRoutes(r *gin.Engine) {
-	api := r.Group("/api/v1")
+	api := r.Group("/api/v1", TenantMiddleware(h.tenants))
 	{
 		documents := api.Group("/documents")
 		{
-			documents.POST("", h.CreateDocument)
-			documents.POST("/send", h.SendDocument)
+			documents.POST("", IdempotencyMiddleware(), h.CreateDocument)
+			documents.GET("", h.ListDocuments)
+			documents.POST("/send", IdempotencyMiddleware(), h.SendDocumentByBody)
 			documents.POST("/void", h.VoidDocument)
 			documents.GET("/status/:ticket", h.CheckStatus)
+			documents.GET("/:id", h.GetDocument)
+			documents.PUT("/:id", h.UpdateDocument)
+			documents.DELETE("/:id", h.DeleteDocument)
+			documents.POST("/:id/send", IdempotencyMiddleware(), h.SendDocument)
+			documents.POST("/:id/cdr", h.CheckCDR)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// respondError traduce los errores conocidos del servicio a su código HTTP;
+// el resto se responde con fallbackStatus
+func respondError(c *gin.Context, err error, fallbackStatus int) {
+	status, body := errorResponse(err, fallbackStatus)
+	c.JSON(status, body)
+}
+
+// errorResponse devuelve el código HTTP y el cuerpo de la respuesta de error.
+// Las respuestas de SUNAT incluyen el código de retorno, el mensaje y una
+// sugerencia de qué corregir.
+func errorResponse(err error, fallbackStatus int) (int, gin.H) {
+	var (
+		transitionErr *models.TransitionError
+		sunatErr      *services.SUNATError
+	)
+
+	body := gin.H{"error": err.Error()}
+	if errors.As(err, &sunatErr) {
+		body["code"] = sunatErr.Code.Code
+		body["message"] = sunatErr.Message
+		body["hint"] = sunatErr.Hint()
+		body["category"] = sunatErr.Category
+		if sunatErr.Field != "" {
+			body["field"] = sunatErr.Field
+		}
+	}
+
+	switch {
+	case errors.Is(err, services.ErrDocumentNotFound), errors.Is(err, storage.ErrTicketNotFound),
+		errors.Is(err, services.ErrJobNotFound):
+		return http.StatusNotFound, body
+	case errors.Is(err, services.ErrDocumentNotDraft), errors.Is(err, services.ErrDocumentNotVoidable),
+		errors.Is(err, services.ErrDocumentNotSent), errors.Is(err, services.ErrDocumentBusy), errors.As(err, &transitionErr),
+		errors.Is(err, services.ErrDocumentExists), errors.Is(err, services.ErrPrepaymentUnavailable):
+		return http.StatusConflict, body
+	case errors.Is(err, services.ErrStorage):
+		return http.StatusInternalServerError, body
+	case errors.Is(err, services.ErrDeliveryFailed):
+		return http.StatusServiceUnavailable, body
+	case sunatErr != nil && sunatErr.Category != sunat.CategoryException:
+		// El comprobante o resumen fue rechazado por su contenido
+		return http.StatusUnprocessableEntity, body
+	case sunatErr != nil, errors.Is(err, services.ErrSummaryNotSent), errors.Is(err, services.ErrStatusNotAvailable),
+		errors.Is(err, services.ErrCDRNotReadable), errors.Is(err, services.ErrCDRPending):
+		return http.StatusBadGateway, body
+	default:
+		return fallbackStatus, body
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"infac/internal/services"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"validation", errors.New("line 1: quantity must be positive"), http.StatusBadRequest},
+		{"not found", fmt.Errorf("advance: %w", services.ErrDocumentNotFound), http.StatusNotFound},
+		{"already exists", fmt.Errorf("%w: F001-00000001", services.ErrDocumentExists), http.StatusConflict},
+		{"busy", services.ErrDocumentBusy, http.StatusConflict},
+		{"storage", fmt.Errorf("%w: failed to save document: %w", services.ErrStorage, errors.New("database is locked")), http.StatusInternalServerError},
+		{"delivery", fmt.Errorf("%w: timeout", services.ErrDeliveryFailed), http.StatusServiceUnavailable},
+		{"CDR pending", fmt.Errorf("%w: CDR of duplicate document F001-00000001 is not available", services.ErrCDRPending), http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		status, body := errorResponse(tt.err, http.StatusBadRequest)
+		if status != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.want)
+		}
+		if body["error"] != tt.err.Error() {
+			t.Errorf("%s: error = %v, want %q", tt.name, body["error"], tt.err.Error())
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"infac/internal/config"
+	"infac/internal/models"
+	"infac/internal/services"
+)
+
+type IssuerHandler struct {
+	tenants *services.TenantRegistry
+}
+
+func NewIssuerHandler(tenants *services.TenantRegistry) *IssuerHandler {
+	return &IssuerHandler{
+		tenants: tenants,
+	}
+}
+
+// IssuerResponse es el emisor sin sus contraseñas
+type IssuerResponse struct {
+	Company        models.Company               `json:"company"`
+	Username       string                       `json:"username"`
+	OSEProvider    string                       `json:"ose_provider,omitempty"`
+	PFXPath        string                       `json:"pfx_path"`
+	Establishments []config.EstablishmentConfig `json:"establishments,omitempty"`
+	Series         []config.SeriesConfig        `json:"series,omitempty"`
+	Source         string                       `json:"source"` // config, api
+}
+
+func newIssuerResponse(tenant *services.Tenant) IssuerResponse {
+	response := IssuerResponse{
+		Company:        tenant.Issuer.Company,
+		Username:       tenant.Issuer.Username,
+		PFXPath:        tenant.Issuer.Certificate.PFXPath,
+		Establishments: tenant.Issuer.Establishments,
+		Series:         tenant.Issuer.Series,
+		Source:         "api",
+	}
+	if tenant.Issuer.OSE != nil && tenant.Issuer.OSE.Enabled {
+		response.OSEProvider = tenant.Issuer.OSE.Provider
+	}
+	if tenant.Configured {
+		response.Source = "config"
+	}
+	return response
+}
+
+func (h *IssuerHandler) ListIssuers(c *gin.Context) {
+	issuers := []IssuerResponse{}
+	for _, tenant := range h.tenants.List() {
+		issuers = append(issuers, newIssuerResponse(tenant))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"issuers": issuers})
+}
+
+func (h *IssuerHandler) GetIssuer(c *gin.Context) {
+	tenant, err := h.tenants.Resolve(c.Param("ruc"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newIssuerResponse(tenant))
+}
+
+// RegisterIssuer crea o reemplaza un emisor. El certificado debe estar en el
+// servidor, en la ruta indicada en certificate.pfx_path.
+func (h *IssuerHandler) RegisterIssuer(c *gin.Context) {
+	var req config.IssuerConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenant, err := h.tenants.Register(req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrTenantExists) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newIssuerResponse(tenant))
+}
+
+func (h *IssuerHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api/v1")
+	{
+		issuers := api.Group("/issuers")
+		{
+			issuers.GET("", h.ListIssuers)
+			issuers.POST("", h.RegisterIssuer)
+			issuers.GET("/:ruc", h.GetIssuer)
+		}
+	}
+}
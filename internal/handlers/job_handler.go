@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"infac/internal/models"
+	"infac/internal/services"
+)
+
+type JobHandler struct {
+	tenants *services.TenantRegistry
+}
+
+func NewJobHandler(tenants *services.TenantRegistry) *JobHandler {
+	return &JobHandler{
+		tenants: tenants,
+	}
+}
+
+// ListJobs devuelve los envíos en cola; ?status= filtra por estado
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	jobs, err := tenantFrom(c).Queue.ListJobs(models.JobStatus(c.Query("status")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if jobs == nil {
+		jobs = []*models.SendJob{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, err := tenantFrom(c).Queue.GetJob(c.Param("id"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *JobHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api/v1", TenantMiddleware(h.tenants))
+	{
+		jobs := api.Group("/jobs")
+		{
+			jobs.GET("", h.ListJobs)
+			jobs.GET("/:id", h.GetJob)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"infac/internal/models"
+	"infac/internal/services"
+)
+
+type NumberingHandler struct {
+	tenants *services.TenantRegistry
+}
+
+func NewNumberingHandler(tenants *services.TenantRegistry) *NumberingHandler {
+	return &NumberingHandler{
+		tenants: tenants,
+	}
+}
+
+func (h *NumberingHandler) ListSeries(c *gin.Context) {
+	series, err := tenantFrom(c).Numbering.Series()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if series == nil {
+		series = []services.SerieStatus{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"series": series})
+}
+
+func (h *NumberingHandler) Gaps(c *gin.Context) {
+	docType := c.Query("type")
+	serie := c.Query("serie")
+	if docType == "" || serie == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type and serie parameters are required"})
+		return
+	}
+
+	status, err := tenantFrom(c).Numbering.Gaps(models.DocumentType(docType), serie)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *NumberingHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api/v1", TenantMiddleware(h.tenants))
+	{
+		numbering := api.Group("/numbering")
+		{
+			numbering.GET("/series", h.ListSeries)
+			numbering.GET("/gaps", h.Gaps)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"infac/internal/models"
+	"infac/internal/services"
+)
+
+type SummaryHandler struct {
+	tenants *services.TenantRegistry
+}
+
+func NewSummaryHandler(tenants *services.TenantRegistry) *SummaryHandler {
+	return &SummaryHandler{
+		tenants: tenants,
+	}
+}
+
+// SendDailySummary genera y envía el Resumen Diario de una fecha
+func (h *SummaryHandler) SendDailySummary(c *gin.Context) {
+	var req models.DailySummaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ticket, err := tenantFrom(c).Documents.SendDailySummary(&req)
+	if err != nil {
+		respondError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, ticket)
+}
+
+func (h *SummaryHandler) ListTickets(c *gin.Context) {
+	tickets, err := tenantFrom(c).Documents.ListTickets(models.TicketStatus(c.Query("status")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if tickets == nil {
+		tickets = []*models.Ticket{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tickets": tickets})
+}
+
+func (h *SummaryHandler) GetTicket(c *gin.Context) {
+	ticket, err := tenantFrom(c).Documents.GetTicket(c.Param("ticket"))
+	if err != nil {
+		respondError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+func (h *SummaryHandler) RegisterRoutes(r *gin.Engine) {
+	api := r.Group("/api/v1", TenantMiddleware(h.tenants))
+	{
+		summaries := api.Group("/summaries")
+		{
+			summaries.GET("", h.ListTickets)
+			summaries.POST("/daily", h.SendDailySummary)
+			summaries.GET("/:ticket", h.GetTicket)
+		}
+	}
+}
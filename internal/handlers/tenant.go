@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"infac/internal/services"
+)
+
+// IssuerHeader selecciona el emisor de la operación. También se acepta el
+// parámetro ?ruc=; sin ninguno se usa el único emisor registrado.
+const IssuerHeader = "X-Issuer-RUC"
+
+const tenantKey = "tenant"
+
+// TenantMiddleware resuelve el emisor de la request y lo deja en el contexto
+func TenantMiddleware(tenants *services.TenantRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ruc := c.GetHeader(IssuerHeader)
+		if ruc == "" {
+			ruc = c.Query("ruc")
+		}
+
+		tenant, err := tenants.Resolve(ruc)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, services.ErrTenantNotFound) {
+				status = http.StatusNotFound
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(tenantKey, tenant)
+		c.Header(IssuerHeader, tenant.RUC())
+		c.Next()
+	}
+}
+
+// tenantFrom devuelve el emisor resuelto por TenantMiddleware
+func tenantFrom(c *gin.Context) *services.Tenant {
+	return c.MustGet(tenantKey).(*services.Tenant)
+}
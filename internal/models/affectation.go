@@ -0,0 +1,64 @@
+package models
+
+import "infac/pkg/decimal"
+
+// IGVAffectation es el tipo de afectación al IGV de una línea (catálogo 07)
+type IGVAffectation string
+
+const (
+	AffectationTaxed      IGVAffectation = "10" // Gravado - Operación Onerosa
+	AffectationIVAP       IGVAffectation = "17" // Gravado - IVAP (arroz pilado)
+	AffectationExonerated IGVAffectation = "20" // Exonerado - Operación Onerosa
+	AffectationUnaffected IGVAffectation = "30" // Inafecto - Operación Onerosa
+	AffectationExport     IGVAffectation = "40" // Exportación de Bienes o Servicios
+)
+
+var igvAffectations = map[IGVAffectation]string{
+	"10": "Gravado - Operación Onerosa",
+	"11": "Gravado - Retiro por premio",
+	"12": "Gravado - Retiro por donación",
+	"13": "Gravado - Retiro",
+	"14": "Gravado - Retiro por publicidad",
+	"15": "Gravado - Bonificaciones",
+	"16": "Gravado - Retiro por entrega a trabajadores",
+	"17": "Gravado - IVAP",
+	"20": "Exonerado - Operación Onerosa",
+	"21": "Exonerado - Transferencia gratuita",
+	"30": "Inafecto - Operación Onerosa",
+	"31": "Inafecto - Retiro por bonificación",
+	"32": "Inafecto - Retiro",
+	"33": "Inafecto - Retiro por muestras médicas",
+	"34": "Inafecto - Retiro por convenio colectivo",
+	"35": "Inafecto - Retiro por premio",
+	"36": "Inafecto - Retiro por publicidad",
+	"37": "Inafecto - Transferencia gratuita",
+	"40": "Exportación de Bienes o Servicios",
+}
+
+// Valid indica si el código está en el catálogo 07
+func (a IGVAffectation) Valid() bool {
+	_, ok := igvAffectations[a]
+	return ok
+}
+
+func (a IGVAffectation) Description() string {
+	return igvAffectations[a]
+}
+
+// IVAPRate es la tasa del Impuesto a la Venta del Arroz Pilado, que grava
+// las líneas 17 en lugar del IGV
+var IVAPRate = decimal.NewFromInt(4)
+
+// Free indica una transferencia gratuita: la línea se informa con su valor
+// referencial, que no forma parte del importe a pagar. Las ventas con IVAP
+// (17) son onerosas.
+func (a IGVAffectation) Free() bool {
+	return a.Valid() && a != AffectationTaxed && a != AffectationIVAP && a != AffectationExonerated &&
+		a != AffectationUnaffected && a != AffectationExport
+}
+
+// Taxed indica que la línea está gravada con IGV (10 a 17). En los retiros
+// gratuitos el IGV se calcula e informa, pero no se cobra al adquirente.
+func (a IGVAffectation) Taxed() bool {
+	return a >= "10" && a <= "17"
+}
@@ -0,0 +1,147 @@
+package models
+
+import "infac/pkg/decimal"
+
+// AllowanceCharge es un descuento o un cargo de la línea o del documento
+// (catálogo 53). El importe se indica directamente o como Factor de la base
+// (0.10 es el 10%); sin base se usa el valor de venta de la línea o del
+// documento.
+type AllowanceCharge struct {
+	Code       AllowanceChargeCode `json:"code"`
+	Factor     *decimal.Decimal    `json:"factor,omitempty"`
+	Amount     decimal.Decimal     `json:"amount"`
+	BaseAmount decimal.Decimal     `json:"base_amount"`
+}
+
+// AllowanceChargeCode es el código de descuento o cargo (catálogo 53)
+type AllowanceChargeCode string
+
+const (
+	DiscountLineAffectsBase   AllowanceChargeCode = "00"
+	DiscountLine              AllowanceChargeCode = "01"
+	DiscountGlobalAffectsBase AllowanceChargeCode = "02"
+	DiscountGlobal            AllowanceChargeCode = "03"
+	AdvanceTaxed              AllowanceChargeCode = "04"
+	AdvanceExonerated         AllowanceChargeCode = "05"
+	AdvanceUnaffected         AllowanceChargeCode = "06"
+	ChargeLineAffectsBase     AllowanceChargeCode = "47"
+	ChargeLine                AllowanceChargeCode = "48"
+	ChargeGlobalAffectsBase   AllowanceChargeCode = "49"
+	ChargeGlobal              AllowanceChargeCode = "50"
+	PerceptionInternalSale    AllowanceChargeCode = "51"
+	PerceptionFuel            AllowanceChargeCode = "52"
+	PerceptionSpecialRate     AllowanceChargeCode = "53"
+	RetentionIGV              AllowanceChargeCode = "62"
+)
+
+// RetentionMinimumAmount es el importe total a partir del cual el agente de
+// retención retiene el IGV
+var RetentionMinimumAmount = decimal.NewFromInt(700)
+
+// allowanceChargeType describe cómo se aplica un código del catálogo 53
+type allowanceChargeType struct {
+	Description string
+	Charge      bool
+	Line        bool
+	// Afectación cuya base imponible modifica; vacío si no la modifica
+	Affectation IGVAffectation
+	// Informative indica que no suma a los descuentos ni a los cargos del
+	// importe a pagar. Se calcula sobre el importe total con la tasa Factor,
+	// salvo que el request indique otra.
+	Informative bool
+	Factor      decimal.Decimal
+}
+
+var allowanceChargeTypes = map[AllowanceChargeCode]allowanceChargeType{
+	"00": {Description: "Descuentos que afectan la base imponible del IGV/IVAP", Line: true, Affectation: AffectationTaxed},
+	"01": {Description: "Descuentos que no afectan la base imponible del IGV/IVAP", Line: true},
+	"02": {Description: "Descuentos globales que afectan la base imponible del IGV/IVAP", Affectation: AffectationTaxed},
+	"03": {Description: "Descuentos globales que no afectan la base imponible del IGV/IVAP"},
+	"04": {Description: "Descuentos globales por anticipos gravados que afectan la base imponible del IGV/IVAP", Affectation: AffectationTaxed},
+	"05": {Description: "Descuentos globales por anticipos exonerados", Affectation: AffectationExonerated},
+	"06": {Description: "Descuentos globales por anticipos inafectos", Affectation: AffectationUnaffected},
+	"45": {Description: "FISE", Charge: true},
+	"46": {Description: "Recargo al consumo y/o propinas", Charge: true},
+	"47": {Description: "Cargos que afectan la base imponible del IGV/IVAP", Charge: true, Line: true, Affectation: AffectationTaxed},
+	"48": {Description: "Cargos que no afectan la base imponible del IGV/IVAP", Charge: true, Line: true},
+	"49": {Description: "Cargos globales que afectan la base imponible del IGV/IVAP", Charge: true, Affectation: AffectationTaxed},
+	"50": {Description: "Cargos globales que no afectan la base imponible del IGV/IVAP", Charge: true},
+	"51": {Description: "Percepción venta interna", Charge: true, Informative: true, Factor: decimal.MustParse("0.02")},
+	"52": {Description: "Percepción a la adquisición de combustible", Charge: true, Informative: true, Factor: decimal.MustParse("0.01")},
+	"53": {Description: "Percepción realizada al agente de percepción con tasa especial", Charge: true, Informative: true, Factor: decimal.MustParse("0.005")},
+	"62": {Description: "Retención del IGV", Informative: true, Factor: decimal.MustParse("0.03")},
+}
+
+// Valid indica si el código está en el catálogo 53
+func (c AllowanceChargeCode) Valid() bool {
+	_, ok := allowanceChargeTypes[c]
+	return ok
+}
+
+func (c AllowanceChargeCode) Description() string {
+	return allowanceChargeTypes[c].Description
+}
+
+// Charge indica un cargo; los demás códigos son descuentos
+func (c AllowanceChargeCode) Charge() bool {
+	return allowanceChargeTypes[c].Charge
+}
+
+// Line indica un código que se aplica a una línea; los demás son globales
+func (c AllowanceChargeCode) Line() bool {
+	return allowanceChargeTypes[c].Line
+}
+
+// AffectsBase indica que el descuento o cargo modifica la base imponible
+func (c AllowanceChargeCode) AffectsBase() bool {
+	return allowanceChargeTypes[c].Affectation != ""
+}
+
+// Affectation devuelve la afectación cuya base imponible modifica el código
+func (c AllowanceChargeCode) Affectation() IGVAffectation {
+	return allowanceChargeTypes[c].Affectation
+}
+
+// Informative indica un cargo o descuento que se informa sin modificar el
+// importe a pagar: la percepción y la retención
+func (c AllowanceChargeCode) Informative() bool {
+	return allowanceChargeTypes[c].Informative
+}
+
+// Factor devuelve la tasa vigente de la percepción o la retención
+func (c AllowanceChargeCode) Factor() decimal.Decimal {
+	return allowanceChargeTypes[c].Factor
+}
+
+// Perception indica un código de percepción (51 a 53)
+func (c AllowanceChargeCode) Perception() bool {
+	return c.Informative() && c.Charge()
+}
+
+// Retention devuelve la retención del IGV del documento, si la tiene
+func (d *Document) Retention() *AllowanceCharge {
+	return d.informative(func(code AllowanceChargeCode) bool { return code == RetentionIGV })
+}
+
+// Perception devuelve la percepción del documento, si la tiene
+func (d *Document) Perception() *AllowanceCharge {
+	return d.informative(AllowanceChargeCode.Perception)
+}
+
+func (d *Document) informative(match func(AllowanceChargeCode) bool) *AllowanceCharge {
+	for i := range d.AllowanceCharges {
+		if match(d.AllowanceCharges[i].Code) {
+			return &d.AllowanceCharges[i]
+		}
+	}
+	return nil
+}
+
+// Signed devuelve el importe con el signo de su efecto: negativo en los
+// descuentos
+func (ac AllowanceCharge) Signed() decimal.Decimal {
+	if ac.Code.Charge() {
+		return ac.Amount
+	}
+	return ac.Amount.Neg()
+}
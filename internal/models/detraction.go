@@ -0,0 +1,84 @@
+package models
+
+import "infac/pkg/decimal"
+
+// Detraction es la detracción (SPOT) de una operación sujeta: el porcentaje
+// del importe total que el adquirente deposita en la cuenta del emisor en el
+// Banco de la Nación. El importe está en soles.
+type Detraction struct {
+	Code    DetractionCode  `json:"code"`
+	Percent decimal.Decimal `json:"percent"`
+	Amount  decimal.Decimal `json:"amount"`
+	Account string          `json:"account"`
+	// Medio de pago (catálogo 59); 001 es depósito en cuenta
+	PaymentMeansCode string `json:"payment_means_code"`
+}
+
+// DetractionMinimumAmount es el importe total a partir del cual una operación
+// está sujeta a detracción
+var DetractionMinimumAmount = decimal.NewFromInt(700)
+
+// DetractionPaymentMeansDeposit es el depósito en cuenta (catálogo 59)
+const DetractionPaymentMeansDeposit = "001"
+
+// DetractionCode es el código de bien o servicio sujeto a detracción
+// (catálogo 54)
+type DetractionCode string
+
+type detractionCode struct {
+	Description string
+	// Porcentaje vigente, que se usa si el request no indica otro
+	Percent decimal.Decimal
+}
+
+var detractionCodes = map[DetractionCode]detractionCode{
+	"001": {"Azúcar y melaza de caña", decimal.NewFromInt(10)},
+	"003": {"Alcohol etílico", decimal.NewFromInt(10)},
+	"004": {"Recursos hidrobiológicos", decimal.NewFromInt(4)},
+	"005": {"Maíz amarillo duro", decimal.NewFromInt(4)},
+	"007": {"Caña de azúcar", decimal.NewFromInt(10)},
+	"008": {"Madera", decimal.NewFromInt(4)},
+	"009": {"Arena y piedra", decimal.NewFromInt(10)},
+	"010": {"Residuos, subproductos, desechos, recortes y desperdicios", decimal.NewFromInt(15)},
+	"011": {"Bienes gravados con el IGV, o renuncia a la exoneración", decimal.NewFromInt(10)},
+	"012": {"Intermediación laboral y tercerización", decimal.NewFromInt(12)},
+	"014": {"Carnes y despojos comestibles", decimal.NewFromInt(4)},
+	"016": {"Aceite de pescado", decimal.NewFromInt(10)},
+	"017": {"Harina, polvo y pellets de pescado, crustáceos, moluscos y demás invertebrados acuáticos", decimal.NewFromInt(4)},
+	"019": {"Arrendamiento de bienes muebles", decimal.NewFromInt(10)},
+	"020": {"Mantenimiento y reparación de bienes muebles", decimal.NewFromInt(12)},
+	"021": {"Movimiento de carga", decimal.NewFromInt(10)},
+	"022": {"Otros servicios empresariales", decimal.NewFromInt(12)},
+	"023": {"Leche", decimal.NewFromInt(4)},
+	"024": {"Comisión mercantil", decimal.NewFromInt(10)},
+	"025": {"Fabricación de bienes por encargo", decimal.NewFromInt(10)},
+	"026": {"Servicio de transporte de personas", decimal.NewFromInt(10)},
+	"027": {"Servicio de transporte de carga", decimal.NewFromInt(4)},
+	"028": {"Transporte de pasajeros", decimal.NewFromInt(10)},
+	"030": {"Contratos de construcción", decimal.NewFromInt(4)},
+	"031": {"Oro gravado con el IGV", decimal.NewFromInt(10)},
+	"032": {"Páprika y otros frutos de los géneros capsicum o pimienta", decimal.NewFromInt(10)},
+	"034": {"Minerales metálicos no auríferos", decimal.NewFromInt(10)},
+	"035": {"Bienes exonerados del IGV", decimal.MustParse("1.5")},
+	"036": {"Oro y demás minerales metálicos exonerados del IGV", decimal.MustParse("1.5")},
+	"037": {"Demás servicios gravados con el IGV", decimal.NewFromInt(12)},
+	"039": {"Minerales no metálicos", decimal.NewFromInt(10)},
+	"040": {"Bien inmueble gravado con IGV", decimal.NewFromInt(4)},
+	"041": {"Plomo", decimal.NewFromInt(15)},
+	"099": {"Ley 30737", decimal.NewFromInt(12)},
+}
+
+// Valid indica si el código está en el catálogo 54
+func (c DetractionCode) Valid() bool {
+	_, ok := detractionCodes[c]
+	return ok
+}
+
+func (c DetractionCode) Description() string {
+	return detractionCodes[c].Description
+}
+
+// Percent devuelve el porcentaje de detracción vigente del código
+func (c DetractionCode) Percent() decimal.Decimal {
+	return detractionCodes[c].Percent
+}
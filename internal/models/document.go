@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"strconv"
+	"time"
+
+	"infac/pkg/decimal"
+)
 
 type DocumentType string
 
@@ -20,24 +25,78 @@ type Document struct {
 	DueDate      *time.Time   `json:"due_date,omitempty"`
 	CurrencyCode string       `json:"currency_code"`
 	
+	// Tipo de operación (catálogo 51); vacío es venta interna
+	OperationType OperationType `json:"operation_type,omitempty"`
+	
 	Issuer   Company `json:"issuer"`
 	Customer Company `json:"customer"`
 	
 	Lines []DocumentLine `json:"lines"`
 	
-	SubTotal      float64 `json:"sub_total"`
-	TotalTaxes    float64 `json:"total_taxes"`
-	TotalAmount   float64 `json:"total_amount"`
+	// Totales con 2 decimales: la suma de los importes redondeados de las
+	// líneas. SubTotal es el valor de venta de las operaciones onerosas; las
+	// gratuitas se informan solo en TotalFree.
+	SubTotal      decimal.Decimal `json:"sub_total"`
+	TotalTaxes    decimal.Decimal `json:"total_taxes"`
+	TotalAmount   decimal.Decimal `json:"total_amount"`
+	
+	// Descuentos y cargos globales (catálogo 53). TaxExclusiveAmount es
+	// SubTotal con los que afectan la base imponible; TotalAllowances y
+	// TotalCharges suman los que no la afectan, de las líneas y globales, y
+	// se restan o suman al importe a pagar (TotalAmount).
+	AllowanceCharges   []AllowanceCharge `json:"allowance_charges,omitempty"`
+	TaxExclusiveAmount decimal.Decimal   `json:"tax_exclusive_amount"`
+	TaxInclusiveAmount decimal.Decimal   `json:"tax_inclusive_amount"`
+	TotalAllowances    decimal.Decimal   `json:"total_allowances"`
+	TotalCharges       decimal.Decimal   `json:"total_charges"`
+	
+	// Valor de venta por tipo de afectación al IGV
+	TotalTaxed      decimal.Decimal `json:"total_taxed"`
+	TotalIVAP       decimal.Decimal `json:"total_ivap"`
+	TotalExonerated decimal.Decimal `json:"total_exonerated"`
+	TotalUnaffected decimal.Decimal `json:"total_unaffected"`
+	TotalExport     decimal.Decimal `json:"total_export"`
+	TotalFree       decimal.Decimal `json:"total_free"`
 	
 	PaymentTerms *PaymentTerms `json:"payment_terms,omitempty"`
 	
+	// Detracción de las operaciones sujetas (tipos de operación 1001 a 1004)
+	Detraction *Detraction `json:"detraction,omitempty"`
+	
+	// Exportaciones: país de destino (ISO 3166-1 alfa-2) y condiciones de
+	// entrega (Incoterm) de los bienes, y tipo de cambio declarado
+	DestinationCountry string        `json:"destination_country,omitempty"`
+	Incoterm           string        `json:"incoterm,omitempty"`
+	ExchangeRate       *ExchangeRate `json:"exchange_rate,omitempty"`
+	
+	// Anticipos: Advance marca la factura o boleta emitida por un anticipo;
+	// Prepayments son los anticipos que deduce el comprobante final y
+	// TotalPrepaid su suma con impuestos, que se resta del importe a pagar
+	Advance      bool            `json:"advance,omitempty"`
+	Prepayments  []Prepayment    `json:"prepayments,omitempty"`
+	TotalPrepaid decimal.Decimal `json:"total_prepaid"`
+	
 	// Para notas de crédito/débito
 	RelatedDocuments []RelatedDocument `json:"related_documents,omitempty"`
 	
 	// Estado del documento
-	Status       DocumentStatus `json:"status"`
-	SUNATStatus  string        `json:"sunat_status,omitempty"`
-	CDR          *CDR          `json:"cdr,omitempty"`
+	Status        DocumentStatus `json:"status"`
+	StatusHistory []StatusChange `json:"status_history,omitempty"`
+	SUNATStatus   string         `json:"sunat_status,omitempty"`
+	CDR           *CDR           `json:"cdr,omitempty"`
+	
+	// Comunicación de baja en la que se solicitó la anulación
+	VoidTicket string `json:"void_ticket,omitempty"`
+	VoidReason string `json:"void_reason,omitempty"`
+	
+	// Último Resumen Diario en el que se informó el documento. Modified
+	// marca un documento ya informado que se corrigió después: se informa con
+	// estado 2 (modificar) en el siguiente resumen de su fecha.
+	SummaryTicket string `json:"summary_ticket,omitempty"`
+	Modified      bool   `json:"modified,omitempty"`
+	
+	// Canal por el que se envió: "sunat" u "ose"
+	Channel string `json:"channel,omitempty"`
 	
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -67,27 +126,57 @@ type Company struct {
 	Email               string `json:"email,omitempty" mapstructure:"email"`
 	Phone               string `json:"phone,omitempty" mapstructure:"phone"`
 	EstablishmentCode   string `json:"establishment_code,omitempty" mapstructure:"establishment_code"` // Código de local anexo
+	DetractionAccount   string `json:"detraction_account,omitempty" mapstructure:"detraction_account"` // Cuenta de detracciones en el Banco de la Nación
 }
 
+// DocumentLine guarda la cantidad y el valor unitario con hasta 10 decimales
+// y los importes de la línea redondeados a 2. TotalPrice es el valor de venta:
+// cantidad por valor unitario, con los descuentos y cargos de la línea.
 type DocumentLine struct {
-	ID               string  `json:"id"`
-	Quantity         float64 `json:"quantity"`
-	UnitCode         string  `json:"unit_code"`
-	Description      string  `json:"description"`
-	UnitPrice        float64 `json:"unit_price"`
-	TotalPrice       float64 `json:"total_price"`
-	TaxableAmount    float64 `json:"taxable_amount"`
+	ID               string          `json:"id"`
+	Quantity         decimal.Decimal `json:"quantity"`
+	UnitCode         string          `json:"unit_code"`
+	Description      string          `json:"description"`
+	UnitPrice        decimal.Decimal `json:"unit_price"`
+	TotalPrice       decimal.Decimal `json:"total_price"`
+	TaxableAmount    decimal.Decimal `json:"taxable_amount"`
+	
+	// Afectación al IGV (catálogo 07); vacío en documentos anteriores
+	// equivale a gravado
+	IGVAffectation IGVAffectation `json:"igv_affectation"`
+	
+	// Descuentos y cargos de la línea (catálogo 53). TotalPrice ya incluye
+	// los que afectan la base imponible.
+	AllowanceCharges []AllowanceCharge `json:"allowance_charges,omitempty"`
 	
 	Taxes []Tax `json:"taxes"`
 	
 	ProductCode string `json:"product_code,omitempty"`
 }
 
+// Affectation devuelve la afectación al IGV de la línea
+func (l DocumentLine) Affectation() IGVAffectation {
+	if l.IGVAffectation == "" {
+		return AffectationTaxed
+	}
+	return l.IGVAffectation
+}
+
+// Tax es un tributo de la línea. Rate es un porcentaje, salvo en el ICBPER
+// y en el ISC de monto fijo, donde es el monto por unidad.
 type Tax struct {
-	Type   TaxType `json:"type"`
-	Code   string  `json:"code"`
-	Rate   float64 `json:"rate"`
-	Amount float64 `json:"amount"`
+	Type   TaxType         `json:"type"`
+	Code   string          `json:"code"`
+	Rate   decimal.Decimal `json:"rate"`
+	Amount decimal.Decimal `json:"amount"`
+	
+	// Base imponible del tributo: el valor de venta, más el ISC en el IGV
+	TaxableAmount decimal.Decimal `json:"taxable_amount"`
+	
+	// Sistema de cálculo del ISC (catálogo 08) y, en el sistema de precios
+	// de venta al público, el precio sugerido por unidad con IGV
+	ISCSystem      ISCSystem        `json:"isc_system,omitempty"`
+	ReferencePrice *decimal.Decimal `json:"reference_price,omitempty"`
 }
 
 type TaxType string
@@ -98,10 +187,34 @@ const (
 	TaxTypeICBP TaxType = "ICBP" // Impuesto a las Bolsas de Plástico
 )
 
+// ISCSystem es el sistema de cálculo del ISC (catálogo 08)
+type ISCSystem string
+
+const (
+	ISCSystemValue       ISCSystem = "01" // Sistema al valor
+	ISCSystemFixedAmount ISCSystem = "02" // Aplicación del monto fijo
+	ISCSystemRetailPrice ISCSystem = "03" // Sistema de precios de venta al público
+)
+
+// PaymentTerms es la forma de pago. Al crédito, Amount es el importe neto
+// pendiente de pago y se paga en cuotas (Installments); DueDate es el
+// vencimiento de la última cuota.
 type PaymentTerms struct {
-	PaymentMeansCode string    `json:"payment_means_code"`
-	DueDate          time.Time `json:"due_date"`
-	Amount           float64   `json:"amount"`
+	PaymentMeansCode string          `json:"payment_means_code"`
+	DueDate          time.Time       `json:"due_date"`
+	Amount           decimal.Decimal `json:"amount"`
+	Installments     []Installment   `json:"installments,omitempty"`
+}
+
+const (
+	PaymentMeansCash   = "Contado"
+	PaymentMeansCredit = "Credito"
+)
+
+// Installment es una cuota de una venta al crédito (Cuota001, Cuota002...)
+type Installment struct {
+	Amount  decimal.Decimal `json:"amount"`
+	DueDate time.Time       `json:"due_date"`
 }
 
 type RelatedDocument struct {
@@ -114,4 +227,31 @@ type CDR struct {
 	ResponseCode string `json:"response_code"`
 	Description  string `json:"description"`
 	Notes        string `json:"notes,omitempty"`
+	
+	// Documento o resumen al que responde la constancia
+	DocumentID   string     `json:"document_id,omitempty"`
+	ResponseDate *time.Time `json:"response_date,omitempty"`
+	
+	// Observaciones (cbc:Note) de una aceptación con códigos 4000 en adelante
+	Observations []CDRObservation `json:"observations,omitempty"`
+}
+
+type CDRObservation struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// Accepted indica si el CDR corresponde a una aceptación: código 0, o un
+// código de observación (4000 en adelante)
+func (c *CDR) Accepted() bool {
+	if c.ResponseCode == "0" {
+		return true
+	}
+	code, err := strconv.Atoi(c.ResponseCode)
+	return err == nil && code >= 4000
+}
+
+// HasObservations distingue una aceptación con observaciones de una limpia
+func (c *CDR) HasObservations() bool {
+	return c.Accepted() && len(c.Observations) > 0
 }
\ No newline at end of file
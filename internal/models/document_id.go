@@ -0,0 +1,32 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Un correlativo explícito tiene de 1 a 8 dígitos; se guarda con 8
+var numberPattern = regexp.MustCompile(`^[0-9]{1,8}$`)
+
+// ValidNumber indica si number es un correlativo de 1 a 8 dígitos
+func ValidNumber(number string) bool {
+	return numberPattern.MatchString(number)
+}
+
+// NormalizeNumber completa con ceros un correlativo válido: "7" y
+// "00000007" son el mismo número. Un correlativo inválido se devuelve sin
+// cambios.
+func NormalizeNumber(number string) string {
+	if !ValidNumber(number) {
+		return number
+	}
+	value, _ := strconv.ParseInt(number, 10, 64)
+	return fmt.Sprintf("%08d", value)
+}
+
+// DocumentID arma el ID de un documento (serie-número) con el número
+// normalizado. Toda búsqueda por serie y número debe usarlo.
+func DocumentID(serie, number string) string {
+	return serie + "-" + NormalizeNumber(number)
+}
@@ -0,0 +1,50 @@
+package models
+
+import "infac/pkg/decimal"
+
+// ExchangeRate es el tipo de cambio de la moneda del documento a
+// TargetCurrencyCode: unidades de la moneda destino por unidad de la moneda
+// del documento
+type ExchangeRate struct {
+	TargetCurrencyCode string          `json:"target_currency_code"`
+	Rate               decimal.Decimal `json:"rate"`
+}
+
+// ToPEN convierte a soles un importe en la moneda del documento, con el tipo
+// de cambio declarado. Los documentos en soles no lo necesitan.
+func (d *Document) ToPEN(amount decimal.Decimal) decimal.Decimal {
+	if d.CurrencyCode == "PEN" || d.ExchangeRate == nil || d.ExchangeRate.TargetCurrencyCode != "PEN" {
+		return amount
+	}
+	return amount.Mul(d.ExchangeRate.Rate).Round(decimal.AmountPlaces)
+}
+
+// FromPEN convierte un importe en soles, como la detracción, a la moneda
+// del documento
+func (d *Document) FromPEN(amount decimal.Decimal) decimal.Decimal {
+	if d.CurrencyCode == "PEN" || d.ExchangeRate == nil || d.ExchangeRate.TargetCurrencyCode != "PEN" {
+		return amount
+	}
+	return amount.Div(d.ExchangeRate.Rate).Round(decimal.AmountPlaces)
+}
+
+// incoterms son las condiciones de entrega Incoterms 2020
+var incoterms = map[string]string{
+	"EXW": "En fábrica",
+	"FCA": "Franco transportista",
+	"FAS": "Franco al costado del buque",
+	"FOB": "Franco a bordo",
+	"CFR": "Costo y flete",
+	"CIF": "Costo, seguro y flete",
+	"CPT": "Transporte pagado hasta",
+	"CIP": "Transporte y seguro pagados hasta",
+	"DAP": "Entregado en un punto",
+	"DPU": "Entregado en un punto descargado",
+	"DDP": "Entregado con derechos pagados",
+}
+
+// ValidIncoterm indica si el código es un Incoterm 2020
+func ValidIncoterm(code string) bool {
+	_, ok := incoterms[code]
+	return ok
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// IdempotencyRecord es la respuesta guardada de una request con
+// Idempotency-Key. RequestHash identifica la request original (método, ruta
+// y cuerpo) para rechazar que la clave se reutilice en otra distinta.
+type IdempotencyRecord struct {
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	StatusCode  int       `json:"status_code"`
+	Response    []byte    `json:"response"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+// identityDocumentType describe un tipo de documento de identidad del
+// catálogo 06
+type identityDocumentType struct {
+	Description string
+	// NonDomiciled indica un documento de un sujeto no domiciliado
+	NonDomiciled bool
+}
+
+const (
+	IdentityNonDomiciled = "0"
+	IdentityDNI          = "1"
+	IdentityRUC          = "6"
+)
+
+var identityDocumentTypes = map[string]identityDocumentType{
+	"0": {Description: "Doc. trib. no dom. sin RUC", NonDomiciled: true},
+	"1": {Description: "DNI"},
+	"4": {Description: "Carnet de extranjería"},
+	"6": {Description: "RUC"},
+	"7": {Description: "Pasaporte", NonDomiciled: true},
+	"A": {Description: "Cédula diplomática de identidad", NonDomiciled: true},
+	"B": {Description: "Doc. identidad país residencia - no domiciliado", NonDomiciled: true},
+	"C": {Description: "Tax Identification Number - TIN", NonDomiciled: true},
+	"D": {Description: "Identification Number - IN", NonDomiciled: true},
+	"E": {Description: "Tarjeta andina de migración - TAM", NonDomiciled: true},
+	"F": {Description: "Permiso temporal de permanencia - PTP"},
+	"G": {Description: "Salvoconducto", NonDomiciled: true},
+}
+
+// ValidIdentityDocumentType indica si el tipo de documento está en el
+// catálogo 06
+func ValidIdentityDocumentType(documentType string) bool {
+	_, ok := identityDocumentTypes[documentType]
+	return ok
+}
+
+// NonDomiciled indica si la empresa o persona se identifica con un
+// documento de no domiciliado
+func (c Company) NonDomiciled() bool {
+	return identityDocumentTypes[c.DocumentType].NonDomiciled
+}
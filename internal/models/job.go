@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// JobStatus es el estado de un envío en la cola
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"    // Esperando un worker
+	JobStatusRunning   JobStatus = "running"   // Enviándose
+	JobStatusRetrying  JobStatus = "retrying"  // Falló la entrega; se reintentará en NextAttemptAt
+	JobStatusSucceeded JobStatus = "succeeded" // Documento aceptado
+	JobStatusFailed    JobStatus = "failed"    // Rechazado o sin más reintentos
+)
+
+// Active indica si el job todavía puede enviar el documento
+func (s JobStatus) Active() bool {
+	return s == JobStatusQueued || s == JobStatusRunning || s == JobStatusRetrying
+}
+
+// SendJob es el envío en segundo plano de un documento. Los errores de
+// entrega (red, timeout, 5xx) se reintentan; un rechazo es definitivo.
+type SendJob struct {
+	ID            string     `json:"id"`
+	DocumentID    string     `json:"document_id"`
+	Status        JobStatus  `json:"status"`
+	Attempts      int        `json:"attempts"`
+	MaxAttempts   int        `json:"max_attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
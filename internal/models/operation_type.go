@@ -0,0 +1,75 @@
+package models
+
+// OperationType es el tipo de operación de la factura (catálogo 51). Se
+// informa en ProfileID y en el listID del tipo de documento.
+type OperationType string
+
+const (
+	OperationInternalSale              OperationType = "0101"
+	OperationExportGoods               OperationType = "0200"
+	OperationDetraction                OperationType = "1001"
+	OperationDetractionHydrobiological OperationType = "1002"
+	OperationDetractionPassengers      OperationType = "1003"
+	OperationDetractionCargo           OperationType = "1004"
+)
+
+type operationType struct {
+	Description string
+	// Detraction indica una operación sujeta a detracción (SPOT)
+	Detraction bool
+	// Export indica una exportación; DomiciledCustomer, una exportación a
+	// un adquirente domiciliado (con RUC)
+	Export            bool
+	DomiciledCustomer bool
+}
+
+var operationTypes = map[OperationType]operationType{
+	"0101": {Description: "Venta interna"},
+	"0200": {Description: "Exportación de bienes", Export: true},
+	"0201": {Description: "Exportación de servicios - Prestación de servicios realizados íntegramente en el país", Export: true},
+	"0202": {Description: "Exportación de servicios - Prestación de servicios de hospedaje no domiciliado", Export: true},
+	"0203": {Description: "Exportación de servicios - Transporte de navieras", Export: true},
+	"0204": {Description: "Exportación de servicios - Servicios a naves y aeronaves de bandera extranjera", Export: true},
+	"0205": {Description: "Exportación de servicios - Servicios que conformen un paquete turístico", Export: true},
+	"0206": {Description: "Exportación de servicios - Servicios complementarios al transporte de carga", Export: true},
+	"0207": {Description: "Exportación de servicios - Suministro de energía eléctrica a favor de sujetos domiciliados en ZED", Export: true, DomiciledCustomer: true},
+	"0208": {Description: "Exportación de servicios - Prestación de servicios realizados parcialmente en el extranjero", Export: true},
+	"1001": {Description: "Operación sujeta a detracción", Detraction: true},
+	"1002": {Description: "Operación sujeta a detracción - Recursos hidrobiológicos", Detraction: true},
+	"1003": {Description: "Operación sujeta a detracción - Servicios de transporte de pasajeros", Detraction: true},
+	"1004": {Description: "Operación sujeta a detracción - Servicios de transporte de carga", Detraction: true},
+}
+
+// Valid indica si el tipo de operación está en el catálogo 51
+func (t OperationType) Valid() bool {
+	_, ok := operationTypes[t]
+	return ok
+}
+
+func (t OperationType) Description() string {
+	return operationTypes[t].Description
+}
+
+// Detraction indica si la operación está sujeta a detracción
+func (t OperationType) Detraction() bool {
+	return operationTypes[t].Detraction
+}
+
+// Export indica si la operación es una exportación de bienes o servicios
+func (t OperationType) Export() bool {
+	return operationTypes[t].Export
+}
+
+// DomiciledCustomer indica una exportación cuyo adquirente es domiciliado
+func (t OperationType) DomiciledCustomer() bool {
+	return operationTypes[t].DomiciledCustomer
+}
+
+// Operation devuelve el tipo de operación del documento; los documentos
+// guardados antes de los tipos de operación son ventas internas
+func (d *Document) Operation() OperationType {
+	if d.OperationType == "" {
+		return OperationInternalSale
+	}
+	return d.OperationType
+}
@@ -0,0 +1,71 @@
+package models
+
+import "infac/pkg/decimal"
+
+// Prepayment es un anticipo deducido en la factura o boleta final: el
+// comprobante emitido por el anticipo y el importe que se deduce de él
+type Prepayment struct {
+	// Tipo del comprobante de anticipo (01 o 03); se informa como documento
+	// relacionado 02 o 03 (catálogo 12)
+	DocumentType DocumentType `json:"document_type"`
+	Serie        string       `json:"serie"`
+	Number       string       `json:"number"`
+
+	// Importe deducido con impuestos; por defecto el saldo del anticipo
+	Amount decimal.Decimal `json:"amount"`
+	// Valor de venta deducido, sin impuestos, que reduce la base imponible
+	// con el descuento 04, 05 o 06 según la afectación del anticipo
+	TaxableAmount decimal.Decimal `json:"taxable_amount"`
+}
+
+// DocumentID es el ID del comprobante de anticipo
+func (p Prepayment) DocumentID() string {
+	return DocumentID(p.Serie, p.Number)
+}
+
+// ReferenceCode devuelve el tipo de documento relacionado (catálogo 12):
+// 02 factura o 03 boleta emitida por anticipos
+func (p Prepayment) ReferenceCode() string {
+	if p.DocumentType == DocumentTypeBoleta {
+		return "03"
+	}
+	return "02"
+}
+
+// PrepaymentRequest indica el anticipo a deducir; sin Amount se deduce
+// todo su saldo
+type PrepaymentRequest struct {
+	Serie  string          `json:"serie" binding:"required"`
+	Number string          `json:"number" binding:"required"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// advanceAllowanceCodes son los descuentos globales por anticipos según la
+// afectación al IGV del anticipo
+var advanceAllowanceCodes = map[IGVAffectation]AllowanceChargeCode{
+	AffectationTaxed:      AdvanceTaxed,
+	AffectationExonerated: AdvanceExonerated,
+	AffectationUnaffected: AdvanceUnaffected,
+}
+
+// AdvanceAllowanceCode devuelve el descuento por anticipos de la afectación
+func AdvanceAllowanceCode(affectation IGVAffectation) (AllowanceChargeCode, bool) {
+	code, ok := advanceAllowanceCodes[affectation]
+	return code, ok
+}
+
+// Advance indica un descuento global por anticipos (04 a 06)
+func (c AllowanceChargeCode) Advance() bool {
+	return c == AdvanceTaxed || c == AdvanceExonerated || c == AdvanceUnaffected
+}
+
+// Deducted suma lo deducido del anticipo indicado en este documento
+func (d *Document) Deducted(advanceID string) decimal.Decimal {
+	total := decimal.Zero
+	for _, p := range d.Prepayments {
+		if p.DocumentID() == advanceID {
+			total = total.Add(p.Amount)
+		}
+	}
+	return total
+}
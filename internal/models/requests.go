@@ -1,9 +1,11 @@
 package models
 
+import "infac/pkg/decimal"
+
 type CreateDocumentRequest struct {
 	Type         DocumentType `json:"type" binding:"required"`
-	Serie        string       `json:"serie" binding:"required"`
-	Number       string       `json:"number" binding:"required"`
+	Serie        string       `json:"serie,omitempty"`  // Opcional: serie configurada del establecimiento
+	Number       string       `json:"number,omitempty"` // Opcional: siguiente correlativo de la serie
 	IssueDate    string       `json:"issue_date" binding:"required"`
 	DueDate      string       `json:"due_date,omitempty"`
 	CurrencyCode string       `json:"currency_code" binding:"required"`
@@ -14,21 +16,65 @@ type CreateDocumentRequest struct {
 	
 	PaymentTerms *PaymentTerms `json:"payment_terms" binding:"required"`
 	
+	// Tipo de operación (catálogo 51) y detracción de las operaciones sujetas
+	OperationType OperationType `json:"operation_type,omitempty"`
+	Detraction    *Detraction   `json:"detraction,omitempty"`
+	
+	// Exportaciones
+	DestinationCountry string        `json:"destination_country,omitempty"`
+	Incoterm           string        `json:"incoterm,omitempty"`
+	ExchangeRate       *ExchangeRate `json:"exchange_rate,omitempty"`
+	
+	// Anticipos: el comprobante se emite por un anticipo (Advance) o
+	// deduce anticipos ya emitidos al mismo adquirente (Prepayments)
+	Advance     bool                `json:"advance,omitempty"`
+	Prepayments []PrepaymentRequest `json:"prepayments,omitempty" binding:"dive"`
+	
 	// Para notas de crédito/débito
 	RelatedDocuments []RelatedDocument `json:"related_documents,omitempty"`
+	
+	// Descuentos y cargos globales (catálogo 53)
+	AllowanceCharges []AllowanceCharge `json:"allowance_charges,omitempty"`
+	
+	// Establecimiento emisor, determina la serie por defecto
+	EstablishmentCode string `json:"establishment_code,omitempty"`
 }
 
+// CreateDocumentLineRequest recibe la cantidad y el valor unitario como
+// números exactos de hasta 10 decimales; el servicio valida que sean
+// positivos
 type CreateDocumentLineRequest struct {
-	Quantity         float64 `json:"quantity" binding:"required,gt=0"`
-	UnitCode         string  `json:"unit_code" binding:"required"`
-	Description      string  `json:"description" binding:"required"`
-	UnitPrice        float64 `json:"unit_price" binding:"required"`
+	Quantity         decimal.Decimal `json:"quantity"`
+	UnitCode         string          `json:"unit_code" binding:"required"`
+	Description      string          `json:"description" binding:"required"`
+	UnitPrice        decimal.Decimal `json:"unit_price"`
+	
+	// Afectación al IGV (catálogo 07), por defecto 10 (gravado)
+	IGVAffectation IGVAffectation `json:"igv_affectation,omitempty"`
+	
+	// Descuentos y cargos de la línea: 00, 01, 47 y 48 (catálogo 53)
+	AllowanceCharges []AllowanceCharge `json:"allowance_charges,omitempty"`
 	
 	Taxes []Tax `json:"taxes" binding:"required"`
 	
 	ProductCode string `json:"product_code,omitempty"`
 }
 
+// DailySummaryRequest genera el Resumen Diario de las boletas y notas
+// emitidas en ReferenceDate. Void lista los documentos ya aceptados que se
+// informan como anulados.
+type DailySummaryRequest struct {
+	ReferenceDate string               `json:"reference_date" binding:"required"`
+	Void          []SummaryVoidRequest `json:"void,omitempty" binding:"dive"`
+}
+
+type SummaryVoidRequest struct {
+	DocumentType DocumentType `json:"document_type" binding:"required"`
+	Serie        string       `json:"serie" binding:"required"`
+	Number       string       `json:"number" binding:"required"`
+	Reason       string       `json:"reason,omitempty"`
+}
+
 type VoidDocumentRequest struct {
 	DocumentType DocumentType `json:"document_type" binding:"required"`
 	Serie        string       `json:"serie" binding:"required"`
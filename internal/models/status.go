@@ -0,0 +1,86 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// statusTransitions es la tabla central de transiciones permitidas.
+// El estado vacío representa un documento que aún no ha sido creado.
+//
+//	draft → pending → sent → accepted → cancelled
+//	                       ↘ rejected → draft (corrección)
+//	pending → draft cuando el envío no llegó a SUNAT (error de firma o de red)
+var statusTransitions = map[DocumentStatus][]DocumentStatus{
+	"":             {StatusDraft},
+	StatusDraft:    {StatusPending},
+	StatusPending:  {StatusSent, StatusDraft},
+	StatusSent:     {StatusAccepted, StatusRejected},
+	StatusAccepted: {StatusCancelled},
+	StatusRejected: {StatusDraft},
+}
+
+// StatusChange registra una transición en el historial del documento
+type StatusChange struct {
+	From   DocumentStatus `json:"from,omitempty"`
+	To     DocumentStatus `json:"to"`
+	Reason string         `json:"reason,omitempty"`
+	At     time.Time      `json:"at"`
+}
+
+// TransitionError indica un cambio de estado no permitido
+type TransitionError struct {
+	From DocumentStatus
+	To   DocumentStatus
+}
+
+func (e *TransitionError) Error() string {
+	from := string(e.From)
+	if from == "" {
+		from = "(new)"
+	}
+	return fmt.Sprintf("invalid status transition from %s to %s", from, e.To)
+}
+
+// CanTransition indica si la tabla permite pasar de from a to
+func CanTransition(from, to DocumentStatus) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Note agrega al historial un evento que no cambia el estado, como un CDR
+// que no se pudo leer
+func (d *Document) Note(reason string) {
+	now := time.Now()
+	d.StatusHistory = append(d.StatusHistory, StatusChange{
+		From:   d.Status,
+		To:     d.Status,
+		Reason: reason,
+		At:     now,
+	})
+	d.UpdatedAt = now
+}
+
+// TransitionTo cambia el estado del documento si la transición es válida
+// y la agrega al historial
+func (d *Document) TransitionTo(to DocumentStatus, reason string) error {
+	if !CanTransition(d.Status, to) {
+		return &TransitionError{From: d.Status, To: to}
+	}
+
+	now := time.Now()
+	d.StatusHistory = append(d.StatusHistory, StatusChange{
+		From:   d.Status,
+		To:     to,
+		Reason: reason,
+		At:     now,
+	})
+	d.Status = to
+	d.UpdatedAt = now
+
+	return nil
+}
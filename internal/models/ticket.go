@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// SummaryType identifica los envíos asíncronos que SUNAT responde con ticket
+type SummaryType string
+
+const (
+	SummaryTypeVoided SummaryType = "RA" // Comunicación de Baja
+	SummaryTypeDaily  SummaryType = "RC" // Resumen Diario
+)
+
+// SummaryCondition es el estado con el que se informa un comprobante en el
+// Resumen Diario (catálogo 19)
+type SummaryCondition string
+
+const (
+	SummaryConditionAdd    SummaryCondition = "1" // Adicionar
+	SummaryConditionModify SummaryCondition = "2" // Modificar
+	SummaryConditionVoid   SummaryCondition = "3" // Anulado
+)
+
+type TicketStatus string
+
+const (
+	TicketStatusPending  TicketStatus = "pending"
+	TicketStatusAccepted TicketStatus = "accepted"
+	TicketStatusRejected TicketStatus = "rejected"
+	// TicketStatusCDRUnreadable indica que SUNAT procesó el ticket pero su
+	// CDR no se pudo leer: no se vuelve a consultar automáticamente
+	TicketStatusCDRUnreadable TicketStatus = "cdr_unreadable"
+)
+
+// Ticket registra un resumen enviado con sendSummary y los documentos que
+// incluye, hasta que SUNAT informe su resultado
+type Ticket struct {
+	Number        string      `json:"ticket"`
+	SummaryID     string      `json:"summary_id"` // RA-YYYYMMDD-n / RC-YYYYMMDD-n
+	Type          SummaryType `json:"type"`
+	IssueDate     time.Time   `json:"issue_date"`
+	ReferenceDate time.Time   `json:"reference_date"`
+	DocumentIDs   []string    `json:"document_ids"`
+	// Estado con el que se informó cada documento en un Resumen Diario
+	Conditions map[string]SummaryCondition `json:"conditions,omitempty"`
+	Status     TicketStatus                `json:"status"`
+	CDR        *CDR                        `json:"cdr,omitempty"`
+	// Canal por el que se envió; getStatus se consulta por el mismo canal
+	Channel string `json:"channel,omitempty"`
+	// Borradores de la fecha que quedaron fuera del resumen y el motivo.
+	// Solo se informa en la respuesta del envío; no se guarda.
+	Skipped map[string]string `json:"skipped,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,243 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"infac/pkg/decimal"
+)
+
+var ErrInvalidTotals = errors.New("document totals are inconsistent")
+
+// documentTotals son los totales que resultan de sumar las líneas
+type documentTotals struct {
+	subTotal, taxes                                   decimal.Decimal
+	taxed, ivap, exonerated, unaffected, export, free decimal.Decimal
+	// IGV de las líneas gravadas, que se recalcula si hay descuentos o
+	// cargos globales que afectan su base
+	igv decimal.Decimal
+	// Descuentos y cargos de línea que no afectan la base imponible
+	allowances, charges decimal.Decimal
+}
+
+// sumLines suma los importes de las líneas por tipo de afectación. Los
+// impuestos de las líneas gratuitas no se cobran y no suman a TotalTaxes.
+func (d *Document) sumLines() (documentTotals, error) {
+	t := documentTotals{
+		subTotal: decimal.Zero, taxes: decimal.Zero,
+		taxed: decimal.Zero, ivap: decimal.Zero, exonerated: decimal.Zero, unaffected: decimal.Zero, export: decimal.Zero, free: decimal.Zero,
+		igv: decimal.Zero, allowances: decimal.Zero, charges: decimal.Zero,
+	}
+
+	for _, line := range d.Lines {
+		if !line.TotalPrice.Exact(decimal.AmountPlaces) {
+			return t, fmt.Errorf("%w: line %s total_price %s has more than %d decimals", ErrInvalidTotals, line.ID, line.TotalPrice, decimal.AmountPlaces)
+		}
+		for _, tax := range line.Taxes {
+			if !tax.Amount.Exact(decimal.AmountPlaces) {
+				return t, fmt.Errorf("%w: line %s %s amount %s has more than %d decimals", ErrInvalidTotals, line.ID, tax.Type, tax.Amount, decimal.AmountPlaces)
+			}
+		}
+
+		affectation := line.Affectation()
+		if affectation.Free() {
+			t.free = t.free.Add(line.TotalPrice)
+			continue
+		}
+
+		switch affectation {
+		case AffectationIVAP:
+			t.ivap = t.ivap.Add(line.TotalPrice)
+		case AffectationExonerated:
+			t.exonerated = t.exonerated.Add(line.TotalPrice)
+		case AffectationUnaffected:
+			t.unaffected = t.unaffected.Add(line.TotalPrice)
+		case AffectationExport:
+			t.export = t.export.Add(line.TotalPrice)
+		default:
+			t.taxed = t.taxed.Add(line.TotalPrice)
+		}
+		t.subTotal = t.subTotal.Add(line.TotalPrice)
+		for _, tax := range line.Taxes {
+			t.taxes = t.taxes.Add(tax.Amount)
+			if tax.Type == TaxTypeIGV && affectation == AffectationTaxed {
+				t.igv = t.igv.Add(tax.Amount)
+			}
+		}
+
+		for _, ac := range line.AllowanceCharges {
+			switch {
+			case ac.Code.AffectsBase():
+				// Ya está incluido en el valor de venta de la línea
+			case ac.Code.Charge():
+				t.charges = t.charges.Add(ac.Amount)
+			default:
+				t.allowances = t.allowances.Add(ac.Amount)
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// BaseAdjustment suma los descuentos (con signo negativo) y cargos globales
+// que modifican la base imponible de la afectación indicada
+func (d *Document) BaseAdjustment(affectation IGVAffectation) decimal.Decimal {
+	total := decimal.Zero
+	for _, ac := range d.AllowanceCharges {
+		if ac.Code.Affectation() == affectation {
+			total = total.Add(ac.Signed())
+		}
+	}
+	return total
+}
+
+// IGVTotals devuelve la base imponible y el IGV de las operaciones gravadas.
+// Sin descuentos ni cargos globales que afecten la base, el IGV es la suma
+// del de las líneas; con ellos, se calcula sobre la base ajustada.
+func (d *Document) IGVTotals() (base, amount decimal.Decimal) {
+	base, amount = decimal.Zero, decimal.Zero
+	rate := decimal.Zero
+	for _, line := range d.Lines {
+		if line.Affectation() != AffectationTaxed {
+			continue
+		}
+		for _, tax := range line.Taxes {
+			if tax.Type != TaxTypeIGV {
+				continue
+			}
+			taxable := tax.TaxableAmount
+			if taxable.IsZero() {
+				taxable = line.TaxableAmount
+			}
+			base = base.Add(taxable)
+			amount = amount.Add(tax.Amount)
+			rate = tax.Rate
+		}
+	}
+
+	adjustment := d.BaseAdjustment(AffectationTaxed)
+	if adjustment.IsZero() {
+		return base, amount
+	}
+	base = base.Add(adjustment).Round(decimal.AmountPlaces)
+	return base, base.Mul(rate).Div(decimal.Hundred).Round(decimal.AmountPlaces)
+}
+
+// SetTotals calcula los totales del documento a partir de sus líneas y de
+// los descuentos y cargos globales
+func (d *Document) SetTotals() error {
+	t, err := d.sumLines()
+	if err != nil {
+		return err
+	}
+
+	adjustments := decimal.Zero
+	allowances, charges := t.allowances, t.charges
+	for _, ac := range d.AllowanceCharges {
+		switch {
+		case ac.Code.AffectsBase():
+			adjustments = adjustments.Add(ac.Signed())
+		case ac.Code.Informative():
+			// Se informa sin modificar el importe a pagar
+		case ac.Code.Charge():
+			charges = charges.Add(ac.Amount)
+		default:
+			allowances = allowances.Add(ac.Amount)
+		}
+	}
+	_, igv := d.IGVTotals()
+	prepaid := decimal.Zero
+	for _, p := range d.Prepayments {
+		prepaid = prepaid.Add(p.Amount)
+	}
+
+	d.SubTotal = t.subTotal.Round(decimal.AmountPlaces)
+	d.TotalTaxes = t.taxes.Sub(t.igv).Add(igv).Round(decimal.AmountPlaces)
+	d.TaxExclusiveAmount = d.SubTotal.Add(adjustments).Round(decimal.AmountPlaces)
+	// Los anticipos deducidos reducen la base imponible con los descuentos
+	// 04 a 06; el importe con impuestos sigue siendo el de toda la venta y
+	// se paga la diferencia con lo ya cobrado
+	d.TotalPrepaid = prepaid.Round(decimal.AmountPlaces)
+	d.TaxInclusiveAmount = d.TaxExclusiveAmount.Add(d.TotalTaxes).Add(d.TotalPrepaid)
+	d.TotalAllowances = allowances.Round(decimal.AmountPlaces)
+	d.TotalCharges = charges.Round(decimal.AmountPlaces)
+	d.TotalAmount = d.TaxInclusiveAmount.Sub(d.TotalAllowances).Add(d.TotalCharges).Sub(d.TotalPrepaid)
+	d.TotalTaxed = t.taxed.Round(decimal.AmountPlaces)
+	d.TotalIVAP = t.ivap.Round(decimal.AmountPlaces)
+	d.TotalExonerated = t.exonerated.Round(decimal.AmountPlaces)
+	d.TotalUnaffected = t.unaffected.Round(decimal.AmountPlaces)
+	d.TotalExport = t.export.Round(decimal.AmountPlaces)
+	d.TotalFree = t.free.Round(decimal.AmountPlaces)
+
+	if d.TaxExclusiveAmount.Sign() < 0 || d.TotalAmount.Sign() < 0 {
+		return fmt.Errorf("%w: discounts exceed the value of the document", ErrInvalidTotals)
+	}
+
+	return nil
+}
+
+// PendingAmount es el importe neto pendiente de pago que se paga al contado
+// o en cuotas: el importe total menos la detracción o la retención. La
+// detracción está en soles y se descuenta en la moneda del documento.
+func (d *Document) PendingAmount() decimal.Decimal {
+	pending := d.TotalAmount
+	if d.Detraction != nil {
+		pending = pending.Sub(d.FromPEN(d.Detraction.Amount))
+	}
+	if retention := d.Retention(); retention != nil {
+		pending = pending.Sub(retention.Amount)
+	}
+	return pending
+}
+
+type totalCheck struct {
+	name       string
+	have, want decimal.Decimal
+}
+
+// CheckTotals verifica que los importes tengan 2 decimales y que los totales
+// del documento cuadren con sus líneas y sus descuentos y cargos, como lo
+// valida SUNAT
+func (d *Document) CheckTotals() error {
+	expected := *d
+	if err := expected.SetTotals(); err != nil {
+		return err
+	}
+
+	checks := []totalCheck{
+		{"sub_total", d.SubTotal, expected.SubTotal},
+		{"total_taxes", d.TotalTaxes, expected.TotalTaxes},
+		{"total_amount", d.TotalAmount, expected.TotalAmount},
+	}
+
+	// Los documentos guardados antes de separar los totales por afectación
+	// o de los descuentos y cargos no tienen los demás totales
+	if !decimal.Sum(d.TotalTaxed, d.TotalIVAP, d.TotalExonerated, d.TotalUnaffected, d.TotalExport, d.TotalFree).IsZero() {
+		checks = append(checks,
+			totalCheck{"total_taxed", d.TotalTaxed, expected.TotalTaxed},
+			totalCheck{"total_ivap", d.TotalIVAP, expected.TotalIVAP},
+			totalCheck{"total_exonerated", d.TotalExonerated, expected.TotalExonerated},
+			totalCheck{"total_unaffected", d.TotalUnaffected, expected.TotalUnaffected},
+			totalCheck{"total_export", d.TotalExport, expected.TotalExport},
+			totalCheck{"total_free", d.TotalFree, expected.TotalFree},
+		)
+	}
+	if !d.TaxInclusiveAmount.IsZero() {
+		checks = append(checks,
+			totalCheck{"tax_exclusive_amount", d.TaxExclusiveAmount, expected.TaxExclusiveAmount},
+			totalCheck{"tax_inclusive_amount", d.TaxInclusiveAmount, expected.TaxInclusiveAmount},
+			totalCheck{"total_allowances", d.TotalAllowances, expected.TotalAllowances},
+			totalCheck{"total_charges", d.TotalCharges, expected.TotalCharges},
+			totalCheck{"total_prepaid", d.TotalPrepaid, expected.TotalPrepaid},
+		)
+	}
+
+	for _, check := range checks {
+		if !check.have.Equal(check.want) {
+			return fmt.Errorf("%w: %s %s does not match the lines (%s)", ErrInvalidTotals, check.name, check.have, check.want)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// resolveAllowanceCharges valida los descuentos o cargos de una línea
+// (line) o del documento y calcula su base e importe. baseFor devuelve la
+// base por defecto de cada código.
+func resolveAllowanceCharges(where string, items []models.AllowanceCharge, line bool, baseFor func(models.AllowanceChargeCode) decimal.Decimal) ([]models.AllowanceCharge, error) {
+	var resolved []models.AllowanceCharge
+	for i, ac := range items {
+		if !ac.Code.Valid() {
+			return nil, fmt.Errorf("%s: allowance_charges[%d]: unknown code %s (catalog 53)", where, i, ac.Code)
+		}
+		if ac.Code.Line() != line {
+			level := "document"
+			if ac.Code.Line() {
+				level = "line"
+			}
+			return nil, fmt.Errorf("%s: allowance_charges[%d]: code %s applies at %s level", where, i, ac.Code, level)
+		}
+
+		if ac.BaseAmount.IsZero() {
+			ac.BaseAmount = baseFor(ac.Code)
+		}
+		if ac.BaseAmount.Sign() < 0 || !ac.BaseAmount.Exact(decimal.AmountPlaces) {
+			return nil, fmt.Errorf("%s: allowance_charges[%d]: invalid base_amount %s", where, i, ac.BaseAmount)
+		}
+		ac.BaseAmount = ac.BaseAmount.Round(decimal.AmountPlaces)
+
+		if ac.Factor != nil {
+			if ac.Factor.Sign() <= 0 || !ac.Factor.Exact(5) {
+				return nil, fmt.Errorf("%s: allowance_charges[%d]: factor must be positive with up to 5 decimals", where, i)
+			}
+			ac.Amount = ac.BaseAmount.Mul(*ac.Factor).Round(decimal.AmountPlaces)
+		}
+		if ac.Amount.Sign() <= 0 || !ac.Amount.Exact(decimal.AmountPlaces) {
+			return nil, fmt.Errorf("%s: allowance_charges[%d]: amount must be positive with up to %d decimals", where, i, decimal.AmountPlaces)
+		}
+		ac.Amount = ac.Amount.Round(decimal.AmountPlaces)
+
+		resolved = append(resolved, ac)
+	}
+
+	return resolved, nil
+}
+
+// documentBase devuelve la base por defecto de un descuento o cargo global:
+// el valor de venta de la afectación cuya base modifica o, si no modifica
+// ninguna, el de todas las operaciones onerosas
+func documentBase(doc *models.Document) func(models.AllowanceChargeCode) decimal.Decimal {
+	return func(code models.AllowanceChargeCode) decimal.Decimal {
+		switch code.Affectation() {
+		case models.AffectationTaxed:
+			return doc.TotalTaxed
+		case models.AffectationExonerated:
+			return doc.TotalExonerated
+		case models.AffectationUnaffected:
+			return doc.TotalUnaffected
+		default:
+			return doc.SubTotal
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// resolveDetraction valida el tipo de operación y calcula la detracción de
+// las operaciones sujetas: el porcentaje del código (catálogo 54), salvo que
+// el request indique otro, sobre el importe total. El importe se calcula en
+// soles: en moneda extranjera, con el tipo de cambio declarado. Sin cuenta en
+// el request se usa la cuenta de detracciones del emisor.
+func resolveDetraction(doc *models.Document, detraction *models.Detraction) error {
+	operation := doc.Operation()
+	if !operation.Valid() {
+		return fmt.Errorf("unknown operation_type %s (catalog 51)", operation)
+	}
+
+	if !operation.Detraction() {
+		if detraction != nil {
+			return fmt.Errorf("detraction requires an operation_type subject to detraction (1001 to 1004)")
+		}
+		return nil
+	}
+	if detraction == nil {
+		return fmt.Errorf("operation_type %s requires detraction", operation)
+	}
+	if doc.Type != models.DocumentTypeFactura {
+		return fmt.Errorf("detraction applies only to invoices (01)")
+	}
+	if err := requirePENRate(doc, "detraction"); err != nil {
+		return err
+	}
+	total := doc.ToPEN(doc.TotalAmount)
+	if total.Cmp(models.DetractionMinimumAmount) <= 0 {
+		return fmt.Errorf("detraction applies to operations over PEN %s (total PEN %s)", models.DetractionMinimumAmount.Round(decimal.AmountPlaces), total)
+	}
+
+	resolved := *detraction
+	if !resolved.Code.Valid() {
+		return fmt.Errorf("detraction: unknown code %s (catalog 54)", resolved.Code)
+	}
+	if resolved.Percent.IsZero() {
+		resolved.Percent = resolved.Code.Percent()
+	}
+	if resolved.Percent.Sign() <= 0 || resolved.Percent.Cmp(decimal.Hundred) > 0 || !resolved.Percent.Exact(decimal.AmountPlaces) {
+		return fmt.Errorf("detraction: percent must be between 0 and 100 with up to %d decimals", decimal.AmountPlaces)
+	}
+	if resolved.Account == "" {
+		resolved.Account = doc.Issuer.DetractionAccount
+	}
+	if resolved.Account == "" {
+		return fmt.Errorf("detraction: account is required (Banco de la Nación)")
+	}
+	if resolved.PaymentMeansCode == "" {
+		resolved.PaymentMeansCode = models.DetractionPaymentMeansDeposit
+	}
+
+	resolved.Amount = total.Mul(resolved.Percent).Div(decimal.Hundred).Round(decimal.AmountPlaces)
+	doc.Detraction = &resolved
+	return nil
+}
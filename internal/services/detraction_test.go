@@ -0,0 +1,62 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// detractionRequest arma una factura sujeta a detracción (037, 12%) por el
+// importe de testRequest: 236.00 en la moneda indicada
+func detractionRequest(t *testing.T, currency string, rate string) *models.CreateDocumentRequest {
+	t.Helper()
+
+	req := testRequest(t, models.DocumentTypeFactura)
+	req.CurrencyCode = currency
+	req.OperationType = models.OperationType("1001")
+	req.Detraction = &models.Detraction{Code: "037", Account: "00-000-000000"}
+	if rate != "" {
+		req.ExchangeRate = &models.ExchangeRate{Rate: decimal.MustParse(rate)}
+	}
+	return req
+}
+
+func TestDetractionForeignCurrency(t *testing.T) {
+	s, _ := newTestService(t)
+
+	// 236.00 USD × 3.75 = 885.00 PEN, sobre el mínimo de S/ 700
+	doc, err := s.buildDocument(detractionRequest(t, "USD", "3.75"))
+	if err != nil {
+		t.Fatalf("buildDocument: %v", err)
+	}
+	if doc.Detraction == nil || doc.Detraction.Amount.String() != "106.20" {
+		t.Fatalf("detraction = %+v, want 106.20 PEN", doc.Detraction)
+	}
+	// El neto pendiente de pago descuenta la detracción en dólares: 106.20 / 3.75 = 28.32
+	if pending := doc.PendingAmount(); pending.String() != "207.68" {
+		t.Fatalf("PendingAmount = %s, want 207.68", pending)
+	}
+}
+
+func TestDetractionForeignCurrencyErrors(t *testing.T) {
+	s, _ := newTestService(t)
+
+	tests := []struct {
+		name string
+		req  *models.CreateDocumentRequest
+		want string
+	}{
+		{"without exchange rate", detractionRequest(t, "USD", ""), "requires the exchange_rate to PEN"},
+		// 236.00 USD × 2.5 = 590.00 PEN no supera el mínimo
+		{"under minimum in PEN", detractionRequest(t, "USD", "2.5"), "total PEN 590.00"},
+		{"under minimum", detractionRequest(t, "PEN", ""), "total PEN 236.00"},
+	}
+	for _, tt := range tests {
+		_, err := s.buildDocument(tt.req)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: buildDocument = %v, want error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
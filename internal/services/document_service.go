@@ -4,66 +4,274 @@ import (
 	"archive/zip"
 	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"infac/internal/config"
 	"infac/internal/models"
+	"infac/internal/storage"
+	"infac/pkg/decimal"
+	"infac/pkg/sunat"
 	"infac/pkg/ubl"
 
 	"github.com/henrybravos/sunatlib"
 )
 
+var (
+	ErrDocumentNotFound = storage.ErrNotFound
+	// ErrDocumentExists indica que ya hay un documento con la misma serie y
+	// número
+	ErrDocumentExists   = storage.ErrAlreadyExists
+	ErrDocumentNotDraft = errors.New("document is not a draft")
+	// ErrDocumentRejected es un rechazo de SUNAT o del OSE: reenviar el
+	// mismo documento no cambia el resultado
+	ErrDocumentRejected = errors.New("document rejected")
+	// ErrDeliveryFailed indica que el documento no llegó a procesarse (red,
+	// timeout, 5xx o servicio no disponible) y puede reenviarse
+	ErrDeliveryFailed = errors.New("document could not be delivered")
+	// ErrCDRNotReadable indica que SUNAT respondió con un CDR que no se pudo
+	// leer: el documento queda enviado hasta volver a consultar su CDR
+	ErrCDRNotReadable = errors.New("CDR could not be read")
+	// ErrCDRPending indica que SUNAT ya registró el documento pero todavía no
+	// se pudo obtener su CDR: el documento queda enviado, sin reenviarlo,
+	// hasta volver a consultar su CDR
+	ErrCDRPending = errors.New("CDR is not available yet")
+	// ErrDocumentNotSent indica que el documento no espera un CDR
+	ErrDocumentNotSent = errors.New("document is not waiting for a CDR")
+	// ErrDocumentBusy indica que el documento tiene un envío o una baja en
+	// curso, o que cambió mientras se preparaba el envío
+	ErrDocumentBusy = errors.New("document is being processed")
+	// ErrStorage indica una falla del repositorio al leer o guardar
+	// documentos, que no depende del contenido de la request
+	ErrStorage = errors.New("storage error")
+	// ErrPrepaymentUnavailable indica que un anticipo que deduce el documento
+	// se anuló o no tiene saldo suficiente al momento del envío
+	ErrPrepaymentUnavailable = errors.New("prepayment is no longer available")
+)
+
+// xmlSigner firma los XML con el certificado del emisor; lo implementa
+// sunatlib.SUNATClient
+type xmlSigner interface {
+	SignXML(xmlContent []byte) ([]byte, error)
+}
+
 type DocumentService struct {
-	issuer      *models.Company
-	sunatClient *sunatlib.SUNATClient
+	issuer    *models.Company
+	signer    xmlSigner
+	senders   *senderRouter
+	repo      storage.DocumentRepository
+	tickets   storage.TicketRepository
+	numbering *NumberingService
+
+	// Locales anexos del emisor; vacío si solo emite desde su domicilio fiscal
+	establishments []config.EstablishmentConfig
+	// filesDir contiene los XML firmados (xml/) y los CDR (cdr/)
+	filesDir string
+
+	// ticketMu evita que el poller y la API procesen el mismo ticket a la vez
+	ticketMu sync.Mutex
+	// signMu serializa las firmas: sunatlib usa archivos temporales fijos
+	signMu sync.Mutex
+	// claimMu protege claimed, los documentos con un envío o una baja en
+	// curso
+	claimMu sync.Mutex
+	claimed map[string]bool
+	// advanceMu protege advanceLocks, un candado por anticipo que serializa
+	// el cálculo de su saldo con el guardado del documento que lo deduce
+	advanceMu    sync.Mutex
+	advanceLocks map[string]*advanceLock
 }
 
-func NewDocumentService(issuer *models.Company) *DocumentService {
-	// Create SUNAT client
-	sunatClient := sunatlib.NewSUNATClient(
+// NewDocumentService crea el servicio con las credenciales SOL, el ambiente,
+// el OSE y el certificado configurados. Falla si el certificado no se puede
+// cargar: sin él ningún documento podría firmarse.
+func NewDocumentService(issuer *models.Company, sunatCfg config.SUNATConfig, certCfg config.CertificateConfig, repo storage.DocumentRepository, tickets storage.TicketRepository, numbering *NumberingService) (*DocumentService, error) {
+	if err := sunatCfg.Validate(); err != nil {
+		return nil, err
+	}
+	endpoint, _ := sunatCfg.Endpoint()
+
+	senders, err := newSenderRouter(issuer.DocumentNumber, sunatCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// sunatlib solo se usa para firmar; los envíos van por los canales
+	signer := sunatlib.NewSUNATClient(
 		issuer.DocumentNumber, // RUC
-		"MODDATOS",            // SOL username
-		"moddatos",            // SOL password
-		"https://e-beta.sunat.gob.pe/ol-ti-itcpfegem-beta/billService", // Beta endpoint
+		sunatCfg.Username,     // SOL username
+		sunatCfg.Password,     // SOL password
+		endpoint,
 	)
 
 	// Set certificate from PFX file
-	err := sunatClient.SetCertificateFromPFX(
-		"pkg/signature/certificate_fixed.pfx",
-		"20612790168NEOFORCE",
-		"pkg/signature/temp",
-	)
+	if certCfg.PFXPath == "" {
+		return nil, fmt.Errorf("certificate.pfx_path is required")
+	}
+	err = signer.SetCertificateFromPFX(certCfg.PFXPath, certCfg.Password, certCfg.TempDir)
 	if err != nil {
-		fmt.Printf("Warning: Failed to set certificate: %v\n", err)
-		fmt.Printf("Continuing without certificate - signatures will not be valid\n")
-	} else {
-		fmt.Printf("Successfully initialized SUNAT client with certificate\n")
+		return nil, fmt.Errorf("failed to load certificate %s: %w", certCfg.PFXPath, err)
 	}
+	fmt.Printf("Successfully initialized SUNAT client (%s: %s)\n", sunatCfg.Environment, endpoint)
 
 	return &DocumentService{
-		issuer:      issuer,
-		sunatClient: sunatClient,
-	}
+		issuer:    issuer,
+		signer:    signer,
+		senders:   senders,
+		repo:      repo,
+		tickets:   tickets,
+		numbering: numbering,
+		filesDir:  "storage",
+	}, nil
 }
 
 func (s *DocumentService) CreateDocument(req *models.CreateDocumentRequest) (*models.Document, error) {
+	// El saldo de los anticipos se calcula y se consume con el guardado
+	unlock := s.lockAdvances(prepaymentIDs(req.Prepayments))
+	defer unlock()
+
+	doc, err := s.buildDocument(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// La numeración se asigna al final para no consumir correlativos con
+	// requests inválidos
+	if err := s.numbering.Assign(doc, req.EstablishmentCode); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Save(doc); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return nil, fmt.Errorf("%w: %s", ErrDocumentExists, doc.ID)
+		}
+		return nil, fmt.Errorf("%w: failed to save document: %w", ErrStorage, err)
+	}
+
+	return doc, nil
+}
+
+// UpdateDocument reemplaza el contenido de un borrador y vuelve a calcular
+// sus importes. Un documento rechazado por SUNAT vuelve a borrador para
+// poder corregirlo. Una boleta o nota de serie B ya aceptada en un Resumen
+// Diario sigue aceptada y se informa corregida, con estado 2 (modificar), en
+// el siguiente resumen de su fecha. La serie y el número no pueden cambiar.
+func (s *DocumentService) UpdateDocument(id string, req *models.CreateDocumentRequest) (*models.Document, error) {
+	existing, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	release, err := s.claim(existing)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	modify := existing.Status == models.StatusAccepted && isSummaryDocument(existing)
+	if modify {
+		if err := s.checkModifiable(existing); err != nil {
+			return nil, err
+		}
+	} else if existing.Status != models.StatusDraft {
+		if err := existing.TransitionTo(models.StatusDraft, "Corrección del documento"); err != nil {
+			return nil, err
+		}
+	}
+
+	// El documento conserva su tipo, serie y número: el request puede
+	// omitir la serie y el número, pero no cambiarlos
+	if req.Type != existing.Type {
+		return nil, fmt.Errorf("document type cannot be changed (%s is %s)", existing.ID, existing.Type)
+	}
+	if (req.Serie != "" && req.Serie != existing.Serie) || (req.Number != "" && !sameNumber(req.Number, existing.Number)) {
+		return nil, fmt.Errorf("serie and number cannot be changed (expected %s)", existing.ID)
+	}
+	update := *req
+	update.Serie, update.Number = existing.Serie, existing.Number
+	unlock := s.lockAdvances(prepaymentIDs(req.Prepayments))
+	defer unlock()
+
+	doc, err := s.buildDocument(&update)
+	if err != nil {
+		return nil, err
+	}
+	doc.CreatedAt = existing.CreatedAt
+	doc.StatusHistory = existing.StatusHistory
+	if modify {
+		if !doc.IssueDate.Equal(existing.IssueDate) {
+			return nil, fmt.Errorf("the issue date of a reported document cannot be changed")
+		}
+		doc.Status = existing.Status
+		doc.SUNATStatus = existing.SUNATStatus
+		doc.CDR = existing.CDR
+		doc.SummaryTicket = existing.SummaryTicket
+		doc.Channel = existing.Channel
+		doc.Modified = true
+		doc.Note("Corrección del documento; se informará en el siguiente Resumen Diario")
+	}
+
+	if err := s.repo.Update(doc); err != nil {
+		return nil, fmt.Errorf("%w: failed to update document: %w", ErrStorage, err)
+	}
+
+	return doc, nil
+}
+
+// sameNumber compara un correlativo del request con el almacenado, que
+// siempre tiene 8 dígitos
+func sameNumber(number, stored string) bool {
+	return models.ValidNumber(number) && models.NormalizeNumber(number) == stored
+}
+
+// DeleteDocument elimina un borrador que nunca fue enviado
+func (s *DocumentService) DeleteDocument(id string) error {
+	doc, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if doc.Status != models.StatusDraft {
+		return ErrDocumentNotDraft
+	}
+	release, err := s.claim(doc)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.repo.Delete(id)
+}
+
+// buildDocument arma el documento a partir del request y calcula líneas,
+// impuestos y totales
+func (s *DocumentService) buildDocument(req *models.CreateDocumentRequest) (*models.Document, error) {
+	issuer, err := s.issuerFor(req.EstablishmentCode)
+	if err != nil {
+		return nil, err
+	}
+
 	doc := &models.Document{
-		ID:               fmt.Sprintf("%s-%s", req.Serie, req.Number),
+		ID:               models.DocumentID(req.Serie, req.Number),
 		Serie:            req.Serie,
 		Number:           req.Number,
 		Type:             req.Type,
 		CurrencyCode:     req.CurrencyCode,
-		Issuer:           *s.issuer,
+		OperationType:    req.OperationType,
+		Issuer:           issuer,
 		Customer:         req.Customer,
 		PaymentTerms:     req.PaymentTerms,
 		RelatedDocuments: req.RelatedDocuments,
-		Status:           models.StatusDraft,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
 
+	if err := doc.TransitionTo(models.StatusDraft, "Documento creado"); err != nil {
+		return nil, err
+	}
+
 	// Parse dates
 	issueDate, err := time.Parse("2006-01-02", req.IssueDate)
 	if err != nil {
@@ -79,42 +287,384 @@ func (s *DocumentService) CreateDocument(req *models.CreateDocumentRequest) (*mo
 		doc.DueDate = &dueDate
 	}
 
-	// Process lines
-	var subTotal float64
-	var totalTaxes float64
-
+	// Process lines. Cada importe de línea se redondea a 2 decimales y los
+	// totales se suman a partir de los importes redondeados.
 	for i, lineReq := range req.Lines {
+		if lineReq.Quantity.Sign() <= 0 {
+			return nil, fmt.Errorf("line %d: quantity must be greater than zero", i+1)
+		}
+		if lineReq.UnitPrice.Sign() <= 0 {
+			return nil, fmt.Errorf("line %d: unit_price must be greater than zero", i+1)
+		}
+		if !lineReq.Quantity.Exact(decimal.MaxPlaces) || !lineReq.UnitPrice.Exact(decimal.MaxPlaces) {
+			return nil, fmt.Errorf("line %d: quantity and unit_price admit up to %d decimals", i+1, decimal.MaxPlaces)
+		}
+
+		affectation := lineReq.IGVAffectation
+		if affectation == "" {
+			affectation = models.AffectationTaxed
+		}
+		if !affectation.Valid() {
+			return nil, fmt.Errorf("line %d: unknown igv_affectation %s (catalog 07)", i+1, affectation)
+		}
+
+		// Los descuentos y cargos de la línea se calculan sobre cantidad por
+		// valor unitario
+		gross := lineReq.Quantity.Mul(lineReq.UnitPrice).Round(decimal.AmountPlaces)
+		allowanceCharges, err := resolveAllowanceCharges(fmt.Sprintf("line %d", i+1), lineReq.AllowanceCharges, true,
+			func(models.AllowanceChargeCode) decimal.Decimal { return gross })
+		if err != nil {
+			return nil, err
+		}
+
 		line := models.DocumentLine{
-			ID:          fmt.Sprintf("%d", i+1),
-			Quantity:    lineReq.Quantity,
-			UnitCode:    lineReq.UnitCode,
-			Description: lineReq.Description,
-			UnitPrice:   lineReq.UnitPrice,
-			TotalPrice:  lineReq.Quantity * lineReq.UnitPrice,
-			ProductCode: lineReq.ProductCode,
-			Taxes:       lineReq.Taxes,
+			ID:               fmt.Sprintf("%d", i+1),
+			Quantity:         lineReq.Quantity,
+			UnitCode:         lineReq.UnitCode,
+			Description:      lineReq.Description,
+			UnitPrice:        lineReq.UnitPrice,
+			TotalPrice:       gross,
+			IGVAffectation:   affectation,
+			AllowanceCharges: allowanceCharges,
+			ProductCode:      lineReq.ProductCode,
+			Taxes:            lineReq.Taxes,
+		}
+		for _, ac := range allowanceCharges {
+			if ac.Code.AffectsBase() {
+				line.TotalPrice = line.TotalPrice.Add(ac.Signed())
+			}
+		}
+		if line.TotalPrice.Sign() <= 0 {
+			return nil, fmt.Errorf("line %d: discounts exceed the line value", i+1)
 		}
 
 		// Calculate taxable amount (base for taxes)
 		line.TaxableAmount = line.TotalPrice
 
-		// Calculate taxes
-		for j, tax := range line.Taxes {
-			line.Taxes[j].Amount = line.TaxableAmount * tax.Rate / 100
-			totalTaxes += line.Taxes[j].Amount
+		// Calculate taxes: ISC, IGV sobre el valor más el ISC e ICBPER
+		if err := computeTaxes(&line); err != nil {
+			return nil, err
 		}
 
-		subTotal += line.TotalPrice
 		doc.Lines = append(doc.Lines, line)
 	}
 
-	doc.SubTotal = subTotal
-	doc.TotalTaxes = totalTaxes
-	doc.TotalAmount = subTotal + totalTaxes
+	// Los descuentos y cargos globales se calculan sobre los totales de las
+	// líneas, y la retención y la percepción sobre el importe total
+	if err := doc.SetTotals(); err != nil {
+		return nil, err
+	}
+	regular, informative := splitInformative(req.AllowanceCharges)
+	doc.AllowanceCharges, err = resolveAllowanceCharges("document", regular, false, documentBase(doc))
+	if err != nil {
+		return nil, err
+	}
+	// Los anticipos deducidos reducen la base con los descuentos 04 a 06
+	prepayments, err := s.resolvePrepayments(doc, req)
+	if err != nil {
+		return nil, err
+	}
+	doc.AllowanceCharges = append(doc.AllowanceCharges, prepayments...)
+	if err := doc.SetTotals(); err != nil {
+		return nil, err
+	}
+	// La detracción y la retención se informan en soles con el tipo de cambio
+	if err := resolveExchangeRate(doc, req); err != nil {
+		return nil, err
+	}
+	withholdings, err := resolveRetentionPerception(doc, informative)
+	if err != nil {
+		return nil, err
+	}
+	doc.AllowanceCharges = append(doc.AllowanceCharges, withholdings...)
+
+	// La detracción se descuenta del importe pendiente de pago
+	if err := resolveDetraction(doc, req.Detraction); err != nil {
+		return nil, err
+	}
+	if err := resolveExport(doc, req); err != nil {
+		return nil, err
+	}
+	if err := resolvePaymentTerms(doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// issuerFor devuelve los datos del emisor para el establecimiento indicado.
+// Sin establecimientos configurados se usan los datos de la empresa.
+func (s *DocumentService) issuerFor(establishmentCode string) (models.Company, error) {
+	issuer := *s.issuer
+	if len(s.establishments) == 0 || establishmentCode == "" {
+		return issuer, nil
+	}
+
+	for _, establishment := range s.establishments {
+		if establishment.Code != establishmentCode {
+			continue
+		}
+		issuer.EstablishmentCode = establishment.Code
+		if establishment.Address != "" {
+			issuer.Address = establishment.Address
+			issuer.District = establishment.District
+			issuer.Province = establishment.Province
+			issuer.Department = establishment.Department
+		}
+		return issuer, nil
+	}
+
+	return issuer, fmt.Errorf("unknown establishment %s for issuer %s", establishmentCode, issuer.DocumentNumber)
+}
+
+func (s *DocumentService) GetDocument(id string) (*models.Document, error) {
+	return s.repo.FindByID(id)
+}
+
+// ListDocuments devuelve una página de documentos y el cursor de la
+// siguiente página (vacío si no hay más resultados)
+func (s *DocumentService) ListDocuments(filter storage.DocumentFilter) ([]*models.Document, string, error) {
+	limit := filter.Limit
+	if limit > 0 {
+		// Se pide uno extra para saber si existe una página siguiente
+		filter.Limit = limit + 1
+	}
+
+	docs, err := s.repo.List(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if limit > 0 && len(docs) > limit {
+		docs = docs[:limit]
+		nextCursor = storage.CursorFor(docs[limit-1]).Encode()
+	}
+
+	return docs, nextCursor, nil
+}
+
+// SendDocumentByID envía a SUNAT el documento almacenado, usando los importes
+// calculados por el servicio y no los que pudiera enviar el cliente
+func (s *DocumentService) SendDocumentByID(id string) (*models.Document, error) {
+	doc, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.SendDocument(doc); err != nil {
+		var transitionErr *models.TransitionError
+		if errors.As(err, &transitionErr) || errors.Is(err, ErrDocumentNotDraft) || errors.Is(err, ErrDocumentBusy) {
+			return nil, err
+		}
+		return doc, err
+	}
 
 	return doc, nil
 }
-func (s *DocumentService) SendDocument(doc *models.Document) error {
+
+// SendDocument firma y envía el documento por el canal que le corresponde
+// (SUNAT u OSE). Solo se pueden enviar borradores; el resultado del envío
+// queda registrado en el historial.
+func (s *DocumentService) SendDocument(doc *models.Document) (err error) {
+	channel := s.senders.channelFor(doc)
+	sender, err := s.senders.sender(channel)
+	if err != nil {
+		return err
+	}
+
+	if doc.Status != models.StatusDraft {
+		return fmt.Errorf("%w: %s is %s", ErrDocumentNotDraft, doc.ID, doc.Status)
+	}
+	release, err := s.claim(doc)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := s.checkPrepayments(doc); err != nil {
+		return err
+	}
+
+	// Se guarda como pendiente antes de contactar a SUNAT para que no se
+	// pueda iniciar un segundo envío del mismo documento. Si no se puede
+	// guardar, no se envía.
+	draft := *doc
+	if err := doc.TransitionTo(models.StatusPending, fmt.Sprintf("Envío por %s", sender.Name())); err != nil {
+		return err
+	}
+	if err := s.persist(doc); err != nil {
+		*doc = draft
+		return err
+	}
+
+	// El resultado del envío se persiste siempre, sea aceptado o rechazado
+	defer func() {
+		if saveErr := s.persist(doc); saveErr != nil {
+			err = errors.Join(err, saveErr)
+		}
+	}()
+
+	signedXML, err := s.signDocument(doc)
+	if err != nil {
+		return revertToDraft(doc, err.Error(), err)
+	}
+
+	baseName := fmt.Sprintf("%s-%s-%s-%s", s.issuer.DocumentNumber, string(doc.Type), doc.Serie, doc.Number)
+	zipContent, err := s.createZipFile(baseName+".xml", signedXML)
+	if err != nil {
+		return revertToDraft(doc, err.Error(), fmt.Errorf("failed to create ZIP: %w", err))
+	}
+
+	cdrContent, err := sender.SendBill(baseName+".zip", zipContent)
+	// Un envío anterior llegó a SUNAT aunque no recibimos la respuesta (por
+	// ejemplo, un timeout): se recupera el CDR ya emitido
+	if sunat.IsDuplicate(err) {
+		cdrContent, err = s.fetchCDR(sender, doc)
+		if err != nil {
+			// El comprobante ya está registrado: reenviarlo solo repetiría
+			// el 1033
+			doc.Channel = channel
+			if tErr := doc.TransitionTo(models.StatusSent, fmt.Sprintf("Ya registrado en %s; CDR pendiente: %v", sender.Name(), err)); tErr != nil {
+				return errors.Join(err, tErr)
+			}
+			return err
+		}
+	}
+	if err != nil {
+		var fault *sunat.Fault
+		if !errors.As(err, &fault) {
+			// El documento no llegó a procesarse: vuelve a borrador para
+			// reenviarlo
+			if sunat.Retryable(err) {
+				return revertToDraft(doc, err.Error(), fmt.Errorf("%w: failed to send document to %s: %v", ErrDeliveryFailed, sender.Name(), err))
+			}
+			return revertToDraft(doc, err.Error(), fmt.Errorf("failed to send document to %s: %w", sender.Name(), err))
+		}
+
+		// Un SOAP Fault es la respuesta del servicio al comprobante. Ante
+		// una excepción el comprobante no se registró y se puede reenviar;
+		// un rechazo es definitivo.
+		sunatErr := newSUNATError(sender.Name(), fault.Code, fault.Message)
+		reason := fmt.Sprintf("%s - %s", fault.Code, fault.Message)
+		if sunatErr.Category == sunat.CategoryException {
+			return revertToDraft(doc, reason, sunatErr)
+		}
+		doc.Channel = channel
+		if err := doc.TransitionTo(models.StatusSent, fmt.Sprintf("Respuesta recibida de %s", sender.Name())); err != nil {
+			return errors.Join(sunatErr, err)
+		}
+		if err := doc.TransitionTo(models.StatusRejected, reason); err != nil {
+			return errors.Join(sunatErr, err)
+		}
+		return sunatErr
+	}
+
+	doc.Channel = channel
+	if err := doc.TransitionTo(models.StatusSent, fmt.Sprintf("Respuesta recibida de %s", sender.Name())); err != nil {
+		return err
+	}
+
+	return s.applyCDR(doc, sender, cdrContent)
+}
+
+// revertToDraft devuelve a borrador un documento cuyo envío no llegó a
+// procesarse y devuelve cause, junto con el error de la transición si falla
+func revertToDraft(doc *models.Document, reason string, cause error) error {
+	if err := doc.TransitionTo(models.StatusDraft, reason); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
+
+// CheckCDR vuelve a consultar con getStatusCdr el CDR de un documento que
+// quedó enviado porque su CDR no se pudo leer o no se pudo obtener
+func (s *DocumentService) CheckCDR(id string) (*models.Document, error) {
+	doc, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	// Las boletas y sus notas se informan en el Resumen Diario: su
+	// respuesta se consulta con el ticket
+	if doc.Status != models.StatusSent || doc.SummaryTicket != "" {
+		return nil, fmt.Errorf("%w: %s is %s", ErrDocumentNotSent, doc.ID, doc.Status)
+	}
+
+	sender, err := s.senders.sender(doc.Channel)
+	if err != nil {
+		return nil, err
+	}
+	status, err := sender.GetStatusCdr(string(doc.Type), doc.Serie, doc.Number)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStatusNotAvailable, sunatErrorFrom(sender.Name(), err))
+	}
+	if status.Code != sunat.CDRStatusExists || len(status.Content) == 0 {
+		return nil, fmt.Errorf("%w: CDR of %s is not available: %s - %s", ErrStatusNotAvailable, doc.ID, status.Code, status.Message)
+	}
+
+	err = s.applyCDR(doc, sender, status.Content)
+	if saveErr := s.persist(doc); saveErr != nil {
+		err = errors.Join(err, saveErr)
+	}
+	return doc, err
+}
+
+// applyCDR guarda el CDR de un documento enviado y lo acepta o rechaza según
+// su código de respuesta. Si el CDR no se puede leer el documento sigue
+// enviado, con el error en el historial, para volver a consultarlo.
+func (s *DocumentService) applyCDR(doc *models.Document, sender Sender, cdrContent []byte) error {
+	baseName := fmt.Sprintf("%s-%s-%s-%s", s.issuer.DocumentNumber, string(doc.Type), doc.Serie, doc.Number)
+	cdrPath := filepath.Join(s.filesDir, "cdr", "R-"+baseName+".zip")
+	if err := os.WriteFile(cdrPath, cdrContent, 0644); err != nil {
+		doc.Note(fmt.Sprintf("No se pudo guardar el CDR: %v", err))
+	}
+
+	cdr, err := ubl.ParseCDR(cdrContent)
+	if err != nil {
+		doc.Note(fmt.Sprintf("CDR de %s ilegible: %v", sender.Name(), err))
+		return fmt.Errorf("%w: %s: %v", ErrCDRNotReadable, doc.ID, err)
+	}
+	doc.CDR = cdr
+
+	// Un CDR con código de error (2000-3999) es un rechazo aunque el
+	// envío haya sido exitoso
+	switch {
+	case !doc.CDR.Accepted():
+		sunatErr := newSUNATError(sender.Name(), doc.CDR.ResponseCode, doc.CDR.Description)
+		if err := doc.TransitionTo(models.StatusRejected, fmt.Sprintf("%s - %s", doc.CDR.ResponseCode, doc.CDR.Description)); err != nil {
+			return errors.Join(sunatErr, err)
+		}
+		return sunatErr
+	case doc.CDR.HasObservations():
+		return doc.TransitionTo(models.StatusAccepted, fmt.Sprintf("Aceptado por %s con %d observaciones", sender.Name(), len(doc.CDR.Observations)))
+	default:
+		return doc.TransitionTo(models.StatusAccepted, fmt.Sprintf("Aceptado por %s", sender.Name()))
+	}
+}
+
+// fetchCDR obtiene el CDR de un documento que SUNAT ya registró. Si la
+// consulta falla o la constancia todavía no está disponible devuelve
+// ErrCDRPending: el CDR se vuelve a consultar con CheckCDR.
+func (s *DocumentService) fetchCDR(sender Sender, doc *models.Document) ([]byte, error) {
+	status, err := sender.GetStatusCdr(string(doc.Type), doc.Serie, doc.Number)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to get CDR of duplicate document %s: %v", ErrCDRPending, doc.ID, err)
+	}
+	if status.Code != sunat.CDRStatusExists || len(status.Content) == 0 {
+		return nil, fmt.Errorf("%w: CDR of duplicate document %s is not available: %s - %s", ErrCDRPending, doc.ID, status.Code, status.Message)
+	}
+
+	doc.Note(fmt.Sprintf("Ya registrado en %s: se usa su CDR", sender.Name()))
+	return status.Content, nil
+}
+
+// signDocument genera el XML UBL según el tipo de documento y lo firma
+func (s *DocumentService) signDocument(doc *models.Document) ([]byte, error) {
+	// Documentos guardados antes de calcular con decimales exactos pueden
+	// tener totales que SUNAT rechazaría
+	if err := doc.CheckTotals(); err != nil {
+		return nil, err
+	}
+
 	// 1. Generar XML según tipo
 	var xmlContent []byte
 	var err error
@@ -123,114 +673,106 @@ func (s *DocumentService) SendDocument(doc *models.Document) error {
 	case models.DocumentTypeFactura, models.DocumentTypeBoleta:
 		invoice, err := ubl.GenerateInvoiceXML(doc, &doc.Issuer)
 		if err != nil {
-			return fmt.Errorf("failed to generate invoice XML: %w", err)
+			return nil, fmt.Errorf("failed to generate invoice XML: %w", err)
 		}
 		xmlContent, err = xml.MarshalIndent(invoice, "", "  ")
 		if err != nil {
-			return fmt.Errorf("failed to marshal invoice XML: %w", err)
+			return nil, fmt.Errorf("failed to marshal invoice XML: %w", err)
 		}
 	case models.DocumentTypeNotaCredito:
 		creditNote, err := ubl.GenerateCreditNoteXML(doc, &doc.Issuer)
 		if err != nil {
-			return fmt.Errorf("failed to generate credit note XML: %w", err)
+			return nil, fmt.Errorf("failed to generate credit note XML: %w", err)
 		}
 		xmlContent, err = xml.MarshalIndent(creditNote, "", "  ")
 		if err != nil {
-			return fmt.Errorf("failed to marshal credit note XML: %w", err)
+			return nil, fmt.Errorf("failed to marshal credit note XML: %w", err)
 		}
 	case models.DocumentTypeNotaDebito:
 		debitNote, err := ubl.GenerateDebitNoteXML(doc, &doc.Issuer)
 		if err != nil {
-			return fmt.Errorf("failed to generate debit note XML: %w", err)
+			return nil, fmt.Errorf("failed to generate debit note XML: %w", err)
 		}
 		xmlContent, err = xml.MarshalIndent(debitNote, "", "  ")
 		if err != nil {
-			return fmt.Errorf("failed to marshal debit note XML: %w", err)
+			return nil, fmt.Errorf("failed to marshal debit note XML: %w", err)
 		}
 	default:
-		return fmt.Errorf("unsupported document type: %s", doc.Type)
+		return nil, fmt.Errorf("unsupported document type: %s", doc.Type)
 	}
 
 	// 2. Agregar declaración XML
 	xmlWithDeclaration := append([]byte(xml.Header), xmlContent...)
 
 	// 3. Firmar XML (antes de enviar)
-	signedXML, err := s.sunatClient.SignXML(xmlWithDeclaration)
+	signedXML, err := s.sign(xmlWithDeclaration)
 	if err != nil {
-		return fmt.Errorf("failed to sign XML: %w", err)
+		return nil, fmt.Errorf("failed to sign XML: %w", err)
 	}
 
 	// 4. Guardar el XML firmado para depuración
 	xmlFileName := fmt.Sprintf("%s-%s-%s-%s-signed.xml", s.issuer.DocumentNumber, string(doc.Type), doc.Serie, doc.Number)
-	xmlPath := filepath.Join("storage", "xml", xmlFileName)
+	xmlPath := filepath.Join(s.filesDir, "xml", xmlFileName)
 	if err := os.WriteFile(xmlPath, signedXML, 0644); err != nil {
 		fmt.Printf("Warning: Failed to save signed XML: %v\n", err)
 	}
 
-	// 5. Generar ID de documento
-	documentID := fmt.Sprintf("%s-%s", doc.Serie, doc.Number)
+	return signedXML, nil
+}
 
-	// 6. Enviar a SUNAT
-	response, err := s.sunatClient.SendToSUNAT(signedXML, string(doc.Type), documentID)
-	if err != nil {
-		doc.Status = models.StatusRejected
-		return fmt.Errorf("failed to send document to SUNAT: %w", err)
-	}
+// sign firma el XML con el certificado del emisor
+func (s *DocumentService) sign(xmlContent []byte) ([]byte, error) {
+	s.signMu.Lock()
+	defer s.signMu.Unlock()
 
-	// 7. Manejar respuesta
-	if response.Success {
-		doc.Status = models.StatusAccepted
-		doc.CDR = &models.CDR{
-			ResponseCode: "0",
-			Description:  "Accepted",
-		}
+	return s.signer.SignXML(xmlContent)
+}
 
-		if len(response.ApplicationResponse) > 0 {
-			cdrPath := filepath.Join("storage", "cdr", fmt.Sprintf("R-%s-%s-%s-%s.zip",
-				s.issuer.DocumentNumber, string(doc.Type), doc.Serie, doc.Number))
-			err = response.SaveApplicationResponse(cdrPath)
-			if err != nil {
-				fmt.Printf("Warning: Failed to save CDR: %v\n", err)
-			}
+// claim reserva los documentos para un envío o una baja. Falla con
+// ErrDocumentBusy si otro envío ya reservó alguno o si alguno cambió en el
+// repositorio desde que se leyó, de modo que dos envíos simultáneos no
+// reclamen el mismo documento. La función devuelta libera la reserva.
+func (s *DocumentService) claim(docs ...*models.Document) (func(), error) {
+	s.claimMu.Lock()
+	defer s.claimMu.Unlock()
+
+	for _, doc := range docs {
+		if s.claimed[doc.ID] {
+			return nil, fmt.Errorf("%w: %s is already being sent", ErrDocumentBusy, doc.ID)
+		}
+		stored, err := s.repo.FindByID(doc.ID)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		doc.Status = models.StatusRejected
-		fmt.Printf("SUNAT response: %+v\n", response)
-		
-		// Try to get the most specific error message available
-		var errorMsg string
-		if response.Message != "" {
-			errorMsg = response.Message
-		} else if response.Error != nil {
-			errorMsg = response.Error.Error()
-		} else {
-			errorMsg = "unknown error"
+		if stored.Status != doc.Status || stored.VoidTicket != doc.VoidTicket || !stored.UpdatedAt.Equal(doc.UpdatedAt) {
+			return nil, fmt.Errorf("%w: %s was modified (now %s)", ErrDocumentBusy, doc.ID, stored.Status)
 		}
-		
-		return fmt.Errorf("document rejected by SUNAT: %s", errorMsg)
 	}
 
-	doc.UpdatedAt = time.Now()
-	return nil
-}
-
-func (s *DocumentService) CheckStatus(ticket string) (*models.CDR, error) {
-	// For now, return a placeholder CDR
-	// TODO: Implement status checking with sunatlib if available
-	cdr := &models.CDR{
-		ResponseCode: "0",
-		Description:  "Status check not implemented yet",
-		Notes:        fmt.Sprintf("Ticket: %s", ticket),
+	if s.claimed == nil {
+		s.claimed = make(map[string]bool)
+	}
+	for _, doc := range docs {
+		s.claimed[doc.ID] = true
 	}
 
-	return cdr, nil
+	return func() {
+		s.claimMu.Lock()
+		defer s.claimMu.Unlock()
+		for _, doc := range docs {
+			delete(s.claimed, doc.ID)
+		}
+	}, nil
 }
 
-func (s *DocumentService) VoidDocument(req *models.VoidDocumentRequest) error {
-	// Generate void communication XML
-	// This would involve creating a summary of voided documents
-	// Implementation depends on SUNAT's specific requirements
-	return fmt.Errorf("void document not implemented yet")
+// persist guarda el estado actual del documento
+func (s *DocumentService) persist(doc *models.Document) error {
+	doc.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(doc); err != nil {
+		return fmt.Errorf("%w: failed to persist document %s: %w", ErrStorage, doc.ID, err)
+	}
+	return nil
 }
 
 func (s *DocumentService) createZipFile(fileName string, content []byte) ([]byte, error) {
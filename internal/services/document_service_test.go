@@ -0,0 +1,467 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"infac/internal/config"
+	"infac/internal/models"
+	"infac/internal/storage"
+	"infac/pkg/sunat"
+	"infac/pkg/sunat/fake"
+)
+
+func TestSendDocumentAccepted(t *testing.T) {
+	s, _ := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	sent, err := s.SendDocumentByID(doc.ID)
+	if err != nil {
+		t.Fatalf("SendDocumentByID: %v", err)
+	}
+	if sent.Status != models.StatusAccepted || sent.CDR == nil || sent.CDR.ResponseCode != "0" {
+		t.Fatalf("sent = %s, CDR %+v", sent.Status, sent.CDR)
+	}
+
+	stored := assertStored(t, s, doc.ID, models.StatusAccepted)
+	if stored.Channel != config.ChannelSUNAT {
+		t.Errorf("channel = %q, want %q", stored.Channel, config.ChannelSUNAT)
+	}
+	cdrPath := filepath.Join(s.filesDir, "cdr", "R-"+testRUC+"-01-"+doc.ID+".zip")
+	if _, err := os.Stat(cdrPath); err != nil {
+		t.Errorf("CDR was not saved: %v", err)
+	}
+}
+
+func TestSendDocumentAcceptedWithObservations(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-01-F001-*", fake.Behavior{
+		Observations: []string{"4252 - El dato ingresado como atributo @listName no cumple con el formato establecido"},
+	})
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	if _, err := s.SendDocumentByID(doc.ID); err != nil {
+		t.Fatalf("SendDocumentByID: %v", err)
+	}
+
+	stored := assertStored(t, s, doc.ID, models.StatusAccepted)
+	if !stored.CDR.HasObservations() || stored.CDR.Observations[0].Code != "4252" {
+		t.Errorf("observations = %+v", stored.CDR.Observations)
+	}
+	last := stored.StatusHistory[len(stored.StatusHistory)-1]
+	if !strings.Contains(last.Reason, "1 observaciones") {
+		t.Errorf("last change = %q", last.Reason)
+	}
+}
+
+func TestSendDocumentRejectedByCDR(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-01-F001-*", fake.Behavior{ResponseCode: "2800", Description: "Tipo de documento no permitido"})
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	_, err := s.SendDocumentByID(doc.ID)
+	if !errors.Is(err, ErrDocumentRejected) {
+		t.Fatalf("SendDocumentByID = %v, want ErrDocumentRejected", err)
+	}
+	var sunatErr *SUNATError
+	if !errors.As(err, &sunatErr) || sunatErr.Code.Code != "2800" {
+		t.Errorf("error = %#v, want SUNAT code 2800", err)
+	}
+
+	stored := assertStored(t, s, doc.ID, models.StatusRejected)
+	if stored.CDR == nil || stored.CDR.ResponseCode != "2800" {
+		t.Errorf("CDR = %+v, want the rejection", stored.CDR)
+	}
+}
+
+func TestSendDocumentRejectedByFault(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-01-F001-*", fake.Behavior{FaultCode: "2017", FaultMessage: "El numero de documento de identidad del receptor debe ser RUC"})
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	if _, err := s.SendDocumentByID(doc.ID); !errors.Is(err, ErrDocumentRejected) {
+		t.Fatalf("SendDocumentByID = %v, want ErrDocumentRejected", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusRejected)
+}
+
+// Una excepción (0100-1999) indica que el comprobante no se registró: vuelve
+// a borrador para corregirlo, sin tratarse como rechazo ni como falla de red
+func TestSendDocumentException(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-01-F001-*", fake.Behavior{FaultCode: "0306"})
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	_, err := s.SendDocumentByID(doc.ID)
+	var sunatErr *SUNATError
+	if !errors.As(err, &sunatErr) || sunatErr.Category != sunat.CategoryException {
+		t.Fatalf("SendDocumentByID = %v, want a SUNAT exception", err)
+	}
+	if errors.Is(err, ErrDocumentRejected) || errors.Is(err, ErrDeliveryFailed) {
+		t.Errorf("exception %v is reported as a rejection or delivery failure", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusDraft)
+}
+
+func TestSendDocumentServiceUnavailable(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-01-F001-*", fake.Behavior{FaultCode: "0109"})
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	if _, err := s.SendDocumentByID(doc.ID); !errors.Is(err, ErrDeliveryFailed) {
+		t.Fatalf("SendDocumentByID = %v, want ErrDeliveryFailed", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusDraft)
+}
+
+// Tras un timeout el documento vuelve a borrador; el reenvío recupera el CDR
+// que SUNAT emitió para el primer envío
+func TestSendDocumentTimeout(t *testing.T) {
+	s, server := newTestService(t)
+	client := s.senders.senders[config.ChannelSUNAT].(*sunat.Client)
+	client.HTTPClient.Timeout = 100 * time.Millisecond
+	server.AddRule(fake.Rule{Pattern: "*-01-F001-*", Behavior: fake.Behavior{Delay: fake.Duration(time.Second)}, Times: 1})
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	if _, err := s.SendDocumentByID(doc.ID); !errors.Is(err, ErrDeliveryFailed) {
+		t.Fatalf("SendDocumentByID = %v, want ErrDeliveryFailed", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusDraft)
+
+	// El servidor registró el primer envío aunque la respuesta no llegó
+	time.Sleep(time.Second)
+	if _, err := s.SendDocumentByID(doc.ID); err != nil {
+		t.Fatalf("resend: %v", err)
+	}
+	stored := assertStored(t, s, doc.ID, models.StatusAccepted)
+
+	var operations []string
+	for _, req := range server.Requests() {
+		operations = append(operations, req.Operation)
+	}
+	if got := strings.Join(operations, ","); got != "sendBill,sendBill,getStatusCdr" {
+		t.Errorf("operations = %s", got)
+	}
+	if !hasHistory(stored, "se usa su CDR") {
+		t.Errorf("history = %+v", stored.StatusHistory)
+	}
+}
+
+// registeredSender entrega el comprobante al canal real pero responde como
+// si ya estuviera registrado (1033), y la primera consulta de su CDR falla
+type registeredSender struct {
+	Sender
+	queried *bool
+}
+
+func (r registeredSender) SendBill(fileName string, zipContent []byte) ([]byte, error) {
+	if _, err := r.Sender.SendBill(fileName, zipContent); err != nil {
+		return nil, err
+	}
+	return nil, &sunat.Fault{Code: sunat.FaultDuplicate, Message: "El comprobante fue registrado previamente con otros datos"}
+}
+
+func (r registeredSender) GetStatusCdr(docType, serie, number string) (*sunat.CDRStatus, error) {
+	if !*r.queried {
+		*r.queried = true
+		return nil, errors.New("connection reset by peer")
+	}
+	return r.Sender.GetStatusCdr(docType, serie, number)
+}
+
+// Un documento ya registrado cuyo CDR no se pudo obtener queda enviado, sin
+// reenviarlo, hasta consultar su CDR
+func TestSendDocumentDuplicateCDRPending(t *testing.T) {
+	s, server := newTestService(t)
+	direct := s.senders.senders[config.ChannelSUNAT]
+	s.senders.senders[config.ChannelSUNAT] = registeredSender{direct, new(bool)}
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	_, err := s.SendDocumentByID(doc.ID)
+	if !errors.Is(err, ErrCDRPending) {
+		t.Fatalf("SendDocumentByID = %v, want ErrCDRPending", err)
+	}
+	if errors.Is(err, ErrDeliveryFailed) {
+		t.Errorf("SendDocumentByID = %v, a registered document is not a delivery failure", err)
+	}
+	stored := assertStored(t, s, doc.ID, models.StatusSent)
+	if !hasHistory(stored, "CDR pendiente") {
+		t.Errorf("history = %+v", stored.StatusHistory)
+	}
+
+	checked, err := s.CheckCDR(doc.ID)
+	if err != nil {
+		t.Fatalf("CheckCDR: %v", err)
+	}
+	if checked.Status != models.StatusAccepted {
+		t.Errorf("checked = %s", checked.Status)
+	}
+	assertStored(t, s, doc.ID, models.StatusAccepted)
+
+	var operations []string
+	for _, req := range server.Requests() {
+		operations = append(operations, req.Operation)
+	}
+	if got := strings.Join(operations, ","); got != "sendBill,getStatusCdr" {
+		t.Errorf("operations = %s", got)
+	}
+}
+
+func TestSendDocumentNotDraft(t *testing.T) {
+	s, _ := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+	if _, err := s.SendDocumentByID(doc.ID); err != nil {
+		t.Fatalf("SendDocumentByID: %v", err)
+	}
+
+	sent, err := s.SendDocumentByID(doc.ID)
+	if !errors.Is(err, ErrDocumentNotDraft) {
+		t.Fatalf("second send = %v, want ErrDocumentNotDraft", err)
+	}
+	if sent != nil {
+		t.Errorf("second send returned the document")
+	}
+}
+
+// failingUpdateRepository no puede guardar cambios de documentos existentes
+type failingUpdateRepository struct {
+	storage.DocumentRepository
+}
+
+func (r failingUpdateRepository) Update(doc *models.Document) error {
+	return errors.New("database is locked")
+}
+
+// Si no se puede guardar el documento como pendiente no se envía a SUNAT
+func TestSendDocumentPendingNotSaved(t *testing.T) {
+	s, server := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+	repo := s.repo
+	s.repo = failingUpdateRepository{repo}
+
+	if err := s.SendDocument(doc); !errors.Is(err, ErrStorage) {
+		t.Fatalf("SendDocument = %v, want ErrStorage", err)
+	}
+	if doc.Status != models.StatusDraft {
+		t.Errorf("status = %s, want draft", doc.Status)
+	}
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("SUNAT received %d requests, want 0", n)
+	}
+
+	// El documento se puede enviar cuando el repositorio se recupera
+	s.repo = repo
+	if err := s.SendDocument(doc); err != nil {
+		t.Fatalf("SendDocument after recovery: %v", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusAccepted)
+}
+
+// updateOnceRepository guarda solo el primer cambio de cada documento
+type updateOnceRepository struct {
+	storage.DocumentRepository
+	updates *int
+}
+
+func (r updateOnceRepository) Update(doc *models.Document) error {
+	*r.updates++
+	if *r.updates > 1 {
+		return errors.New("disk full")
+	}
+	return r.DocumentRepository.Update(doc)
+}
+
+// Un resultado que no se pudo guardar se informa al llamador
+func TestSendDocumentResultNotSaved(t *testing.T) {
+	s, server := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+	s.repo = updateOnceRepository{s.repo, new(int)}
+
+	if err := s.SendDocument(doc); !errors.Is(err, ErrStorage) {
+		t.Fatalf("SendDocument = %v, want ErrStorage", err)
+	}
+	if doc.Status != models.StatusAccepted {
+		t.Errorf("status = %s, want accepted", doc.Status)
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("SUNAT received %d requests, want 1", n)
+	}
+}
+
+// corruptCDRSender entrega el comprobante al canal real pero devuelve un CDR
+// que no se puede leer
+type corruptCDRSender struct {
+	Sender
+}
+
+func (c corruptCDRSender) SendBill(fileName string, zipContent []byte) ([]byte, error) {
+	if _, err := c.Sender.SendBill(fileName, zipContent); err != nil {
+		return nil, err
+	}
+	return []byte("not a zip"), nil
+}
+
+// Un CDR ilegible deja el documento enviado hasta consultarlo con
+// getStatusCdr
+func TestSendDocumentCDRNotReadable(t *testing.T) {
+	s, _ := newTestService(t)
+	direct := s.senders.senders[config.ChannelSUNAT]
+	s.senders.senders[config.ChannelSUNAT] = corruptCDRSender{direct}
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	if _, err := s.SendDocumentByID(doc.ID); !errors.Is(err, ErrCDRNotReadable) {
+		t.Fatalf("SendDocumentByID = %v, want ErrCDRNotReadable", err)
+	}
+	stored := assertStored(t, s, doc.ID, models.StatusSent)
+	if !hasHistory(stored, "ilegible") {
+		t.Errorf("history = %+v", stored.StatusHistory)
+	}
+
+	s.senders.senders[config.ChannelSUNAT] = direct
+	checked, err := s.CheckCDR(doc.ID)
+	if err != nil {
+		t.Fatalf("CheckCDR: %v", err)
+	}
+	if checked.Status != models.StatusAccepted {
+		t.Errorf("checked = %s", checked.Status)
+	}
+	assertStored(t, s, doc.ID, models.StatusAccepted)
+}
+
+// Dos envíos simultáneos del mismo borrador llegan una sola vez a SUNAT
+func TestSendDocumentConcurrentClaim(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-01-F001-*", fake.Behavior{Delay: fake.Duration(200 * time.Millisecond)})
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	// Cada envío parte de su propia copia del borrador, como dos requests
+	// que lo leyeron a la vez
+	const senders = 5
+	copies := make([]*models.Document, senders)
+	for i := range copies {
+		draft, err := s.GetDocument(doc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copies[i] = draft
+	}
+
+	errs := make(chan error, senders)
+	var wg sync.WaitGroup
+	for _, draft := range copies {
+		wg.Add(1)
+		go func(draft *models.Document) {
+			defer wg.Done()
+			errs <- s.SendDocument(draft)
+		}(draft)
+	}
+	wg.Wait()
+	close(errs)
+
+	accepted := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			accepted++
+		case errors.Is(err, ErrDocumentBusy):
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("%d sends succeeded, want 1", accepted)
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("SUNAT received %d requests, want 1", n)
+	}
+	assertStored(t, s, doc.ID, models.StatusAccepted)
+}
+
+// Un documento leído antes de que otro proceso lo modificara no se envía
+func TestSendDocumentStaleCopy(t *testing.T) {
+	s, server := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+	stale, err := s.GetDocument(doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SendDocumentByID(doc.ID); err != nil {
+		t.Fatalf("SendDocumentByID: %v", err)
+	}
+	// La copia anterior sigue como borrador en memoria
+	if err := s.SendDocument(stale); !errors.Is(err, ErrDocumentBusy) {
+		t.Fatalf("SendDocument(stale) = %v, want ErrDocumentBusy", err)
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("SUNAT received %d requests, want 1", n)
+	}
+}
+
+// La modificación conserva la serie y el número del documento de la ruta
+func TestUpdateDocumentKeepsSerieAndNumber(t *testing.T) {
+	s, _ := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	req := testRequest(t, models.DocumentTypeFactura)
+	req.Lines[0].Description = "Item corregido"
+	updated, err := s.UpdateDocument(doc.ID, req)
+	if err != nil {
+		t.Fatalf("UpdateDocument without serie and number: %v", err)
+	}
+	if updated.ID != doc.ID || updated.Serie != doc.Serie || updated.Number != doc.Number {
+		t.Fatalf("updated = %s (%s %s), want %s", updated.ID, updated.Serie, updated.Number, doc.ID)
+	}
+	if stored := assertStored(t, s, doc.ID, models.StatusDraft); stored.Lines[0].Description != "Item corregido" {
+		t.Errorf("stored description = %q", stored.Lines[0].Description)
+	}
+
+	// El mismo correlativo sin ceros a la izquierda es el mismo documento
+	req.Serie, req.Number = doc.Serie, "1"
+	if _, err := s.UpdateDocument(doc.ID, req); err != nil {
+		t.Errorf("UpdateDocument with number 1: %v", err)
+	}
+
+	for name, change := range map[string]func(*models.CreateDocumentRequest){
+		"serie":  func(r *models.CreateDocumentRequest) { r.Serie = "F002" },
+		"number": func(r *models.CreateDocumentRequest) { r.Number = "2" },
+		"type":   func(r *models.CreateDocumentRequest) { r.Type = models.DocumentTypeBoleta },
+	} {
+		req := testRequest(t, models.DocumentTypeFactura)
+		change(req)
+		if _, err := s.UpdateDocument(doc.ID, req); err == nil {
+			t.Errorf("UpdateDocument changing the %s succeeded", name)
+		}
+	}
+	if _, err := s.GetDocument("F002-00000001"); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("GetDocument(F002-00000001) = %v, want ErrDocumentNotFound", err)
+	}
+}
+
+func TestCreateDocumentExists(t *testing.T) {
+	s, _ := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	req := testRequest(t, models.DocumentTypeFactura)
+	req.Serie, req.Number = doc.Serie, "1"
+	_, err := s.CreateDocument(req)
+	if !errors.Is(err, ErrDocumentExists) {
+		t.Fatalf("CreateDocument = %v, want ErrDocumentExists", err)
+	}
+	if errors.Is(err, ErrStorage) {
+		t.Errorf("CreateDocument = %v, a duplicate is not a storage error", err)
+	}
+}
+
+func TestCreateDocumentStorageError(t *testing.T) {
+	s, _ := newTestService(t)
+	s.repo.(*storage.SQLiteRepository).Close()
+
+	if _, err := s.CreateDocument(testRequest(t, models.DocumentTypeFactura)); !errors.Is(err, ErrStorage) {
+		t.Fatalf("CreateDocument = %v, want ErrStorage", err)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+
+	"infac/internal/models"
+)
+
+var (
+	countryCodePattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+	currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// resolveExchangeRate valida el tipo de cambio declarado. En moneda
+// extranjera convierte a soles por defecto.
+func resolveExchangeRate(doc *models.Document, req *models.CreateDocumentRequest) error {
+	if req.ExchangeRate == nil {
+		return nil
+	}
+	rate := *req.ExchangeRate
+	if rate.TargetCurrencyCode == "" && doc.CurrencyCode != "PEN" {
+		rate.TargetCurrencyCode = "PEN"
+	}
+	if !currencyCodePattern.MatchString(rate.TargetCurrencyCode) || rate.TargetCurrencyCode == doc.CurrencyCode {
+		return fmt.Errorf("exchange_rate: target_currency_code must be a currency other than %s", doc.CurrencyCode)
+	}
+	if rate.Rate.Sign() <= 0 || !rate.Rate.Exact(6) {
+		return fmt.Errorf("exchange_rate: rate must be positive with up to 6 decimals")
+	}
+	doc.ExchangeRate = &rate
+	return nil
+}
+
+// requirePENRate exige el tipo de cambio a soles de los documentos en
+// moneda extranjera, para los importes que se informan en soles
+func requirePENRate(doc *models.Document, what string) error {
+	if doc.CurrencyCode == "PEN" {
+		return nil
+	}
+	if doc.ExchangeRate == nil || doc.ExchangeRate.TargetCurrencyCode != "PEN" {
+		return fmt.Errorf("%s in %s requires the exchange_rate to PEN", what, doc.CurrencyCode)
+	}
+	return nil
+}
+
+// resolveExport valida el adquirente y, en las
+// exportaciones (tipos de operación 0200 a 0208), que las líneas estén
+// afectas a exportación (40), que el adquirente sea no domiciliado y que el
+// documento use una moneda extranjera o declare el tipo de cambio
+func resolveExport(doc *models.Document, req *models.CreateDocumentRequest) error {
+	if doc.Customer.DocumentType != "" && !models.ValidIdentityDocumentType(doc.Customer.DocumentType) {
+		return fmt.Errorf("customer: unknown document_type %s (catalog 06)", doc.Customer.DocumentType)
+	}
+
+	operation := doc.Operation()
+	if !operation.Export() {
+		for _, line := range doc.Lines {
+			if line.Affectation() == models.AffectationExport {
+				return fmt.Errorf("line %s: igv_affectation 40 requires an export operation_type (0200 to 0208)", line.ID)
+			}
+		}
+		if req.DestinationCountry != "" || req.Incoterm != "" {
+			return fmt.Errorf("destination_country and incoterm apply only to exports (0200 to 0208)")
+		}
+		return nil
+	}
+
+	if doc.Type != models.DocumentTypeFactura {
+		return fmt.Errorf("exports are issued only with invoices (01)")
+	}
+	if operation.DomiciledCustomer() {
+		if doc.Customer.DocumentType != models.IdentityRUC {
+			return fmt.Errorf("operation_type %s requires a customer with RUC", operation)
+		}
+	} else if !doc.Customer.NonDomiciled() {
+		return fmt.Errorf("operation_type %s requires a non-domiciled customer (document_type 0, 7, A, B, C, D, E or G)", operation)
+	}
+	for _, line := range doc.Lines {
+		if line.Affectation() != models.AffectationExport {
+			return fmt.Errorf("line %s: exports require igv_affectation 40", line.ID)
+		}
+	}
+	if doc.CurrencyCode == "PEN" && doc.ExchangeRate == nil {
+		return fmt.Errorf("exports in PEN must declare the exchange_rate of the agreed foreign currency")
+	}
+
+	if req.DestinationCountry != "" {
+		if !countryCodePattern.MatchString(req.DestinationCountry) || req.DestinationCountry == "PE" {
+			return fmt.Errorf("destination_country must be a foreign ISO 3166-1 alpha-2 code")
+		}
+		doc.DestinationCountry = req.DestinationCountry
+	}
+	if req.Incoterm != "" {
+		if operation != models.OperationExportGoods {
+			return fmt.Errorf("incoterm applies only to exports of goods (0200)")
+		}
+		if !models.ValidIncoterm(req.Incoterm) {
+			return fmt.Errorf("unknown incoterm %s (Incoterms 2020)", req.Incoterm)
+		}
+		doc.Incoterm = req.Incoterm
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"infac/internal/models"
+	"infac/internal/storage"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+// IdempotencyService guarda la respuesta de cada request con Idempotency-Key
+// para devolverla otra vez si el cliente la reintenta, por ejemplo tras un
+// timeout, en lugar de crear o enviar el documento dos veces
+type IdempotencyService struct {
+	repo storage.IdempotencyRepository
+	ttl  time.Duration
+
+	mu sync.Mutex
+	// inFlight guarda el hash de las requests que se están procesando
+	inFlight  map[string]string
+	lastPurge time.Time
+}
+
+func NewIdempotencyService(repo storage.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	return &IdempotencyService{
+		repo:     repo,
+		ttl:      ttl,
+		inFlight: make(map[string]string),
+	}
+}
+
+// Begin reserva la clave para la request. Si la misma request ya se procesó
+// devuelve su respuesta guardada; si la clave se usó con otra request
+// devuelve ErrIdempotencyKeyReused. Una clave reservada se libera con
+// Complete o Release.
+func (s *IdempotencyService) Begin(key, requestHash string) (*models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastPurge) > s.ttl {
+		s.purge()
+	}
+
+	if hash, ok := s.inFlight[key]; ok {
+		if hash != requestHash {
+			return nil, ErrIdempotencyKeyReused
+		}
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	record, err := s.repo.FindIdempotencyRecord(key)
+	switch {
+	case errors.Is(err, storage.ErrIdempotencyKeyNotFound):
+	case err != nil:
+		return nil, err
+	case time.Since(record.CreatedAt) < s.ttl:
+		if record.RequestHash != requestHash {
+			return nil, ErrIdempotencyKeyReused
+		}
+		return record, nil
+	}
+
+	s.inFlight[key] = requestHash
+	return nil, nil
+}
+
+// Complete guarda la respuesta de la request y libera la clave. Los errores
+// del servidor (5xx) no se guardan para que el cliente pueda reintentar con
+// la misma clave.
+func (s *IdempotencyService) Complete(key, requestHash string, statusCode int, response []byte) {
+	defer s.Release(key)
+
+	if statusCode >= 500 {
+		return
+	}
+
+	record := &models.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		StatusCode:  statusCode,
+		Response:    response,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.SaveIdempotencyRecord(record); err != nil {
+		fmt.Printf("Warning: Failed to save idempotency key: %v\n", err)
+	}
+}
+
+// Release libera la clave sin guardar una respuesta
+func (s *IdempotencyService) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, key)
+}
+
+// purge elimina los registros vencidos; se ejecuta como mucho una vez por ttl
+func (s *IdempotencyService) purge() {
+	s.lastPurge = time.Now()
+	if _, err := s.repo.DeleteIdempotencyRecords(s.lastPurge.Add(-s.ttl)); err != nil {
+		fmt.Printf("Warning: Failed to delete expired idempotency keys: %v\n", err)
+	}
+}
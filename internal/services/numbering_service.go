@@ -0,0 +1,265 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"infac/internal/config"
+	"infac/internal/models"
+	"infac/internal/storage"
+)
+
+var (
+	// Las series tienen 4 caracteres: la letra del tipo de comprobante y 3
+	// alfanuméricos en mayúsculas
+	seriePatterns = map[string]*regexp.Regexp{
+		"F": regexp.MustCompile(`^F[A-Z0-9]{3}$`),
+		"B": regexp.MustCompile(`^B[A-Z0-9]{3}$`),
+	}
+)
+
+// NumberingService asigna series y correlativos por RUC, tipo y serie
+type NumberingService struct {
+	issuer    *models.Company
+	sequences storage.SequenceRepository
+	documents storage.DocumentRepository
+	series    []config.SeriesConfig
+}
+
+func NewNumberingService(issuer *models.Company, sequences storage.SequenceRepository, documents storage.DocumentRepository, series []config.SeriesConfig) *NumberingService {
+	return &NumberingService{
+		issuer:    issuer,
+		sequences: sequences,
+		documents: documents,
+		series:    series,
+	}
+}
+
+// Assign completa la serie (si falta) con la configurada para el
+// establecimiento, valida su prefijo y asigna el siguiente correlativo cuando
+// el documento no trae número. Un número explícito se respeta y reserva el
+// correlativo para que no vuelva a asignarse.
+func (n *NumberingService) Assign(doc *models.Document, establishmentCode string) error {
+	if doc.Serie == "" {
+		serie, err := n.defaultSerie(doc, establishmentCode)
+		if err != nil {
+			return err
+		}
+		doc.Serie = serie
+	}
+
+	if err := ValidateSerie(doc.Type, doc.Serie, doc.RelatedDocuments); err != nil {
+		return err
+	}
+	if err := n.checkSerieType(doc.Type, doc.Serie); err != nil {
+		return err
+	}
+
+	key := n.key(doc.Type, doc.Serie)
+
+	if doc.Number == "" {
+		next, err := n.sequences.Next(key)
+		if err != nil {
+			return fmt.Errorf("%w: failed to number serie %s: %w", ErrStorage, doc.Serie, err)
+		}
+		if next > 99999999 {
+			return fmt.Errorf("serie %s has run out of numbers", doc.Serie)
+		}
+		doc.Number = fmt.Sprintf("%08d", next)
+	} else {
+		if !models.ValidNumber(doc.Number) {
+			return fmt.Errorf("invalid number %q: must have between 1 and 8 digits", doc.Number)
+		}
+		value, _ := strconv.ParseInt(doc.Number, 10, 64)
+		if value == 0 {
+			return fmt.Errorf("invalid number %q: must be greater than zero", doc.Number)
+		}
+		if err := n.sequences.Reserve(key, value); err != nil {
+			return fmt.Errorf("%w: failed to reserve number %s: %w", ErrStorage, doc.Number, err)
+		}
+		// "7" y "00000007" son el mismo correlativo y el mismo documento
+		doc.Number = models.NormalizeNumber(doc.Number)
+	}
+
+	doc.ID = models.DocumentID(doc.Serie, doc.Number)
+	return nil
+}
+
+// NextSummaryID asigna el identificador de un resumen (RA-YYYYMMDD-n o
+// RC-YYYYMMDD-n). El correlativo se reinicia cada día de generación.
+func (n *NumberingService) NextSummaryID(summaryType models.SummaryType, issueDate time.Time) (string, error) {
+	day := issueDate.Format("20060102")
+	next, err := n.sequences.Next(storage.SequenceKey{
+		RUC:          n.issuer.DocumentNumber,
+		DocumentType: string(summaryType),
+		Serie:        day,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s-%s-%d", summaryType, day, next), nil
+}
+
+// ValidateSerie comprueba la serie según el tipo de documento: F para
+// facturas, B para boletas y, para notas, la letra del comprobante que
+// modifican (FC01/FD01 para facturas, BC01/BD01 para boletas), seguida de 3
+// caracteres alfanuméricos en mayúsculas
+func ValidateSerie(docType models.DocumentType, serie string, related []models.RelatedDocument) error {
+	expected := seriePrefix(docType, related)
+	if expected == "" {
+		return fmt.Errorf("unsupported document type: %s", docType)
+	}
+
+	if !seriePatterns[expected].MatchString(serie) {
+		return fmt.Errorf("invalid serie %q for document type %s: must be %s followed by 3 uppercase alphanumeric characters", serie, docType, expected)
+	}
+
+	return nil
+}
+
+// ValidateSeries comprueba las series configuradas. El ID de un documento
+// (serie-número) no incluye el tipo, por lo que cada serie se asigna a un
+// solo tipo de documento.
+func ValidateSeries(series []config.SeriesConfig) error {
+	types := make(map[string]string)
+	for _, s := range series {
+		// Las notas de boletas usan series B
+		var related []models.RelatedDocument
+		if strings.HasPrefix(s.Serie, "B") {
+			related = []models.RelatedDocument{{DocumentType: models.DocumentTypeBoleta}}
+		}
+		if err := ValidateSerie(models.DocumentType(s.DocumentType), s.Serie, related); err != nil {
+			return fmt.Errorf("numbering series: %w", err)
+		}
+
+		if docType, ok := types[s.Serie]; ok && docType != s.DocumentType {
+			return fmt.Errorf("numbering series: serie %s is configured for document types %s and %s", s.Serie, docType, s.DocumentType)
+		}
+		types[s.Serie] = s.DocumentType
+	}
+	return nil
+}
+
+// checkSerieType rechaza una serie configurada o ya usada para otro tipo de
+// documento: sus documentos tendrían los mismos IDs
+func (n *NumberingService) checkSerieType(docType models.DocumentType, serie string) error {
+	for _, series := range n.series {
+		if series.Serie == serie && series.DocumentType != string(docType) {
+			return fmt.Errorf("serie %s is configured for document type %s", serie, series.DocumentType)
+		}
+	}
+
+	docs, err := n.documents.List(storage.DocumentFilter{Serie: serie, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("%w: failed to check serie %s: %w", ErrStorage, serie, err)
+	}
+	if len(docs) > 0 && docs[0].Type != docType {
+		return fmt.Errorf("serie %s is used for document type %s", serie, docs[0].Type)
+	}
+	return nil
+}
+
+func seriePrefix(docType models.DocumentType, related []models.RelatedDocument) string {
+	switch docType {
+	case models.DocumentTypeFactura:
+		return "F"
+	case models.DocumentTypeBoleta:
+		return "B"
+	case models.DocumentTypeNotaCredito, models.DocumentTypeNotaDebito:
+		if len(related) > 0 && related[0].DocumentType == models.DocumentTypeBoleta {
+			return "B"
+		}
+		return "F"
+	default:
+		return ""
+	}
+}
+
+func (n *NumberingService) defaultSerie(doc *models.Document, establishmentCode string) (string, error) {
+	if establishmentCode == "" {
+		establishmentCode = n.issuer.EstablishmentCode
+	}
+	if establishmentCode == "" {
+		establishmentCode = "0000"
+	}
+
+	prefix := seriePrefix(doc.Type, doc.RelatedDocuments)
+	for _, series := range n.series {
+		if series.EstablishmentCode == establishmentCode &&
+			series.DocumentType == string(doc.Type) &&
+			strings.HasPrefix(series.Serie, prefix) {
+			return series.Serie, nil
+		}
+	}
+
+	return "", fmt.Errorf("no serie configured for document type %s in establishment %s", doc.Type, establishmentCode)
+}
+
+func (n *NumberingService) key(docType models.DocumentType, serie string) storage.SequenceKey {
+	return storage.SequenceKey{
+		RUC:          n.issuer.DocumentNumber,
+		DocumentType: string(docType),
+		Serie:        serie,
+	}
+}
+
+// SerieStatus resume el estado de la numeración de una serie
+type SerieStatus struct {
+	DocumentType      models.DocumentType `json:"document_type"`
+	Serie             string              `json:"serie"`
+	EstablishmentCode string              `json:"establishment_code,omitempty"`
+	LastNumber        int64               `json:"last_number"`
+	Gaps              []string            `json:"gaps,omitempty"`
+}
+
+// Series lista las series configuradas con su último correlativo
+func (n *NumberingService) Series() ([]SerieStatus, error) {
+	var result []SerieStatus
+	for _, series := range n.series {
+		last, err := n.sequences.Current(n.key(models.DocumentType(series.DocumentType), series.Serie))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, SerieStatus{
+			DocumentType:      models.DocumentType(series.DocumentType),
+			Serie:             series.Serie,
+			EstablishmentCode: series.EstablishmentCode,
+			LastNumber:        last,
+		})
+	}
+	return result, nil
+}
+
+// Gaps detecta los correlativos asignados hasta el último número que no
+// tienen un documento almacenado, para auditoría
+func (n *NumberingService) Gaps(docType models.DocumentType, serie string) (*SerieStatus, error) {
+	last, err := n.sequences.Current(n.key(docType, serie))
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := n.documents.List(storage.DocumentFilter{Type: docType, Serie: serie})
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[int64]bool, len(docs))
+	for _, doc := range docs {
+		if value, err := strconv.ParseInt(doc.Number, 10, 64); err == nil {
+			used[value] = true
+		}
+	}
+
+	status := &SerieStatus{DocumentType: docType, Serie: serie, LastNumber: last, Gaps: []string{}}
+	for number := int64(1); number <= last; number++ {
+		if !used[number] {
+			status.Gaps = append(status.Gaps, fmt.Sprintf("%08d", number))
+		}
+	}
+
+	return status, nil
+}
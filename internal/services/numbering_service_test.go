@@ -0,0 +1,162 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"infac/internal/config"
+	"infac/internal/models"
+	"infac/internal/storage"
+)
+
+func newTestNumbering(t *testing.T, series []config.SeriesConfig) (*NumberingService, *storage.SQLiteRepository) {
+	t.Helper()
+
+	repo, err := storage.NewSQLiteRepository(filepath.Join(t.TempDir(), "infac.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	issuer := &models.Company{DocumentNumber: testRUC}
+	return NewNumberingService(issuer, repo, repo, series), repo
+}
+
+func TestAssignNormalizesNumber(t *testing.T) {
+	n, _ := newTestNumbering(t, nil)
+
+	for _, number := range []string{"7", "007", "00000007"} {
+		doc := &models.Document{Type: models.DocumentTypeFactura, Serie: "F001", Number: number}
+		if err := n.Assign(doc, ""); err != nil {
+			t.Fatalf("Assign(%q): %v", number, err)
+		}
+		if doc.Number != "00000007" || doc.ID != "F001-00000007" {
+			t.Errorf("Assign(%q) = %s / %s, want 00000007 / F001-00000007", number, doc.Number, doc.ID)
+		}
+	}
+
+	// El siguiente correlativo automático continúa después del reservado
+	doc := &models.Document{Type: models.DocumentTypeFactura, Serie: "F001"}
+	if err := n.Assign(doc, ""); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	if doc.ID != "F001-00000008" {
+		t.Errorf("next ID = %s, want F001-00000008", doc.ID)
+	}
+}
+
+func TestDocumentID(t *testing.T) {
+	tests := map[string]string{
+		"7":         "F001-00000007",
+		"00000007":  "F001-00000007",
+		"12345678":  "F001-12345678",
+		"123456789": "F001-123456789",
+		"7a":        "F001-7a",
+	}
+	for number, want := range tests {
+		if got := models.DocumentID("F001", number); got != want {
+			t.Errorf("DocumentID(F001, %q) = %s, want %s", number, got, want)
+		}
+	}
+}
+
+func TestAssignRejectsInvalidNumber(t *testing.T) {
+	n, _ := newTestNumbering(t, nil)
+
+	for _, number := range []string{"0", "00000000", "123456789", "-1", "+1", "1e3", "12a", " 1", "１"} {
+		doc := &models.Document{Type: models.DocumentTypeFactura, Serie: "F001", Number: number}
+		if err := n.Assign(doc, ""); err == nil {
+			t.Errorf("Assign(%q) = %s, want error", number, doc.ID)
+		}
+	}
+}
+
+func TestValidateSerie(t *testing.T) {
+	boleta := []models.RelatedDocument{{DocumentType: models.DocumentTypeBoleta}}
+	factura := []models.RelatedDocument{{DocumentType: models.DocumentTypeFactura}}
+	tests := []struct {
+		docType models.DocumentType
+		serie   string
+		related []models.RelatedDocument
+		valid   bool
+	}{
+		{models.DocumentTypeFactura, "F001", nil, true},
+		{models.DocumentTypeFactura, "FZ9A", nil, true},
+		{models.DocumentTypeFactura, "B001", nil, false},
+		{models.DocumentTypeFactura, "f001", nil, false},
+		{models.DocumentTypeFactura, "F01", nil, false},
+		{models.DocumentTypeFactura, "F0001", nil, false},
+		{models.DocumentTypeFactura, "F-01", nil, false},
+		{models.DocumentTypeFactura, "0001", nil, false},
+		{models.DocumentTypeBoleta, "B001", nil, true},
+		{models.DocumentTypeBoleta, "F001", nil, false},
+		{models.DocumentTypeNotaCredito, "FC01", factura, true},
+		{models.DocumentTypeNotaCredito, "BC01", factura, false},
+		{models.DocumentTypeNotaCredito, "BC01", boleta, true},
+		{models.DocumentTypeNotaDebito, "BD01", boleta, true},
+		{models.DocumentTypeNotaDebito, "FD01", boleta, false},
+		{models.DocumentType("09"), "T001", nil, false},
+	}
+	for _, tt := range tests {
+		err := ValidateSerie(tt.docType, tt.serie, tt.related)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateSerie(%s, %q) = %v, want valid %v", tt.docType, tt.serie, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateSeries(t *testing.T) {
+	valid := []config.SeriesConfig{
+		{EstablishmentCode: "0000", DocumentType: "01", Serie: "F001"},
+		{EstablishmentCode: "0001", DocumentType: "01", Serie: "F001"},
+		{EstablishmentCode: "0000", DocumentType: "07", Serie: "FC01"},
+		{EstablishmentCode: "0000", DocumentType: "07", Serie: "BC01"},
+	}
+	if err := ValidateSeries(valid); err != nil {
+		t.Errorf("ValidateSeries: %v", err)
+	}
+
+	invalid := [][]config.SeriesConfig{
+		{{DocumentType: "01", Serie: "F001"}, {DocumentType: "07", Serie: "F001"}},
+		{{DocumentType: "03", Serie: "F001"}},
+		{{DocumentType: "01", Serie: "f001"}},
+	}
+	for _, series := range invalid {
+		if err := ValidateSeries(series); err == nil {
+			t.Errorf("ValidateSeries(%+v) succeeded", series)
+		}
+	}
+}
+
+// Una factura y una nota con la misma serie tendrían el mismo ID
+func TestAssignRejectsSerieOfAnotherType(t *testing.T) {
+	n, repo := newTestNumbering(t, []config.SeriesConfig{{EstablishmentCode: "0000", DocumentType: "07", Serie: "FC01"}})
+
+	// Serie configurada para notas de crédito
+	doc := &models.Document{Type: models.DocumentTypeFactura, Serie: "FC01"}
+	if err := n.Assign(doc, ""); err == nil {
+		t.Errorf("Assign factura in FC01 = %s, want error", doc.ID)
+	}
+
+	// Serie no configurada pero ya usada por otro tipo
+	invoice := &models.Document{Type: models.DocumentTypeFactura, Serie: "F002", Status: models.StatusDraft}
+	if err := n.Assign(invoice, ""); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	if err := repo.Save(invoice); err != nil {
+		t.Fatal(err)
+	}
+	note := &models.Document{
+		Type: models.DocumentTypeNotaDebito, Serie: "F002",
+		RelatedDocuments: []models.RelatedDocument{{DocumentType: models.DocumentTypeFactura, Serie: "F002", Number: invoice.Number}},
+	}
+	if err := n.Assign(note, ""); err == nil {
+		t.Errorf("Assign debit note in F002 = %s, want error", note.ID)
+	}
+
+	// El mismo tipo sigue numerando la serie
+	next := &models.Document{Type: models.DocumentTypeFactura, Serie: "F002"}
+	if err := n.Assign(next, ""); err != nil {
+		t.Errorf("Assign: %v", err)
+	}
+}
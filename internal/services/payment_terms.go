@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// resolvePaymentTerms valida la forma de pago. Al crédito, las cuotas deben
+// vencer después de la emisión, en orden, y sumar el importe neto pendiente
+// de pago. Sin cuotas, el vencimiento y el importe de payment_terms forman
+// una única cuota.
+func resolvePaymentTerms(doc *models.Document) error {
+	if doc.PaymentTerms == nil {
+		return nil
+	}
+	terms := *doc.PaymentTerms
+	terms.Amount = terms.Amount.Round(decimal.AmountPlaces)
+
+	switch terms.PaymentMeansCode {
+	case models.PaymentMeansCash:
+		if len(terms.Installments) > 0 {
+			return fmt.Errorf("payment_terms: installments require payment_means_code %s", models.PaymentMeansCredit)
+		}
+	case models.PaymentMeansCredit:
+		pending := doc.PendingAmount()
+		if len(terms.Installments) == 0 {
+			if terms.DueDate.IsZero() {
+				return fmt.Errorf("payment_terms: credit sales require installments or a due_date")
+			}
+			amount := terms.Amount
+			if amount.IsZero() {
+				amount = pending
+			}
+			terms.Installments = []models.Installment{{Amount: amount, DueDate: terms.DueDate}}
+		}
+
+		installments := make([]models.Installment, len(terms.Installments))
+		total := decimal.Zero
+		for i, installment := range terms.Installments {
+			if installment.Amount.Sign() <= 0 || !installment.Amount.Exact(decimal.AmountPlaces) {
+				return fmt.Errorf("payment_terms: installment %d amount must be positive with up to %d decimals", i+1, decimal.AmountPlaces)
+			}
+			if !installment.DueDate.After(doc.IssueDate) {
+				return fmt.Errorf("payment_terms: installment %d is due on or before the issue date", i+1)
+			}
+			if i > 0 && !installment.DueDate.After(installments[i-1].DueDate) {
+				return fmt.Errorf("payment_terms: installment %d is not due after installment %d", i+1, i)
+			}
+			installment.Amount = installment.Amount.Round(decimal.AmountPlaces)
+			installments[i] = installment
+			total = total.Add(installment.Amount)
+		}
+		if !total.Equal(pending) {
+			return fmt.Errorf("payment_terms: installments add up to %s but the pending amount is %s", total, pending)
+		}
+
+		terms.Installments = installments
+		terms.Amount = pending
+		terms.DueDate = installments[len(installments)-1].DueDate
+	default:
+		return fmt.Errorf("payment_terms: unknown payment_means_code %q (%s or %s)", terms.PaymentMeansCode, models.PaymentMeansCash, models.PaymentMeansCredit)
+	}
+
+	doc.PaymentTerms = &terms
+	return nil
+}
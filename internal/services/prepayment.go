@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"infac/internal/models"
+	"infac/internal/storage"
+	"infac/pkg/decimal"
+)
+
+// resolvePrepayments valida los anticipos que deduce el comprobante final y
+// devuelve los descuentos globales 04 a 06 que reducen su base imponible.
+// Cada anticipo debe ser una factura o boleta aceptada, emitida por anticipo
+// al mismo adquirente y en la misma moneda, y entre todos los comprobantes
+// que lo deducen no puede superarse su importe total.
+func (s *DocumentService) resolvePrepayments(doc *models.Document, req *models.CreateDocumentRequest) ([]models.AllowanceCharge, error) {
+	for i, ac := range doc.AllowanceCharges {
+		if ac.Code.Advance() {
+			return nil, fmt.Errorf("document: allowance_charges[%d]: code %s is derived from prepayments", i, ac.Code)
+		}
+	}
+
+	sale := doc.Type == models.DocumentTypeFactura || doc.Type == models.DocumentTypeBoleta
+	doc.Advance = req.Advance
+	if doc.Advance {
+		if !sale {
+			return nil, fmt.Errorf("advance applies only to invoices (01) and boletas (03)")
+		}
+		if len(req.Prepayments) > 0 {
+			return nil, fmt.Errorf("an advance cannot deduct other advances")
+		}
+	}
+	if len(req.Prepayments) == 0 {
+		return nil, nil
+	}
+	if !sale {
+		return nil, fmt.Errorf("prepayments apply only to invoices (01) and boletas (03)")
+	}
+
+	base := documentBase(doc)
+	deducted := map[models.AllowanceChargeCode]decimal.Decimal{}
+	var allowances []models.AllowanceCharge
+	for i, p := range req.Prepayments {
+		where := fmt.Sprintf("prepayments[%d]", i)
+		id := models.DocumentID(p.Serie, p.Number)
+		if !doc.Deducted(id).IsZero() {
+			return nil, fmt.Errorf("%s: advance %s is listed more than once", where, id)
+		}
+
+		advance, err := s.repo.FindByID(id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: advance %s not found", where, id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to load advance %s: %w", ErrStorage, id, err)
+		}
+		code, err := checkAdvance(doc, advance)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", where, err)
+		}
+
+		amount, taxable, err := s.advanceDeduction(doc, advance, p.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", where, err)
+		}
+
+		doc.Prepayments = append(doc.Prepayments, models.Prepayment{
+			DocumentType:  advance.Type,
+			Serie:         advance.Serie,
+			Number:        advance.Number,
+			Amount:        amount,
+			TaxableAmount: taxable,
+		})
+		allowances = append(allowances, models.AllowanceCharge{
+			Code:       code,
+			Amount:     taxable,
+			BaseAmount: base(code),
+		})
+
+		deducted[code] = deducted[code].Add(taxable)
+		if deducted[code].Cmp(base(code)) > 0 {
+			return nil, fmt.Errorf("prepayments exceed the value of the document with the affectation of advance %s", id)
+		}
+	}
+
+	return allowances, nil
+}
+
+// checkAdvance verifica que el comprobante pueda deducirse como anticipo del
+// documento y devuelve el descuento por anticipos de su afectación
+func checkAdvance(doc, advance *models.Document) (models.AllowanceChargeCode, error) {
+	if !advance.Advance {
+		return "", fmt.Errorf("document %s was not issued as an advance", advance.ID)
+	}
+	if advance.Type != doc.Type {
+		return "", fmt.Errorf("advance %s must be of the same type as the document (%s)", advance.ID, doc.Type)
+	}
+	if advance.Status != models.StatusAccepted {
+		return "", fmt.Errorf("advance %s is %s; only accepted advances can be deducted", advance.ID, advance.Status)
+	}
+	if advance.Customer.DocumentNumber != doc.Customer.DocumentNumber {
+		return "", fmt.Errorf("advance %s was issued to another customer", advance.ID)
+	}
+	if advance.CurrencyCode != doc.CurrencyCode {
+		return "", fmt.Errorf("advance %s was issued in %s", advance.ID, advance.CurrencyCode)
+	}
+	if advance.IssueDate.After(doc.IssueDate) {
+		return "", fmt.Errorf("advance %s is dated after the document", advance.ID)
+	}
+
+	// El descuento por anticipos reduce la base de una sola afectación
+	var affectation models.IGVAffectation
+	for _, line := range advance.Lines {
+		if line.Affectation().Free() {
+			continue
+		}
+		if affectation != "" && line.Affectation() != affectation {
+			return "", fmt.Errorf("advance %s mixes IGV affectations", advance.ID)
+		}
+		affectation = line.Affectation()
+	}
+	code, ok := models.AdvanceAllowanceCode(affectation)
+	if !ok {
+		return "", fmt.Errorf("advances with igv_affectation %s cannot be deducted", affectation)
+	}
+	return code, nil
+}
+
+// advanceDeduction calcula el importe con impuestos y el valor de venta que
+// se deducen del anticipo. Sin importe se deduce todo el saldo, descontando
+// lo que ya deducen los demás comprobantes vigentes; el valor de venta es
+// proporcional y el de la última deducción completa el del anticipo.
+func (s *DocumentService) advanceDeduction(doc, advance *models.Document, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	deducted, deductedTaxable, err := s.advanceDeducted(doc, advance)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+
+	balance := advance.TotalAmount.Sub(deducted)
+	if balance.Sign() <= 0 {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("advance %s has already been fully deducted", advance.ID)
+	}
+	if amount.IsZero() {
+		amount = balance
+	}
+	if amount.Sign() < 0 || !amount.Exact(decimal.AmountPlaces) {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("amount must be positive with up to %d decimals", decimal.AmountPlaces)
+	}
+	if amount.Cmp(balance) > 0 {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("amount %s exceeds the balance of advance %s (%s)", amount, advance.ID, balance)
+	}
+	amount = amount.Round(decimal.AmountPlaces)
+
+	taxable := advance.TaxExclusiveAmount.Sub(deductedTaxable)
+	if !amount.Equal(balance) {
+		taxable = amount.Mul(advance.TaxExclusiveAmount).Div(advance.TotalAmount).Round(decimal.AmountPlaces)
+	}
+	return amount, taxable, nil
+}
+
+// advanceDeducted suma el importe y el valor de venta que deducen del
+// anticipo los demás comprobantes vigentes
+func (s *DocumentService) advanceDeducted(doc, advance *models.Document) (decimal.Decimal, decimal.Decimal, error) {
+	others, err := s.repo.List(storage.DocumentFilter{Type: doc.Type, CustomerRUC: advance.Customer.DocumentNumber})
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("%w: failed to list deductions of advance %s: %w", ErrStorage, advance.ID, err)
+	}
+
+	deducted, deductedTaxable := decimal.Zero, decimal.Zero
+	for _, other := range others {
+		if other.ID == doc.ID || other.Status == models.StatusRejected || other.Status == models.StatusCancelled {
+			continue
+		}
+		for _, p := range other.Prepayments {
+			if p.DocumentID() == advance.ID {
+				deducted = deducted.Add(p.Amount)
+				deductedTaxable = deductedTaxable.Add(p.TaxableAmount)
+			}
+		}
+	}
+	return deducted, deductedTaxable, nil
+}
+
+// checkPrepayments vuelve a verificar antes del envío que los anticipos que
+// deduce el documento sigan aceptados y que su saldo alcance: desde que se
+// creó el borrador, el anticipo pudo anularse o deducirse en otro documento
+func (s *DocumentService) checkPrepayments(doc *models.Document) error {
+	if len(doc.Prepayments) == 0 {
+		return nil
+	}
+	ids := make([]string, len(doc.Prepayments))
+	for i, p := range doc.Prepayments {
+		ids[i] = p.DocumentID()
+	}
+	unlock := s.lockAdvances(ids)
+	defer unlock()
+
+	for _, p := range doc.Prepayments {
+		advance, err := s.repo.FindByID(p.DocumentID())
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%w: advance %s not found", ErrPrepaymentUnavailable, p.DocumentID())
+		}
+		if err != nil {
+			return fmt.Errorf("%w: failed to load advance %s: %w", ErrStorage, p.DocumentID(), err)
+		}
+		if advance.Status != models.StatusAccepted {
+			return fmt.Errorf("%w: advance %s is %s", ErrPrepaymentUnavailable, advance.ID, advance.Status)
+		}
+
+		deducted, _, err := s.advanceDeducted(doc, advance)
+		if err != nil {
+			return err
+		}
+		if balance := advance.TotalAmount.Sub(deducted); p.Amount.Cmp(balance) > 0 {
+			return fmt.Errorf("%w: the document deducts %s from advance %s but its balance is %s", ErrPrepaymentUnavailable, p.Amount, advance.ID, balance)
+		}
+	}
+	return nil
+}
+
+// advanceLock serializa las deducciones de un anticipo; refs cuenta los
+// documentos que lo esperan para descartarlo cuando nadie lo usa
+type advanceLock struct {
+	sync.Mutex
+	refs int
+}
+
+// prepaymentIDs devuelve los IDs de los anticipos que deduce el request
+func prepaymentIDs(prepayments []models.PrepaymentRequest) []string {
+	ids := make([]string, len(prepayments))
+	for i, p := range prepayments {
+		ids[i] = models.DocumentID(p.Serie, p.Number)
+	}
+	return ids
+}
+
+// lockAdvances bloquea los anticipos indicados hasta que se llame a la
+// función devuelta. Se bloquean en orden para que dos documentos que deducen
+// los mismos anticipos no se esperen mutuamente.
+func (s *DocumentService) lockAdvances(ids []string) func() {
+	ids = append([]string(nil), ids...)
+	sort.Strings(ids)
+	unique := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			unique = append(unique, id)
+		}
+	}
+
+	s.advanceMu.Lock()
+	if s.advanceLocks == nil {
+		s.advanceLocks = make(map[string]*advanceLock)
+	}
+	locks := make([]*advanceLock, len(unique))
+	for i, id := range unique {
+		lock := s.advanceLocks[id]
+		if lock == nil {
+			lock = &advanceLock{}
+			s.advanceLocks[id] = lock
+		}
+		lock.refs++
+		locks[i] = lock
+	}
+	s.advanceMu.Unlock()
+
+	for _, lock := range locks {
+		lock.Lock()
+	}
+	return func() {
+		for _, lock := range locks {
+			lock.Unlock()
+		}
+		s.advanceMu.Lock()
+		defer s.advanceMu.Unlock()
+		for i, lock := range locks {
+			if lock.refs--; lock.refs == 0 {
+				delete(s.advanceLocks, unique[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"infac/internal/models"
+	"infac/internal/storage"
+)
+
+// slowSaveRepository demora el guardado para que los documentos creados a
+// la vez calculen el saldo antes de que se guarde alguno
+type slowSaveRepository struct {
+	storage.DocumentRepository
+}
+
+func (r slowSaveRepository) Save(doc *models.Document) error {
+	time.Sleep(50 * time.Millisecond)
+	return r.DocumentRepository.Save(doc)
+}
+
+// acceptedAdvance crea una factura o boleta por anticipo de 236.00 y la
+// marca como aceptada
+func acceptedAdvance(t *testing.T, s *DocumentService, docType models.DocumentType) *models.Document {
+	t.Helper()
+
+	req := testRequest(t, docType)
+	req.Advance = true
+	advance, err := s.CreateDocument(req)
+	if err != nil {
+		t.Fatalf("CreateDocument advance: %v", err)
+	}
+	advance.Status = models.StatusAccepted
+	if err := s.repo.Update(advance); err != nil {
+		t.Fatal(err)
+	}
+	return advance
+}
+
+// deductionRequest arma el comprobante final, del tipo del anticipo, que
+// deduce todo su saldo
+func deductionRequest(t *testing.T, advance *models.Document) *models.CreateDocumentRequest {
+	t.Helper()
+
+	req := testRequest(t, advance.Type)
+	req.Prepayments = []models.PrepaymentRequest{{Serie: advance.Serie, Number: advance.Number}}
+	return req
+}
+
+// Dos documentos creados a la vez no pueden deducir el mismo saldo
+func TestCreateDocumentConcurrentDeductions(t *testing.T) {
+	s, _ := newTestService(t)
+	advance := acceptedAdvance(t, s, models.DocumentTypeFactura)
+	s.repo = slowSaveRepository{s.repo}
+
+	const attempts = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		req := deductionRequest(t, advance)
+		// El mismo anticipo indicado con y sin ceros a la izquierda
+		if i%2 == 1 {
+			req.Prepayments[0].Number = strings.TrimLeft(advance.Number, "0")
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.CreateDocument(req)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d documents deducted the whole advance, want 1", created)
+	}
+}
+
+// El envío vuelve a verificar el saldo: otro documento pudo deducirlo
+// desde que se creó el borrador
+func TestSendDocumentRechecksPrepayments(t *testing.T) {
+	s, server := newTestService(t)
+	advance := acceptedAdvance(t, s, models.DocumentTypeFactura)
+
+	doc, err := s.CreateDocument(deductionRequest(t, advance))
+	if err != nil {
+		t.Fatalf("CreateDocument: %v", err)
+	}
+	// Una deducción guardada sin pasar por el cálculo del saldo, como las
+	// de documentos anteriores a la verificación
+	other := *doc
+	other.ID, other.Number = doc.Serie+"-00000099", "00000099"
+	if err := s.repo.Save(&other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SendDocument(doc); !errors.Is(err, ErrPrepaymentUnavailable) {
+		t.Fatalf("SendDocument = %v, want ErrPrepaymentUnavailable", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusDraft)
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("SUNAT received %d requests, want 0", n)
+	}
+}
+
+func TestSendDocumentCancelledAdvance(t *testing.T) {
+	s, server := newTestService(t)
+	advance := acceptedAdvance(t, s, models.DocumentTypeFactura)
+
+	doc, err := s.CreateDocument(deductionRequest(t, advance))
+	if err != nil {
+		t.Fatalf("CreateDocument: %v", err)
+	}
+	advance.Status = models.StatusCancelled
+	if err := s.repo.Update(advance); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SendDocument(doc); !errors.Is(err, ErrPrepaymentUnavailable) {
+		t.Fatalf("SendDocument = %v, want ErrPrepaymentUnavailable", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusDraft)
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("SUNAT received %d requests, want 0", n)
+	}
+}
+
+// Un documento que deduce su saldo se envía normalmente
+func TestSendDocumentWithPrepayment(t *testing.T) {
+	s, _ := newTestService(t)
+	advance := acceptedAdvance(t, s, models.DocumentTypeFactura)
+
+	doc, err := s.CreateDocument(deductionRequest(t, advance))
+	if err != nil {
+		t.Fatalf("CreateDocument: %v", err)
+	}
+	if err := s.SendDocument(doc); err != nil {
+		t.Fatalf("SendDocument: %v", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusAccepted)
+}
+
+// El anticipo se puede indicar con el número sin ceros a la izquierda
+func TestCreateDocumentUnpaddedAdvance(t *testing.T) {
+	s, _ := newTestService(t)
+	advance := acceptedAdvance(t, s, models.DocumentTypeFactura)
+
+	req := deductionRequest(t, advance)
+	req.Prepayments[0].Number = strings.TrimLeft(advance.Number, "0")
+	doc, err := s.CreateDocument(req)
+	if err != nil {
+		t.Fatalf("CreateDocument: %v", err)
+	}
+	if p := doc.Prepayments[0]; p.DocumentID() != advance.ID || p.Number != advance.Number {
+		t.Errorf("prepayment = %s, want %s", p.DocumentID(), advance.ID)
+	}
+	if deducted := doc.Deducted(advance.ID); deducted.String() != "236.00" {
+		t.Errorf("deducted = %s, want 236.00", deducted)
+	}
+}
+
+// Una boleta cuyo anticipo ya no está disponible queda fuera del Resumen
+// Diario sin impedir el envío de las demás
+func TestDailySummarySkipsUnavailablePrepayment(t *testing.T) {
+	s, _ := newTestService(t)
+	advance := acceptedAdvance(t, s, models.DocumentTypeBoleta)
+	deduction, err := s.CreateDocument(deductionRequest(t, advance))
+	if err != nil {
+		t.Fatalf("CreateDocument: %v", err)
+	}
+	advance.Status = models.StatusCancelled
+	if err := s.repo.Update(advance); err != nil {
+		t.Fatal(err)
+	}
+	other := createTestDocument(t, s, models.DocumentTypeBoleta)
+
+	ticket, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()})
+	if err != nil {
+		t.Fatalf("SendDailySummary: %v", err)
+	}
+	if len(ticket.DocumentIDs) != 1 || ticket.DocumentIDs[0] != other.ID {
+		t.Errorf("ticket documents = %v, want [%s]", ticket.DocumentIDs, other.ID)
+	}
+	if _, ok := ticket.Skipped[deduction.ID]; !ok || len(ticket.Skipped) != 1 {
+		t.Errorf("skipped = %v, want %s", ticket.Skipped, deduction.ID)
+	}
+	assertStored(t, s, deduction.ID, models.StatusDraft)
+	assertStored(t, s, other.ID, models.StatusSent)
+
+	// Sin otros documentos, el resumen no se envía
+	if _, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()}); !errors.Is(err, ErrPrepaymentUnavailable) {
+		t.Fatalf("second SendDailySummary = %v, want ErrPrepaymentUnavailable", err)
+	}
+	assertStored(t, s, deduction.ID, models.StatusDraft)
+}
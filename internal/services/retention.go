@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// splitInformative separa la retención y la percepción de los demás
+// descuentos y cargos globales: se calculan sobre el importe total, después
+// de aplicar los demás
+func splitInformative(items []models.AllowanceCharge) (regular, informative []models.AllowanceCharge) {
+	for _, ac := range items {
+		if ac.Code.Informative() {
+			informative = append(informative, ac)
+		} else {
+			regular = append(regular, ac)
+		}
+	}
+	return regular, informative
+}
+
+// resolveRetentionPerception calcula la retención del IGV (código 62) y la
+// percepción (51 a 53) sobre el importe total, con la tasa vigente del
+// código si el request no indica el factor ni el importe. No modifican el
+// importe total; la retención se descuenta del importe pendiente de pago.
+// La percepción solo aplica en soles; la retención de un documento en
+// moneda extranjera se informa en soles con el tipo de cambio declarado.
+func resolveRetentionPerception(doc *models.Document, items []models.AllowanceCharge) ([]models.AllowanceCharge, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	withDefaults := make([]models.AllowanceCharge, len(items))
+	for i, ac := range items {
+		if ac.Factor == nil && ac.Amount.IsZero() {
+			factor := ac.Code.Factor()
+			ac.Factor = &factor
+		}
+		withDefaults[i] = ac
+	}
+	resolved, err := resolveAllowanceCharges("document", withDefaults, false,
+		func(models.AllowanceChargeCode) decimal.Decimal { return doc.TotalAmount })
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resolved) > 1 {
+		return nil, fmt.Errorf("a document admits either one retention (62) or one perception (51 to 53)")
+	}
+
+	ac := resolved[0]
+	if ac.Code == models.RetentionIGV {
+		if doc.Type != models.DocumentTypeFactura {
+			return nil, fmt.Errorf("retention applies only to invoices (01)")
+		}
+		if doc.Operation().Detraction() {
+			return nil, fmt.Errorf("retention does not apply to operations subject to detraction")
+		}
+		if err := requirePENRate(doc, "retention"); err != nil {
+			return nil, err
+		}
+		if total := doc.ToPEN(doc.TotalAmount); total.Cmp(models.RetentionMinimumAmount) <= 0 {
+			return nil, fmt.Errorf("retention applies to operations over PEN %s (total PEN %s)", models.RetentionMinimumAmount.Round(decimal.AmountPlaces), total)
+		}
+	} else {
+		if doc.Type != models.DocumentTypeFactura && doc.Type != models.DocumentTypeBoleta {
+			return nil, fmt.Errorf("perception applies only to invoices (01) and receipts (03)")
+		}
+		if doc.CurrencyCode != "PEN" {
+			return nil, fmt.Errorf("perception requires currency_code PEN")
+		}
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+	"infac/pkg/ubl"
+)
+
+// retentionRequest arma una factura con retención del IGV (62, 3%) por el
+// importe de testRequest: 236.00 en la moneda indicada
+func retentionRequest(t *testing.T, currency string, rate string) *models.CreateDocumentRequest {
+	t.Helper()
+
+	req := testRequest(t, models.DocumentTypeFactura)
+	req.CurrencyCode = currency
+	req.AllowanceCharges = []models.AllowanceCharge{{Code: models.RetentionIGV}}
+	if rate != "" {
+		req.ExchangeRate = &models.ExchangeRate{Rate: decimal.MustParse(rate)}
+	}
+	return req
+}
+
+func TestRetentionForeignCurrency(t *testing.T) {
+	s, _ := newTestService(t)
+
+	// 236.00 USD × 3.75 = 885.00 PEN, sobre el mínimo de S/ 700
+	doc, err := s.buildDocument(retentionRequest(t, "USD", "3.75"))
+	if err != nil {
+		t.Fatalf("buildDocument: %v", err)
+	}
+	retention := doc.Retention()
+	if retention == nil || retention.Amount.String() != "7.08" {
+		t.Fatalf("retention = %+v, want 7.08 USD", retention)
+	}
+	if pending := doc.PendingAmount(); pending.String() != "228.92" {
+		t.Fatalf("PendingAmount = %s, want 228.92", pending)
+	}
+
+	// El XML informa la retención en soles: 7.08 × 3.75 = 26.55
+	invoice, err := ubl.GenerateInvoiceXML(doc, &doc.Issuer)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML: %v", err)
+	}
+	var reported *ubl.MonetaryAmount
+	for _, term := range invoice.PaymentTerms {
+		if term.ID == "Retencion" {
+			reported = term.Amount
+		}
+	}
+	if reported == nil || reported.CurrencyID != "PEN" || reported.Value.String() != "26.55" {
+		t.Fatalf("Retencion amount = %+v, want PEN 26.55", reported)
+	}
+}
+
+func TestRetentionErrors(t *testing.T) {
+	s, _ := newTestService(t)
+
+	perception := testRequest(t, models.DocumentTypeFactura)
+	perception.CurrencyCode = "USD"
+	perception.ExchangeRate = &models.ExchangeRate{Rate: decimal.MustParse("3.75")}
+	perception.AllowanceCharges = []models.AllowanceCharge{{Code: models.PerceptionInternalSale}}
+
+	tests := []struct {
+		name string
+		req  *models.CreateDocumentRequest
+		want string
+	}{
+		{"without exchange rate", retentionRequest(t, "USD", ""), "requires the exchange_rate to PEN"},
+		// 236.00 USD × 2.5 = 590.00 PEN no supera el mínimo
+		{"under minimum in PEN", retentionRequest(t, "USD", "2.5"), "total PEN 590.00"},
+		{"under minimum", retentionRequest(t, "PEN", ""), "total PEN 236.00"},
+		{"perception in USD", perception, "perception requires currency_code PEN"},
+	}
+	for _, tt := range tests {
+		_, err := s.buildDocument(tt.req)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: buildDocument = %v, want error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"infac/internal/config"
+	"infac/internal/models"
+	"infac/internal/storage"
+)
+
+var ErrJobNotFound = storage.ErrJobNotFound
+
+// SendQueue envía documentos en segundo plano con un pool de workers. Los
+// jobs se guardan en cada cambio de estado para retomarlos tras un reinicio.
+type SendQueue struct {
+	service *DocumentService
+	jobs    storage.JobRepository
+	cfg     config.QueueConfig
+
+	ready chan string
+
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	timers map[string]*time.Timer
+	// active indexa el job en curso de cada documento; un envío
+	// sincrónico (SendNow) se registra sin job
+	active map[string]string
+	wg     sync.WaitGroup
+}
+
+func NewSendQueue(service *DocumentService, jobs storage.JobRepository, cfg config.QueueConfig) *SendQueue {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 10 * time.Second
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+
+	return &SendQueue{
+		service: service,
+		jobs:    jobs,
+		cfg:     cfg,
+		ready:   make(chan string),
+		timers:  make(map[string]*time.Timer),
+		active:  make(map[string]string),
+	}
+}
+
+// Start lanza los workers y retoma los jobs que quedaron sin terminar
+func (q *SendQueue) Start(ctx context.Context) error {
+	jobs, err := q.jobs.ListJobs("")
+	if err != nil {
+		return fmt.Errorf("failed to load send jobs: %w", err)
+	}
+
+	q.mu.Lock()
+	q.ctx, q.cancel = context.WithCancel(ctx)
+	q.mu.Unlock()
+
+	for i := 0; i < q.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+
+	for _, job := range jobs {
+		if !job.Status.Active() {
+			continue
+		}
+		if job.Status == models.JobStatusRunning {
+			q.recoverInterrupted(job)
+		}
+
+		next := time.Now()
+		if job.NextAttemptAt != nil {
+			next = *job.NextAttemptAt
+		}
+		q.mu.Lock()
+		q.active[job.DocumentID] = job.ID
+		q.mu.Unlock()
+		q.schedule(job.ID, next)
+	}
+
+	return nil
+}
+
+// Stop detiene los workers y espera a que terminen el envío en curso
+func (q *SendQueue) Stop() {
+	q.mu.Lock()
+	if q.cancel != nil {
+		q.cancel()
+	}
+	for id, timer := range q.timers {
+		timer.Stop()
+		delete(q.timers, id)
+	}
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+// Enqueue agrega el envío del documento a la cola. Si el documento ya tiene
+// un envío en curso se devuelve ese job.
+func (q *SendQueue) Enqueue(documentID string) (*models.SendJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if id, ok := q.active[documentID]; ok {
+		if id == "" {
+			return nil, fmt.Errorf("%w: %s is already being sent", ErrDocumentBusy, documentID)
+		}
+		return q.jobs.FindJob(id)
+	}
+
+	doc, err := q.service.GetDocument(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Status != models.StatusDraft {
+		return nil, fmt.Errorf("%w: %s is %s", ErrDocumentNotDraft, doc.ID, doc.Status)
+	}
+
+	now := time.Now()
+	job := &models.SendJob{
+		ID:          newJobID(),
+		DocumentID:  doc.ID,
+		Status:      models.JobStatusQueued,
+		MaxAttempts: q.cfg.MaxAttempts,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := q.jobs.SaveJob(job); err != nil {
+		return nil, err
+	}
+
+	q.active[doc.ID] = job.ID
+	q.scheduleLocked(job.ID, now)
+
+	return job, nil
+}
+
+// SendNow envía el documento sin encolarlo y espera la respuesta de SUNAT.
+// Comparte con Enqueue el registro de envíos en curso: un documento con un
+// job activo o con otro envío sincrónico no se vuelve a enviar.
+func (q *SendQueue) SendNow(documentID string) (*models.Document, error) {
+	q.mu.Lock()
+	if id, ok := q.active[documentID]; ok {
+		q.mu.Unlock()
+		if id == "" {
+			return nil, fmt.Errorf("%w: %s is already being sent", ErrDocumentBusy, documentID)
+		}
+		return nil, fmt.Errorf("%w: %s is queued as job %s", ErrDocumentBusy, documentID, id)
+	}
+	q.active[documentID] = ""
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		delete(q.active, documentID)
+		q.mu.Unlock()
+	}()
+
+	return q.service.SendDocumentByID(documentID)
+}
+
+func (q *SendQueue) GetJob(id string) (*models.SendJob, error) {
+	return q.jobs.FindJob(id)
+}
+
+// ListJobs devuelve los jobs en el estado indicado ("" = todos)
+func (q *SendQueue) ListJobs(status models.JobStatus) ([]*models.SendJob, error) {
+	return q.jobs.ListJobs(status)
+}
+
+func (q *SendQueue) schedule(id string, at time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.scheduleLocked(id, at)
+}
+
+func (q *SendQueue) scheduleLocked(id string, at time.Time) {
+	if q.ctx == nil {
+		return
+	}
+	q.timers[id] = time.AfterFunc(time.Until(at), func() {
+		q.mu.Lock()
+		delete(q.timers, id)
+		ctx := q.ctx
+		q.mu.Unlock()
+
+		select {
+		case q.ready <- id:
+		case <-ctx.Done():
+		}
+	})
+}
+
+func (q *SendQueue) worker() {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case id := <-q.ready:
+			q.process(id)
+		}
+	}
+}
+
+// process realiza un intento de envío. Un error de entrega se reintenta
+// mientras queden intentos; cualquier otro resultado es definitivo. Salvo que
+// el job quede programado, el documento deja de figurar como en curso.
+func (q *SendQueue) process(id string) {
+	scheduled := false
+	defer func() {
+		if !scheduled {
+			q.release(id)
+		}
+	}()
+
+	job, err := q.jobs.FindJob(id)
+	if err != nil {
+		fmt.Printf("Warning: Failed to load send job %s: %v\n", id, err)
+		// Un job que no se pudo leer se vuelve a intentar; uno que ya no
+		// existe se descarta
+		if !errors.Is(err, ErrJobNotFound) {
+			q.schedule(id, time.Now().Add(q.cfg.InitialBackoff))
+			scheduled = true
+		}
+		return
+	}
+	if !job.Status.Active() {
+		return
+	}
+
+	job.Status = models.JobStatusRunning
+	job.Attempts++
+	job.NextAttemptAt = nil
+	q.save(job)
+
+	_, err = q.service.SendDocumentByID(job.DocumentID)
+
+	now := time.Now()
+	job.UpdatedAt = now
+	switch {
+	case err == nil:
+		job.Status = models.JobStatusSucceeded
+		job.LastError = ""
+	case errors.Is(err, ErrDeliveryFailed) && job.Attempts < job.MaxAttempts:
+		next := now.Add(q.backoff(job.Attempts))
+		job.Status = models.JobStatusRetrying
+		job.NextAttemptAt = &next
+		job.LastError = err.Error()
+		q.save(job)
+		q.schedule(job.ID, next)
+		scheduled = true
+		return
+	default:
+		job.Status = models.JobStatusFailed
+		job.LastError = err.Error()
+	}
+
+	job.CompletedAt = &now
+	q.save(job)
+}
+
+// release quita el job de los envíos en curso. Se busca por el ID del job
+// porque puede no haberse leído, y así no se quita un job posterior del
+// mismo documento.
+func (q *SendQueue) release(jobID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for documentID, id := range q.active {
+		if id == jobID {
+			delete(q.active, documentID)
+		}
+	}
+}
+
+// backoff duplica la espera en cada intento fallido, hasta MaxBackoff
+func (q *SendQueue) backoff(attempts int) time.Duration {
+	wait := q.cfg.InitialBackoff
+	for i := 1; i < attempts && wait < q.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > q.cfg.MaxBackoff {
+		wait = q.cfg.MaxBackoff
+	}
+	return wait
+}
+
+// recoverInterrupted vuelve a borrador el documento de un job que se estaba
+// enviando cuando se detuvo el proceso, para poder reenviarlo
+func (q *SendQueue) recoverInterrupted(job *models.SendJob) {
+	job.Status = models.JobStatusQueued
+	job.UpdatedAt = time.Now()
+	q.save(job)
+
+	doc, err := q.service.GetDocument(job.DocumentID)
+	if err != nil {
+		return
+	}
+	if doc.Status != models.StatusPending {
+		return
+	}
+	if err := doc.TransitionTo(models.StatusDraft, "Envío interrumpido, se reintentará"); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+	if err := q.service.persist(doc); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+func (q *SendQueue) save(job *models.SendJob) {
+	job.UpdatedAt = time.Now()
+	if err := q.jobs.SaveJob(job); err != nil {
+		fmt.Printf("Warning: Failed to persist send job %s: %v\n", job.ID, err)
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
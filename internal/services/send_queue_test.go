@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"infac/internal/config"
+	"infac/internal/models"
+	"infac/internal/storage"
+	"infac/pkg/sunat/fake"
+)
+
+// newTestQueue crea una cola sin iniciar: los jobs quedan encolados sin que
+// ningún worker los procese
+func newTestQueue(t *testing.T, s *DocumentService) *SendQueue {
+	t.Helper()
+	return NewSendQueue(s, s.repo.(storage.JobRepository), config.QueueConfig{})
+}
+
+func TestSendNowSkipsQueuedDocument(t *testing.T) {
+	s, server := newTestService(t)
+	queue := newTestQueue(t, s)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	job, err := queue.Enqueue(doc.ID)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := queue.SendNow(doc.ID); !errors.Is(err, ErrDocumentBusy) {
+		t.Fatalf("SendNow = %v, want ErrDocumentBusy", err)
+	}
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("SUNAT received %d requests, want 0", n)
+	}
+
+	// Un segundo Enqueue devuelve el mismo job
+	again, err := queue.Enqueue(doc.ID)
+	if err != nil || again.ID != job.ID {
+		t.Fatalf("Enqueue again = %v, %v; want job %s", again, err, job.ID)
+	}
+}
+
+func TestEnqueueDuringSendNow(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-01-F001-*", fake.Behavior{Delay: fake.Duration(300 * time.Millisecond)})
+	queue := newTestQueue(t, s)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := queue.SendNow(doc.ID)
+		done <- err
+	}()
+	// Espera a que el envío llegue al servidor
+	for len(server.Requests()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := queue.Enqueue(doc.ID); !errors.Is(err, ErrDocumentBusy) {
+		t.Errorf("Enqueue = %v, want ErrDocumentBusy", err)
+	}
+	if _, err := queue.SendNow(doc.ID); !errors.Is(err, ErrDocumentBusy) {
+		t.Errorf("second SendNow = %v, want ErrDocumentBusy", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("SendNow: %v", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusAccepted)
+
+	// Terminado el envío, el documento ya no es un borrador
+	if _, err := queue.Enqueue(doc.ID); !errors.Is(err, ErrDocumentNotDraft) {
+		t.Errorf("Enqueue after send = %v, want ErrDocumentNotDraft", err)
+	}
+}
+
+// Un job que ya terminó no deja al documento como en curso
+func TestProcessInactiveJobReleasesDocument(t *testing.T) {
+	s, _ := newTestService(t)
+	queue := newTestQueue(t, s)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	job, err := queue.Enqueue(doc.ID)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	job.Status = models.JobStatusFailed
+	if err := queue.jobs.SaveJob(job); err != nil {
+		t.Fatal(err)
+	}
+
+	queue.process(job.ID)
+	if _, err := queue.SendNow(doc.ID); err != nil {
+		t.Fatalf("SendNow = %v, want the document released", err)
+	}
+}
+
+func TestProcessMissingJobReleasesDocument(t *testing.T) {
+	s, _ := newTestService(t)
+	queue := newTestQueue(t, s)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	queue.active[doc.ID] = "missing"
+	queue.process("missing")
+	if _, err := queue.SendNow(doc.ID); err != nil {
+		t.Fatalf("SendNow = %v, want the document released", err)
+	}
+}
+
+// flakyJobRepository falla la primera lectura de un job
+type flakyJobRepository struct {
+	storage.JobRepository
+	failed atomic.Bool
+}
+
+func (r *flakyJobRepository) FindJob(id string) (*models.SendJob, error) {
+	if r.failed.CompareAndSwap(false, true) {
+		return nil, errors.New("database is locked")
+	}
+	return r.JobRepository.FindJob(id)
+}
+
+// Un job que no se pudo leer se reprograma en lugar de perderse
+func TestProcessRetriesJobLoadFailure(t *testing.T) {
+	s, _ := newTestService(t)
+	jobs := &flakyJobRepository{JobRepository: s.repo.(storage.JobRepository)}
+	queue := NewSendQueue(s, jobs, config.QueueConfig{InitialBackoff: 10 * time.Millisecond})
+	if err := queue.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer queue.Stop()
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	job, err := queue.Enqueue(doc.ID)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stored, err := jobs.JobRepository.FindJob(job.ID)
+		if err == nil && stored.Status == models.JobStatusSucceeded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job = %+v, %v; want succeeded", stored, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !jobs.failed.Load() {
+		t.Errorf("the job was never loaded with an error")
+	}
+	assertStored(t, s, doc.ID, models.StatusAccepted)
+}
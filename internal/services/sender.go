@@ -0,0 +1,115 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"infac/internal/config"
+	"infac/internal/models"
+	"infac/pkg/sunat"
+)
+
+// Sender es un canal de envío de comprobantes: el billService de SUNAT o el
+// de un OSE
+type Sender interface {
+	Name() string
+	SendBill(fileName string, zipContent []byte) ([]byte, error)
+	SendSummary(fileName string, zipContent []byte) (string, error)
+	GetStatus(ticket string) (*sunat.TicketStatus, error)
+	GetStatusCdr(docType, serie, number string) (*sunat.CDRStatus, error)
+}
+
+// senderRouter elige el canal de cada documento según las reglas de
+// sunat.routes
+type senderRouter struct {
+	senders        map[string]Sender
+	routes         []config.RouteConfig
+	defaultChannel string
+}
+
+func newSenderRouter(ruc string, cfg config.SUNATConfig) (*senderRouter, error) {
+	endpoint, err := cfg.Endpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	direct := sunat.NewClient(ruc, cfg.Username, cfg.Password, endpoint)
+	direct.ConsultEndpoint = cfg.ConsultEndpoint()
+
+	router := &senderRouter{
+		senders: map[string]Sender{
+			config.ChannelSUNAT: direct,
+		},
+		routes:         cfg.Routes,
+		defaultChannel: cfg.DefaultChannel(),
+	}
+
+	if cfg.OSE.Enabled {
+		provider, err := sunat.LookupProvider(cfg.OSE.Provider)
+		if err != nil {
+			return nil, err
+		}
+		client, err := sunat.NewProviderClient(provider, ruc, cfg.OSE.Username, cfg.OSE.Password, cfg.OSE.URL)
+		if err != nil {
+			return nil, err
+		}
+		router.senders[config.ChannelOSE] = client
+		fmt.Printf("OSE channel enabled (%s: %s)\n", provider.Name, client.Endpoint)
+	}
+
+	return router, nil
+}
+
+// channelFor devuelve el canal del documento. Un documento ya enviado
+// conserva su canal para las bajas y los resúmenes posteriores; los enviados
+// antes de que existieran los canales fueron a SUNAT.
+func (r *senderRouter) channelFor(doc *models.Document) string {
+	if doc.Channel != "" {
+		return doc.Channel
+	}
+	if doc.Status != models.StatusDraft {
+		return config.ChannelSUNAT
+	}
+	for _, route := range r.routes {
+		if route.Matches(string(doc.Type), doc.Serie) {
+			return route.Channel
+		}
+	}
+	return r.defaultChannel
+}
+
+// summaryChannel devuelve el canal común de los documentos de un resumen;
+// SUNAT y el OSE no aceptan resúmenes con documentos que no recibieron
+func (r *senderRouter) summaryChannel(docs []*models.Document) (string, error) {
+	channels := make(map[string][]string)
+	for _, doc := range docs {
+		channel := r.channelFor(doc)
+		channels[channel] = append(channels[channel], doc.ID)
+	}
+	if len(channels) > 1 {
+		var parts []string
+		for channel, ids := range channels {
+			parts = append(parts, fmt.Sprintf("%s: %s", channel, strings.Join(ids, ", ")))
+		}
+		sort.Strings(parts)
+		return "", fmt.Errorf("documents of a summary must use the same channel (%s)", strings.Join(parts, "; "))
+	}
+	for channel := range channels {
+		return channel, nil
+	}
+	return r.defaultChannel, nil
+}
+
+// sender devuelve el canal indicado. Los tickets registrados antes de que
+// existieran los canales se consultan en SUNAT.
+func (r *senderRouter) sender(channel string) (Sender, error) {
+	if channel == "" {
+		channel = config.ChannelSUNAT
+	}
+	sender, ok := r.senders[channel]
+	if !ok {
+		return nil, fmt.Errorf("channel %s is not configured", channel)
+	}
+	return sender, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"infac/internal/config"
+	"infac/internal/models"
+	"infac/internal/storage"
+	"infac/pkg/sunat/fake"
+)
+
+const testRUC = "20612790168"
+
+// testSigner devuelve el XML sin firmar: el servidor simulado no verifica la
+// firma y las pruebas no dependen de xmlsec1
+type testSigner struct{}
+
+func (testSigner) SignXML(xmlContent []byte) ([]byte, error) {
+	return xmlContent, nil
+}
+
+// newTestService crea un servicio que envía al billService simulado y guarda
+// en una base SQLite temporal
+func newTestService(t *testing.T) (*DocumentService, *fake.Server) {
+	t.Helper()
+
+	server := fake.NewServer()
+	server.Username, server.Password = "MODDATOS", "moddatos"
+	httpServer := server.StartHTTPTest()
+	t.Cleanup(httpServer.Close)
+
+	senders, err := newSenderRouter(testRUC, config.SUNATConfig{
+		Environment: config.EnvironmentBeta,
+		URL:         httpServer.URL,
+		Username:    "MODDATOS",
+		Password:    "moddatos",
+	})
+	if err != nil {
+		t.Fatalf("newSenderRouter: %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, sub := range []string{"xml", "cdr"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo, err := storage.NewSQLiteRepository(filepath.Join(dir, "infac.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	issuer := &models.Company{
+		DocumentType: "6", DocumentNumber: testRUC, Name: "EMISOR SAC", Address: "AV. EJEMPLO 123",
+		District: "LIMA", Province: "LIMA", Department: "LIMA", Country: "PE",
+	}
+	numbering := NewNumberingService(issuer, repo, repo, []config.SeriesConfig{
+		{EstablishmentCode: "0000", DocumentType: "01", Serie: "F001"},
+		{EstablishmentCode: "0000", DocumentType: "03", Serie: "B001"},
+		{EstablishmentCode: "0000", DocumentType: "07", Serie: "FC01"},
+		{EstablishmentCode: "0000", DocumentType: "07", Serie: "BC01"},
+	})
+
+	return &DocumentService{
+		issuer:    issuer,
+		signer:    testSigner{},
+		senders:   senders,
+		repo:      repo,
+		tickets:   repo,
+		numbering: numbering,
+		filesDir:  dir,
+	}, server
+}
+
+// testRequest arma un borrador de dos unidades a 100.00 más IGV emitido hoy
+func testRequest(t *testing.T, docType models.DocumentType) *models.CreateDocumentRequest {
+	t.Helper()
+
+	customer := `{"document_type": "6", "document_number": "20100070970", "name": "CLIENTE SAC"}`
+	if docType == models.DocumentTypeBoleta {
+		customer = `{"document_type": "1", "document_number": "12345678", "name": "JUAN PEREZ"}`
+	}
+	body := `{
+		"type": "` + string(docType) + `",
+		"issue_date": "` + time.Now().Format("2006-01-02") + `",
+		"currency_code": "PEN",
+		"customer": ` + customer + `,
+		"lines": [{"quantity": 2, "unit_code": "NIU", "description": "Item", "unit_price": 100,
+			"taxes": [{"type": "IGV", "rate": 18}]}],
+		"payment_terms": {"payment_means_code": "Contado", "amount": 236}
+	}`
+
+	var req models.CreateDocumentRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	return &req
+}
+
+func createTestDocument(t *testing.T, s *DocumentService, docType models.DocumentType) *models.Document {
+	t.Helper()
+
+	doc, err := s.CreateDocument(testRequest(t, docType))
+	if err != nil {
+		t.Fatalf("CreateDocument: %v", err)
+	}
+	return doc
+}
+
+// assertStored verifica el estado con el que quedó guardado el documento
+func assertStored(t *testing.T, s *DocumentService, id string, status models.DocumentStatus) *models.Document {
+	t.Helper()
+
+	doc, err := s.GetDocument(id)
+	if err != nil {
+		t.Fatalf("GetDocument: %v", err)
+	}
+	if doc.Status != status {
+		t.Fatalf("stored status = %s, want %s (history: %+v)", doc.Status, status, doc.StatusHistory)
+	}
+	return doc
+}
+
+// hasHistory indica si algún cambio del historial menciona reason
+func hasHistory(doc *models.Document, reason string) bool {
+	for _, change := range doc.StatusHistory {
+		if strings.Contains(change.Reason, reason) {
+			return true
+		}
+	}
+	return false
+}
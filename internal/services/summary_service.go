@@ -0,0 +1,663 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"infac/internal/models"
+	"infac/internal/storage"
+	"infac/pkg/sunat"
+	"infac/pkg/ubl"
+)
+
+const (
+	// voidDeadlineDays es el plazo para comunicar la baja de un comprobante,
+	// contado desde su fecha de emisión
+	voidDeadlineDays = 7
+	// summaryDeadlineDays es el plazo para informar las boletas de un día en
+	// el Resumen Diario
+	summaryDeadlineDays = 7
+	// maxSummaryLines es la cantidad máxima de comprobantes por resumen
+	maxSummaryLines = 500
+)
+
+var (
+	ErrDocumentNotVoidable = errors.New("document cannot be voided")
+	ErrSummaryNotSent      = errors.New("summary could not be sent to SUNAT")
+	ErrStatusNotAvailable  = errors.New("ticket status could not be retrieved from SUNAT")
+)
+
+// VoidDocuments envía una Comunicación de Baja (RA) con los documentos
+// indicados. Todos deben estar aceptados, tener la misma fecha de emisión y la
+// misma fecha de baja. Los documentos pasan a anulados recién cuando SUNAT
+// acepta el ticket.
+func (s *DocumentService) VoidDocuments(reqs []models.VoidDocumentRequest) (*models.Ticket, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("at least one document is required")
+	}
+
+	var (
+		items         []ubl.VoidedItem
+		voidDate      time.Time
+		referenceDate time.Time
+		seen          = make(map[string]bool)
+	)
+
+	for i, req := range reqs {
+		doc, err := s.repo.FindByID(models.DocumentID(req.Serie, req.Number))
+		if err != nil {
+			return nil, err
+		}
+		if seen[doc.ID] {
+			return nil, fmt.Errorf("document %s is included more than once", doc.ID)
+		}
+		seen[doc.ID] = true
+
+		date, err := time.Parse("2006-01-02", req.VoidDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid void date format: %w", err)
+		}
+		if i == 0 {
+			voidDate = date
+			referenceDate = doc.IssueDate
+		}
+
+		if err := s.checkVoidable(doc, req, date, voidDate, referenceDate); err != nil {
+			return nil, err
+		}
+
+		items = append(items, ubl.VoidedItem{Document: doc, Reason: strings.TrimSpace(req.Reason)})
+	}
+
+	docs := make([]*models.Document, 0, len(items))
+	for _, item := range items {
+		docs = append(docs, item.Document)
+	}
+	// La reserva impide que otra baja o un resumen incluya los mismos
+	// documentos antes de que quede registrado el ticket
+	release, err := s.claim(docs...)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	summaryID, err := s.numbering.NextSummaryID(models.SummaryTypeVoided, voidDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign summary ID: %w", err)
+	}
+
+	ticket := &models.Ticket{
+		SummaryID:     summaryID,
+		Type:          models.SummaryTypeVoided,
+		IssueDate:     voidDate,
+		ReferenceDate: referenceDate,
+		Status:        models.TicketStatusPending,
+	}
+	for _, item := range items {
+		ticket.DocumentIDs = append(ticket.DocumentIDs, item.Document.ID)
+	}
+
+	voided, err := ubl.GenerateVoidedDocumentsXML(ticket, items, s.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate voided documents XML: %w", err)
+	}
+
+	channel, err := s.senders.summaryChannel(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sendSummary(ticket, voided, channel); err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		item.Document.VoidTicket = ticket.Number
+		item.Document.VoidReason = item.Reason
+		// El ticket ya está registrado: el documento se actualiza igual al
+		// consultarlo
+		if err := s.persist(item.Document); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	return ticket, nil
+}
+
+// SendDailySummary envía el Resumen Diario (RC) de las boletas y notas de
+// serie B emitidas en la fecha indicada. Los borradores de ese día se
+// informan como adicionados y los documentos de req.Void como anulados.
+func (s *DocumentService) SendDailySummary(req *models.DailySummaryRequest) (*models.Ticket, error) {
+	referenceDate, err := time.Parse("2006-01-02", req.ReferenceDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference date format: %w", err)
+	}
+
+	issueDate, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	if referenceDate.After(issueDate) {
+		return nil, fmt.Errorf("reference date cannot be in the future")
+	}
+	if issueDate.After(referenceDate.AddDate(0, 0, summaryDeadlineDays)) {
+		return nil, fmt.Errorf("documents issued on %s can no longer be reported (%d days limit)", req.ReferenceDate, summaryDeadlineDays)
+	}
+
+	issued, err := s.repo.List(storage.DocumentFilter{
+		From: &referenceDate,
+		To:   &referenceDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		items  []ubl.SummaryItem
+		drafts []*models.Document
+	)
+	for _, doc := range issued {
+		if !isSummaryDocument(doc) {
+			continue
+		}
+		switch {
+		case doc.Status == models.StatusDraft:
+			drafts = append(drafts, doc)
+		case doc.Status == models.StatusAccepted && doc.Modified:
+			// Mientras un resumen anterior con la corrección siga en
+			// proceso no se vuelve a informar
+			pending, err := s.ticketPending(doc.SummaryTicket)
+			if err != nil {
+				return nil, err
+			}
+			if !pending {
+				items = append(items, ubl.SummaryItem{Document: doc, Condition: models.SummaryConditionModify})
+			}
+		}
+	}
+
+	voidReasons := make(map[string]string)
+	for _, voidReq := range req.Void {
+		doc, err := s.repo.FindByID(models.DocumentID(voidReq.Serie, voidReq.Number))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := voidReasons[doc.ID]; ok {
+			return nil, fmt.Errorf("document %s is included more than once", doc.ID)
+		}
+		if doc.Type != voidReq.DocumentType {
+			return nil, fmt.Errorf("%w: %s is of type %s, not %s", ErrDocumentNotVoidable, doc.ID, doc.Type, voidReq.DocumentType)
+		}
+		if !isSummaryDocument(doc) {
+			return nil, fmt.Errorf("%w: %s must be voided through a voided documents communication (RA)", ErrDocumentNotVoidable, doc.ID)
+		}
+		if doc.Status != models.StatusAccepted {
+			return nil, fmt.Errorf("%w: %s is %s, only accepted documents can be voided", ErrDocumentNotVoidable, doc.ID, doc.Status)
+		}
+		if !doc.IssueDate.Equal(referenceDate) {
+			return nil, fmt.Errorf("document %s was not issued on %s", doc.ID, req.ReferenceDate)
+		}
+		if err := s.checkPendingVoid(doc); err != nil {
+			return nil, err
+		}
+
+		// La anulación reemplaza la corrección pendiente del documento
+		for i, item := range items {
+			if item.Document.ID == doc.ID {
+				items = append(items[:i], items[i+1:]...)
+				break
+			}
+		}
+
+		voidReasons[doc.ID] = strings.TrimSpace(voidReq.Reason)
+		items = append(items, ubl.SummaryItem{Document: doc, Condition: models.SummaryConditionVoid})
+	}
+
+	// Los documentos se reservan antes de pasar a pendientes: otro resumen
+	// o un envío individual no puede tomarlos mientras tanto
+	docs := make([]*models.Document, 0, len(items))
+	for _, item := range items {
+		docs = append(docs, item.Document)
+	}
+	release, err := s.claim(docs...)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Cada borrador se reserva y verifica por separado, como en un envío
+	// individual: el que no se puede informar queda fuera del resumen sin
+	// impedir el de los demás
+	skipped := make(map[string]error)
+	for _, doc := range drafts {
+		releaseDraft, err := s.claimDraft(doc)
+		if err != nil {
+			skipped[doc.ID] = err
+			continue
+		}
+		defer releaseDraft()
+		items = append(items, ubl.SummaryItem{Document: doc, Condition: models.SummaryConditionAdd})
+		docs = append(docs, doc)
+	}
+
+	if len(items) == 0 {
+		if len(skipped) > 0 {
+			return nil, fmt.Errorf("no documents to report for %s: %w", req.ReferenceDate, skippedError(skipped))
+		}
+		return nil, fmt.Errorf("no documents to report for %s", req.ReferenceDate)
+	}
+	if len(items) > maxSummaryLines {
+		return nil, fmt.Errorf("a daily summary cannot have more than %d documents (%d found)", maxSummaryLines, len(items))
+	}
+
+	summaryID, err := s.numbering.NextSummaryID(models.SummaryTypeDaily, issueDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign summary ID: %w", err)
+	}
+
+	ticket := &models.Ticket{
+		SummaryID:     summaryID,
+		Type:          models.SummaryTypeDaily,
+		IssueDate:     issueDate,
+		ReferenceDate: referenceDate,
+		Conditions:    make(map[string]models.SummaryCondition),
+		Status:        models.TicketStatusPending,
+	}
+	for _, item := range items {
+		ticket.DocumentIDs = append(ticket.DocumentIDs, item.Document.ID)
+		ticket.Conditions[item.Document.ID] = item.Condition
+	}
+
+	summary, err := ubl.GenerateSummaryDocumentsXML(ticket, items, s.issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate summary documents XML: %w", err)
+	}
+
+	channel, err := s.senders.summaryChannel(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Los borradores quedan pendientes mientras se envía el resumen para que
+	// no se incluyan en otro ni se envíen por separado. Si alguno no se
+	// puede guardar, el resumen no se envía.
+	var added []*models.Document
+	for _, item := range items {
+		if item.Condition != models.SummaryConditionAdd {
+			continue
+		}
+		err := item.Document.TransitionTo(models.StatusPending, fmt.Sprintf("Incluido en el resumen %s", summaryID))
+		if err == nil {
+			err = s.persist(item.Document)
+		}
+		if err != nil {
+			return nil, errors.Join(err, s.revertDrafts(added, err.Error()))
+		}
+		added = append(added, item.Document)
+	}
+
+	if err := s.sendSummary(ticket, summary, channel); err != nil {
+		return nil, errors.Join(err, s.revertDrafts(added, err.Error()))
+	}
+
+	for _, item := range items {
+		doc := item.Document
+		doc.SummaryTicket = ticket.Number
+		doc.Channel = ticket.Channel
+		switch item.Condition {
+		case models.SummaryConditionVoid:
+			doc.VoidTicket = ticket.Number
+			doc.VoidReason = voidReasons[doc.ID]
+		case models.SummaryConditionModify:
+			doc.Note(fmt.Sprintf("Corrección informada en el resumen %s con ticket %s", summaryID, ticket.Number))
+		default:
+			if err := doc.TransitionTo(models.StatusSent, fmt.Sprintf("Resumen %s enviado con ticket %s", summaryID, ticket.Number)); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+		// El ticket ya está registrado: el documento se actualiza igual al
+		// consultarlo
+		if err := s.persist(doc); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	if len(skipped) > 0 {
+		ticket.Skipped = make(map[string]string, len(skipped))
+		for id, err := range skipped {
+			ticket.Skipped[id] = err.Error()
+		}
+	}
+	return ticket, nil
+}
+
+// claimDraft reserva un borrador para el Resumen Diario y verifica, como
+// antes de un envío individual, sus totales y el saldo de los anticipos que
+// deduce
+func (s *DocumentService) claimDraft(doc *models.Document) (func(), error) {
+	release, err := s.claim(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.CheckTotals(); err != nil {
+		release()
+		return nil, err
+	}
+	if err := s.checkPrepayments(doc); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// skippedError reúne los motivos por los que los borradores quedaron fuera
+// de un resumen, conservando sus errores para responder con su código
+func skippedError(skipped map[string]error) error {
+	ids := make([]string, 0, len(skipped))
+	for id := range skipped {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		errs[i] = fmt.Errorf("%s: %w", id, skipped[id])
+	}
+	return errors.Join(errs...)
+}
+
+// revertDrafts devuelve a borrador los documentos que pasaron a pendientes
+// para un resumen que no se envió
+func (s *DocumentService) revertDrafts(docs []*models.Document, reason string) error {
+	var errs []error
+	for _, doc := range docs {
+		if err := doc.TransitionTo(models.StatusDraft, reason); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.persist(doc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isSummaryDocument indica si el documento se informa en el Resumen Diario:
+// boletas y notas de serie B
+func isSummaryDocument(doc *models.Document) bool {
+	return doc.Type == models.DocumentTypeBoleta || strings.HasPrefix(doc.Serie, "B")
+}
+
+// checkPendingVoid rechaza documentos con una baja en curso o ya aceptada
+func (s *DocumentService) checkPendingVoid(doc *models.Document) error {
+	if doc.VoidTicket == "" {
+		return nil
+	}
+
+	previous, err := s.tickets.FindTicket(doc.VoidTicket)
+	if errors.Is(err, storage.ErrTicketNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if previous.Status != models.TicketStatusRejected {
+		return fmt.Errorf("%w: %s already has void ticket %s", ErrDocumentNotVoidable, doc.ID, doc.VoidTicket)
+	}
+
+	return nil
+}
+
+// checkModifiable verifica que una boleta o nota ya aceptada en un Resumen
+// Diario pueda corregirse: sin una baja solicitada y sin un resumen en
+// proceso que la incluya
+func (s *DocumentService) checkModifiable(doc *models.Document) error {
+	if doc.VoidTicket != "" {
+		voided, err := s.tickets.FindTicket(doc.VoidTicket)
+		if err != nil && !errors.Is(err, storage.ErrTicketNotFound) {
+			return err
+		}
+		if err == nil && voided.Status != models.TicketStatusRejected {
+			return fmt.Errorf("%w: %s has void ticket %s", ErrDocumentNotDraft, doc.ID, doc.VoidTicket)
+		}
+	}
+
+	pending, err := s.ticketPending(doc.SummaryTicket)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return fmt.Errorf("%w: %s is reported in pending ticket %s", ErrDocumentBusy, doc.ID, doc.SummaryTicket)
+	}
+	return nil
+}
+
+// ticketPending indica si todavía no se conoce la respuesta de SUNAT al
+// ticket: sigue en proceso o su CDR no se pudo leer
+func (s *DocumentService) ticketPending(number string) (bool, error) {
+	if number == "" {
+		return false, nil
+	}
+	ticket, err := s.tickets.FindTicket(number)
+	if errors.Is(err, storage.ErrTicketNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return ticket.Status == models.TicketStatusPending || ticket.Status == models.TicketStatusCDRUnreadable, nil
+}
+
+// checkVoidable valida que el documento pueda incluirse en la comunicación
+// de baja junto con los anteriores
+func (s *DocumentService) checkVoidable(doc *models.Document, req models.VoidDocumentRequest, date, voidDate, referenceDate time.Time) error {
+	if doc.Type != req.DocumentType {
+		return fmt.Errorf("%w: %s is of type %s, not %s", ErrDocumentNotVoidable, doc.ID, doc.Type, req.DocumentType)
+	}
+	if doc.Status != models.StatusAccepted {
+		return fmt.Errorf("%w: %s is %s, only accepted documents can be voided", ErrDocumentNotVoidable, doc.ID, doc.Status)
+	}
+	// Las boletas y sus notas se anulan en el Resumen Diario
+	if isSummaryDocument(doc) {
+		return fmt.Errorf("%w: %s must be voided through the daily summary (RC)", ErrDocumentNotVoidable, doc.ID)
+	}
+	if err := s.checkPendingVoid(doc); err != nil {
+		return err
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || len([]rune(reason)) > 100 {
+		return fmt.Errorf("void reason for %s must have between 1 and 100 characters", doc.ID)
+	}
+
+	if !date.Equal(voidDate) {
+		return fmt.Errorf("all documents must have the same void date")
+	}
+	if !doc.IssueDate.Equal(referenceDate) {
+		return fmt.Errorf("all documents must have the same issue date (%s)", referenceDate.Format("2006-01-02"))
+	}
+	if date.Before(doc.IssueDate) {
+		return fmt.Errorf("void date cannot be before the issue date of %s", doc.ID)
+	}
+	if date.After(doc.IssueDate.AddDate(0, 0, voidDeadlineDays)) {
+		return fmt.Errorf("%w: %s was issued more than %d days before the void date", ErrDocumentNotVoidable, doc.ID, voidDeadlineDays)
+	}
+
+	return nil
+}
+
+// sendSummary firma y comprime el resumen, lo envía con sendSummary por el
+// canal de sus documentos y guarda el ticket recibido
+func (s *DocumentService) sendSummary(ticket *models.Ticket, summary interface{}, channel string) error {
+	sender, err := s.senders.sender(channel)
+	if err != nil {
+		return err
+	}
+
+	xmlContent, err := xml.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal summary XML: %w", err)
+	}
+
+	signedXML, err := s.sign(append([]byte(xml.Header), xmlContent...))
+	if err != nil {
+		return fmt.Errorf("%w: failed to sign XML: %v", ErrSummaryNotSent, err)
+	}
+
+	baseName := fmt.Sprintf("%s-%s", s.issuer.DocumentNumber, ticket.SummaryID)
+	xmlPath := filepath.Join(s.filesDir, "xml", baseName+"-signed.xml")
+	if err := os.WriteFile(xmlPath, signedXML, 0644); err != nil {
+		fmt.Printf("Warning: Failed to save signed XML: %v\n", err)
+	}
+
+	zipContent, err := s.createZipFile(baseName+".xml", signedXML)
+	if err != nil {
+		return fmt.Errorf("failed to create ZIP: %w", err)
+	}
+
+	number, err := sender.SendSummary(baseName+".zip", zipContent)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSummaryNotSent, sunatErrorFrom(sender.Name(), err))
+	}
+
+	ticket.Number = number
+	ticket.Channel = channel
+	ticket.CreatedAt = time.Now()
+	ticket.UpdatedAt = ticket.CreatedAt
+	if err := s.tickets.SaveTicket(ticket); err != nil {
+		return fmt.Errorf("failed to save ticket %s: %w", number, err)
+	}
+
+	return nil
+}
+
+// CheckStatus consulta el ticket con getStatus. Si SUNAT ya lo procesó,
+// guarda el CDR y actualiza los documentos incluidos; mientras siga en
+// proceso el ticket se devuelve pendiente. Si el CDR no se puede leer, el
+// ticket queda cdr_unreadable con el CDR original guardado y se vuelve a
+// consultar solo con una nueva llamada a CheckStatus.
+func (s *DocumentService) CheckStatus(number string) (*models.Ticket, error) {
+	s.ticketMu.Lock()
+	defer s.ticketMu.Unlock()
+
+	ticket, err := s.tickets.FindTicket(number)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.Status != models.TicketStatusPending && ticket.Status != models.TicketStatusCDRUnreadable {
+		return ticket, nil
+	}
+
+	sender, err := s.senders.sender(ticket.Channel)
+	if err != nil {
+		return nil, err
+	}
+	status, err := sender.GetStatus(number)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStatusNotAvailable, sunatErrorFrom(sender.Name(), err))
+	}
+	if status.Code == sunat.StatusInProgress {
+		return ticket, nil
+	}
+
+	cdr := &models.CDR{ResponseCode: status.Code, Description: "Procesado con errores"}
+	if len(status.Content) > 0 {
+		cdrPath := filepath.Join(s.filesDir, "cdr", fmt.Sprintf("R-%s-%s.zip", s.issuer.DocumentNumber, ticket.SummaryID))
+		if err := os.WriteFile(cdrPath, status.Content, 0644); err != nil {
+			fmt.Printf("Warning: Failed to save CDR: %v\n", err)
+		}
+
+		cdr, err = ubl.ParseCDR(status.Content)
+		if err != nil {
+			// Se deja de consultar el ticket para no descargar el mismo CDR
+			// una y otra vez
+			ticket.Status = models.TicketStatusCDRUnreadable
+			ticket.UpdatedAt = time.Now()
+			if updateErr := s.tickets.UpdateTicket(ticket); updateErr != nil {
+				return nil, fmt.Errorf("failed to update ticket %s: %w", number, updateErr)
+			}
+			return nil, fmt.Errorf("%w: ticket %s: %v", ErrCDRNotReadable, number, err)
+		}
+	}
+
+	accepted := status.Code == sunat.StatusProcessed && cdr.Accepted()
+	if err := s.completeTicket(ticket, cdr, accepted); err != nil {
+		return nil, err
+	}
+
+	return ticket, nil
+}
+
+// completeTicket registra la respuesta de SUNAT para el ticket y actualiza
+// sus documentos: los anulados pasan a cancelled si el ticket fue aceptado, y
+// los informados en un Resumen Diario quedan aceptados o rechazados.
+func (s *DocumentService) completeTicket(ticket *models.Ticket, cdr *models.CDR, accepted bool) error {
+	ticket.CDR = cdr
+	ticket.UpdatedAt = time.Now()
+	if accepted {
+		ticket.Status = models.TicketStatusAccepted
+	} else {
+		ticket.Status = models.TicketStatusRejected
+	}
+
+	if err := s.tickets.UpdateTicket(ticket); err != nil {
+		return fmt.Errorf("failed to update ticket %s: %w", ticket.Number, err)
+	}
+
+	for _, id := range ticket.DocumentIDs {
+		void := ticket.Type == models.SummaryTypeVoided || ticket.Conditions[id] == models.SummaryConditionVoid
+		if void && !accepted {
+			// El documento sigue aceptado; se puede volver a solicitar la baja
+			continue
+		}
+
+		doc, err := s.repo.FindByID(id)
+		if err != nil {
+			fmt.Printf("Warning: Failed to load document %s of ticket %s: %v\n", id, ticket.Number, err)
+			continue
+		}
+
+		// Un documento corregido sigue aceptado: si el resumen se rechaza,
+		// la corrección queda pendiente para el siguiente
+		modify := ticket.Conditions[id] == models.SummaryConditionModify
+		switch {
+		case void:
+			err = doc.TransitionTo(models.StatusCancelled, fmt.Sprintf("Baja aceptada en %s", ticket.SummaryID))
+		case modify && accepted:
+			doc.Modified = false
+			doc.Note(fmt.Sprintf("Corrección aceptada en el resumen %s", ticket.SummaryID))
+		case modify:
+			doc.Note(fmt.Sprintf("Corrección rechazada en el resumen %s", ticket.SummaryID))
+		case accepted:
+			err = doc.TransitionTo(models.StatusAccepted, fmt.Sprintf("Resumen %s aceptado", ticket.SummaryID))
+		default:
+			err = doc.TransitionTo(models.StatusRejected, fmt.Sprintf("Resumen %s rechazado", ticket.SummaryID))
+		}
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			continue
+		}
+		// Un documento anulado conserva el CDR de su aceptación, y uno
+		// corregido el del último resumen aceptado
+		if !void && (!modify || accepted) {
+			doc.CDR = cdr
+		}
+		if err := s.persist(doc); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *DocumentService) GetTicket(number string) (*models.Ticket, error) {
+	return s.tickets.FindTicket(number)
+}
+
+// ListTickets devuelve los tickets en el estado indicado ("" = todos)
+func (s *DocumentService) ListTickets(status models.TicketStatus) ([]*models.Ticket, error) {
+	return s.tickets.ListTickets(status)
+}
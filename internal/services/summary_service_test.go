@@ -0,0 +1,411 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"infac/internal/config"
+	"infac/internal/models"
+	"infac/internal/storage"
+	"infac/pkg/decimal"
+	"infac/pkg/sunat"
+	"infac/pkg/sunat/fake"
+)
+
+func today() string {
+	return time.Now().Format("2006-01-02")
+}
+
+// El resumen deja las boletas enviadas con su ticket; getStatus responde "98"
+// mientras SUNAT lo procesa y luego el CDR que las acepta
+func TestDailySummaryTicketFlow(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-RC-*", fake.Behavior{Polls: 1})
+	first := createTestDocument(t, s, models.DocumentTypeBoleta)
+	second := createTestDocument(t, s, models.DocumentTypeBoleta)
+	invoice := createTestDocument(t, s, models.DocumentTypeFactura)
+
+	ticket, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()})
+	if err != nil {
+		t.Fatalf("SendDailySummary: %v", err)
+	}
+	if ticket.Number == "" || ticket.Status != models.TicketStatusPending || len(ticket.DocumentIDs) != 2 {
+		t.Fatalf("ticket = %+v", ticket)
+	}
+	for _, id := range []string{first.ID, second.ID} {
+		if doc := assertStored(t, s, id, models.StatusSent); doc.SummaryTicket != ticket.Number {
+			t.Errorf("%s summary ticket = %q, want %q", id, doc.SummaryTicket, ticket.Number)
+		}
+	}
+	// Las facturas se envían por separado
+	assertStored(t, s, invoice.ID, models.StatusDraft)
+
+	checked, err := s.CheckStatus(ticket.Number)
+	if err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	if checked.Status != models.TicketStatusPending {
+		t.Fatalf("ticket in progress = %s", checked.Status)
+	}
+
+	checked, err = s.CheckStatus(ticket.Number)
+	if err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	if checked.Status != models.TicketStatusAccepted || checked.CDR == nil || !checked.CDR.Accepted() {
+		t.Fatalf("ticket = %s, CDR %+v", checked.Status, checked.CDR)
+	}
+	for _, id := range []string{first.ID, second.ID} {
+		if doc := assertStored(t, s, id, models.StatusAccepted); doc.CDR == nil {
+			t.Errorf("%s has no CDR", id)
+		}
+	}
+
+	var operations []string
+	for _, req := range server.Requests() {
+		operations = append(operations, req.Operation)
+	}
+	if len(operations) != 3 || operations[0] != "sendSummary" || operations[2] != "getStatus" {
+		t.Errorf("operations = %v", operations)
+	}
+}
+
+func TestDailySummaryRejected(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-RC-*", fake.Behavior{ResponseCode: "2220", Description: "El ID debe coincidir con el nombre del archivo"})
+	doc := createTestDocument(t, s, models.DocumentTypeBoleta)
+
+	ticket, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()})
+	if err != nil {
+		t.Fatalf("SendDailySummary: %v", err)
+	}
+	checked, err := s.CheckStatus(ticket.Number)
+	if err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	if checked.Status != models.TicketStatusRejected || checked.CDR.ResponseCode != "2220" {
+		t.Fatalf("ticket = %s, CDR %+v", checked.Status, checked.CDR)
+	}
+	assertStored(t, s, doc.ID, models.StatusRejected)
+}
+
+// Si sendSummary falla los borradores vuelven a estar disponibles
+func TestDailySummaryNotSent(t *testing.T) {
+	s, server := newTestService(t)
+	server.AddRule(fake.Rule{Pattern: "*-RC-*", Behavior: fake.Behavior{FaultCode: "0130"}, Times: 1})
+	doc := createTestDocument(t, s, models.DocumentTypeBoleta)
+
+	if _, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()}); !errors.Is(err, ErrSummaryNotSent) {
+		t.Fatalf("SendDailySummary = %v, want ErrSummaryNotSent", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusDraft)
+
+	if _, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()}); err != nil {
+		t.Fatalf("second SendDailySummary: %v", err)
+	}
+	assertStored(t, s, doc.ID, models.StatusSent)
+}
+
+// corruptStatusSender consulta el ticket en el canal real pero devuelve un
+// CDR que no se puede leer
+type corruptStatusSender struct {
+	Sender
+}
+
+func (c corruptStatusSender) GetStatus(ticket string) (*sunat.TicketStatus, error) {
+	status, err := c.Sender.GetStatus(ticket)
+	if err != nil || len(status.Content) == 0 {
+		return status, err
+	}
+	return &sunat.TicketStatus{Code: status.Code, Content: []byte("not a zip")}, nil
+}
+
+// Un CDR ilegible deja de consultarse automáticamente; se recupera con una
+// consulta explícita
+func TestCheckStatusCDRNotReadable(t *testing.T) {
+	s, server := newTestService(t)
+	direct := s.senders.senders[config.ChannelSUNAT]
+	s.senders.senders[config.ChannelSUNAT] = corruptStatusSender{direct}
+	doc := createTestDocument(t, s, models.DocumentTypeBoleta)
+
+	ticket, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()})
+	if err != nil {
+		t.Fatalf("SendDailySummary: %v", err)
+	}
+	if _, err := s.CheckStatus(ticket.Number); !errors.Is(err, ErrCDRNotReadable) {
+		t.Fatalf("CheckStatus = %v, want ErrCDRNotReadable", err)
+	}
+	stored, err := s.GetTicket(ticket.Number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.TicketStatusCDRUnreadable {
+		t.Fatalf("ticket status = %s, want %s", stored.Status, models.TicketStatusCDRUnreadable)
+	}
+	pending, err := s.ListTickets(models.TicketStatusPending)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("pending tickets = %v, %v; want none", pending, err)
+	}
+	assertStored(t, s, doc.ID, models.StatusSent)
+
+	// El poller ya no lo consulta
+	requests := len(server.Requests())
+	NewTicketPoller(s, time.Millisecond, time.Millisecond).poll(time.Now())
+	if n := len(server.Requests()); n != requests {
+		t.Errorf("poller sent %d requests, want 0", n-requests)
+	}
+
+	s.senders.senders[config.ChannelSUNAT] = direct
+	checked, err := s.CheckStatus(ticket.Number)
+	if err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	if checked.Status != models.TicketStatusAccepted {
+		t.Errorf("ticket status = %s, want accepted", checked.Status)
+	}
+	assertStored(t, s, doc.ID, models.StatusAccepted)
+}
+
+func TestCheckStatusUnknownTicket(t *testing.T) {
+	s, _ := newTestService(t)
+	if _, err := s.CheckStatus("123"); !errors.Is(err, storage.ErrTicketNotFound) {
+		t.Fatalf("CheckStatus = %v, want ErrTicketNotFound", err)
+	}
+}
+
+// Dos resúmenes simultáneos no informan las mismas boletas
+func TestDailySummaryConcurrentClaim(t *testing.T) {
+	s, server := newTestService(t)
+	server.On("*-RC-*", fake.Behavior{Delay: fake.Duration(200 * time.Millisecond)})
+	doc := createTestDocument(t, s, models.DocumentTypeBoleta)
+
+	const summaries = 4
+	tickets := make(chan *models.Ticket, summaries)
+	var wg sync.WaitGroup
+	for i := 0; i < summaries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticket, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()})
+			if err == nil {
+				tickets <- ticket
+			}
+		}()
+	}
+	wg.Wait()
+	close(tickets)
+
+	if n := len(tickets); n != 1 {
+		t.Fatalf("%d summaries were sent, want 1", n)
+	}
+	ticket := <-tickets
+	if stored := assertStored(t, s, doc.ID, models.StatusSent); stored.SummaryTicket != ticket.Number {
+		t.Errorf("summary ticket = %q, want %q", stored.SummaryTicket, ticket.Number)
+	}
+}
+
+// Dos bajas simultáneas de la misma factura generan un solo ticket
+func TestVoidDocumentsConcurrentClaim(t *testing.T) {
+	s, server := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+	if _, err := s.SendDocumentByID(doc.ID); err != nil {
+		t.Fatalf("SendDocumentByID: %v", err)
+	}
+	server.On("*-RA-*", fake.Behavior{Delay: fake.Duration(200 * time.Millisecond)})
+
+	req := []models.VoidDocumentRequest{{
+		DocumentType: models.DocumentTypeFactura, Serie: doc.Serie, Number: doc.Number,
+		VoidDate: today(), Reason: "Error en el RUC del adquirente",
+	}}
+	const voids = 4
+	tickets := make(chan *models.Ticket, voids)
+	var wg sync.WaitGroup
+	for i := 0; i < voids; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticket, err := s.VoidDocuments(req)
+			if err == nil {
+				tickets <- ticket
+			} else if !errors.Is(err, ErrDocumentBusy) && !errors.Is(err, ErrDocumentNotVoidable) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(tickets)
+
+	if n := len(tickets); n != 1 {
+		t.Fatalf("%d void tickets were created, want 1", n)
+	}
+	ticket := <-tickets
+	if stored := assertStored(t, s, doc.ID, models.StatusAccepted); stored.VoidTicket != ticket.Number {
+		t.Errorf("void ticket = %q, want %q", stored.VoidTicket, ticket.Number)
+	}
+}
+
+// La baja encuentra el documento aunque el número no tenga los ceros a la
+// izquierda
+func TestVoidDocumentsUnpaddedNumber(t *testing.T) {
+	s, _ := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeFactura)
+	if _, err := s.SendDocumentByID(doc.ID); err != nil {
+		t.Fatalf("SendDocumentByID: %v", err)
+	}
+
+	ticket, err := s.VoidDocuments([]models.VoidDocumentRequest{{
+		DocumentType: models.DocumentTypeFactura, Serie: doc.Serie, Number: strings.TrimLeft(doc.Number, "0"),
+		VoidDate: today(), Reason: "Error en el RUC del adquirente",
+	}})
+	if err != nil {
+		t.Fatalf("VoidDocuments: %v", err)
+	}
+	if len(ticket.DocumentIDs) != 1 || ticket.DocumentIDs[0] != doc.ID {
+		t.Errorf("ticket documents = %v, want [%s]", ticket.DocumentIDs, doc.ID)
+	}
+}
+
+// Un resumen informa los borradores del día como adicionados (1), las boletas
+// ya aceptadas y corregidas como modificadas (2) y las anuladas (3)
+func TestDailySummaryConditions(t *testing.T) {
+	s, _ := newTestService(t)
+	modified := createTestDocument(t, s, models.DocumentTypeBoleta)
+	voided := createTestDocument(t, s, models.DocumentTypeBoleta)
+	first, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()})
+	if err != nil {
+		t.Fatalf("SendDailySummary: %v", err)
+	}
+	if _, err := s.CheckStatus(first.Number); err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+
+	added := createTestDocument(t, s, models.DocumentTypeBoleta)
+	correction := testRequest(t, models.DocumentTypeBoleta)
+	correction.Serie, correction.Number = modified.Serie, modified.Number
+	correction.Lines[0].Quantity = decimal.MustParse("3")
+	correction.PaymentTerms.Amount = decimal.MustParse("354")
+	updated, err := s.UpdateDocument(modified.ID, correction)
+	if err != nil {
+		t.Fatalf("UpdateDocument: %v", err)
+	}
+	if updated.Status != models.StatusAccepted || !updated.Modified || updated.TotalAmount.String() != "354.00" {
+		t.Fatalf("updated = %s, modified %v, total %s", updated.Status, updated.Modified, updated.TotalAmount)
+	}
+
+	second, err := s.SendDailySummary(&models.DailySummaryRequest{
+		ReferenceDate: today(),
+		// El número se puede indicar sin los ceros a la izquierda
+		Void: []models.SummaryVoidRequest{{
+			DocumentType: models.DocumentTypeBoleta, Serie: voided.Serie, Number: strings.TrimLeft(voided.Number, "0"), Reason: "Venta anulada",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("SendDailySummary: %v", err)
+	}
+	want := map[string]models.SummaryCondition{
+		added.ID:    models.SummaryConditionAdd,
+		modified.ID: models.SummaryConditionModify,
+		voided.ID:   models.SummaryConditionVoid,
+	}
+	if len(second.Conditions) != len(want) {
+		t.Fatalf("conditions = %v, want %v", second.Conditions, want)
+	}
+	for id, condition := range want {
+		if second.Conditions[id] != condition {
+			t.Errorf("condition of %s = %q, want %q", id, second.Conditions[id], condition)
+		}
+	}
+
+	// El XML enviado informa cada línea con su estado y el importe corregido
+	data, err := os.ReadFile(filepath.Join(s.filesDir, "xml", testRUC+"-"+second.SummaryID+"-signed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary struct {
+		Lines []struct {
+			ID          string `xml:"ID"`
+			Condition   string `xml:"Status>ConditionCode"`
+			TotalAmount string `xml:"TotalAmount"`
+		} `xml:"SummaryDocumentsLine"`
+	}
+	if err := xml.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Lines) != 3 {
+		t.Fatalf("summary has %d lines, want 3", len(summary.Lines))
+	}
+	for _, line := range summary.Lines {
+		if models.SummaryCondition(line.Condition) != want[line.ID] {
+			t.Errorf("XML condition of %s = %s, want %s", line.ID, line.Condition, want[line.ID])
+		}
+		if line.ID == modified.ID && line.TotalAmount != "354.00" {
+			t.Errorf("XML total of %s = %s, want 354.00", line.ID, line.TotalAmount)
+		}
+	}
+
+	// Con el resumen en proceso la boleta no se puede volver a corregir
+	if _, err := s.UpdateDocument(modified.ID, correction); !errors.Is(err, ErrDocumentBusy) {
+		t.Errorf("UpdateDocument during summary = %v, want ErrDocumentBusy", err)
+	}
+
+	if _, err := s.CheckStatus(second.Number); err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	assertStored(t, s, added.ID, models.StatusAccepted)
+	assertStored(t, s, voided.ID, models.StatusCancelled)
+	if stored := assertStored(t, s, modified.ID, models.StatusAccepted); stored.Modified || !hasHistory(stored, "Corrección aceptada") {
+		t.Errorf("modified = %v, history %+v", stored.Modified, stored.StatusHistory)
+	}
+
+	// Sin pendientes no hay nada más que informar
+	if _, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()}); err == nil {
+		t.Error("third SendDailySummary succeeded without documents")
+	}
+}
+
+// Si el resumen con la corrección se rechaza, se vuelve a informar en el
+// siguiente
+func TestDailySummaryModifyRejected(t *testing.T) {
+	s, server := newTestService(t)
+	doc := createTestDocument(t, s, models.DocumentTypeBoleta)
+	first, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()})
+	if err != nil {
+		t.Fatalf("SendDailySummary: %v", err)
+	}
+	if _, err := s.CheckStatus(first.Number); err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+
+	correction := testRequest(t, models.DocumentTypeBoleta)
+	correction.Serie, correction.Number = doc.Serie, doc.Number
+	if _, err := s.UpdateDocument(doc.ID, correction); err != nil {
+		t.Fatalf("UpdateDocument: %v", err)
+	}
+
+	server.AddRule(fake.Rule{Pattern: "*-RC-*", Behavior: fake.Behavior{ResponseCode: "2220"}, Times: 1})
+	rejected, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()})
+	if err != nil {
+		t.Fatalf("SendDailySummary: %v", err)
+	}
+	if _, err := s.CheckStatus(rejected.Number); err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	stored := assertStored(t, s, doc.ID, models.StatusAccepted)
+	if !stored.Modified || stored.CDR == nil || !stored.CDR.Accepted() {
+		t.Fatalf("after rejection: modified %v, CDR %+v", stored.Modified, stored.CDR)
+	}
+
+	retry, err := s.SendDailySummary(&models.DailySummaryRequest{ReferenceDate: today()})
+	if err != nil {
+		t.Fatalf("SendDailySummary: %v", err)
+	}
+	if retry.Conditions[doc.ID] != models.SummaryConditionModify {
+		t.Errorf("condition = %q, want modify", retry.Conditions[doc.ID])
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"infac/pkg/sunat"
+)
+
+// SUNATError es la respuesta de SUNAT o del OSE que impidió aceptar un
+// comprobante o un resumen: un SOAP Fault o un CDR con código de rechazo,
+// clasificado según el catálogo de códigos de retorno
+type SUNATError struct {
+	sunat.Code
+	// Message es el texto devuelto por el servicio, que suele ser más
+	// específico que la descripción del catálogo
+	Message string
+	// Channel es el servicio que respondió (sunat o el proveedor OSE)
+	Channel string
+}
+
+func newSUNATError(channel, code, message string) *SUNATError {
+	e := &SUNATError{Code: sunat.LookupCode(code), Message: message, Channel: channel}
+	if e.Message == "" {
+		e.Message = e.Description
+	}
+	return e
+}
+
+// sunatErrorFrom convierte un SOAP Fault en un SUNATError; los demás errores
+// se devuelven sin cambios
+func sunatErrorFrom(channel string, err error) error {
+	var fault *sunat.Fault
+	if errors.As(err, &fault) {
+		return newSUNATError(channel, fault.Code, fault.Message)
+	}
+	return err
+}
+
+func (e *SUNATError) Error() string {
+	switch {
+	case e.Retryable:
+		return fmt.Sprintf("%s is not available: %s - %s", e.Channel, e.Code.Code, e.Message)
+	case e.Category == sunat.CategoryException:
+		return fmt.Sprintf("%s returned exception %s - %s", e.Channel, e.Code.Code, e.Message)
+	default:
+		return fmt.Sprintf("rejected by %s: %s - %s", e.Channel, e.Code.Code, e.Message)
+	}
+}
+
+// Unwrap permite distinguir con errors.Is una falla de entrega, que se
+// reintenta, de un rechazo definitivo. Una excepción que no es de
+// disponibilidad no es ninguna de las dos: se corrige y se reenvía.
+func (e *SUNATError) Unwrap() error {
+	switch {
+	case e.Retryable:
+		return ErrDeliveryFailed
+	case e.Category == sunat.CategoryException:
+		return nil
+	default:
+		return ErrDocumentRejected
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// taxRule es la regla de cálculo de un tributo. Los tributos de la línea se
+// calculan por orden: el ISC forma parte de la base del IGV.
+type taxRule struct {
+	order   int
+	compute func(line *models.DocumentLine, tax *models.Tax, isc decimal.Decimal) error
+}
+
+var taxRules = map[models.TaxType]taxRule{
+	models.TaxTypeISC:  {order: 0, compute: computeISC},
+	models.TaxTypeIGV:  {order: 1, compute: computeIGV},
+	models.TaxTypeICBP: {order: 2, compute: computeICBPER},
+}
+
+// computeTaxes calcula la base y el importe de cada tributo de la línea,
+// redondeados a 2 decimales
+func computeTaxes(line *models.DocumentLine) error {
+	order := make([]int, len(line.Taxes))
+	for j, tax := range line.Taxes {
+		if _, ok := taxRules[tax.Type]; !ok {
+			return fmt.Errorf("unknown tax type %q (IGV, ISC or ICBP)", tax.Type)
+		}
+		order[j] = j
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return taxRules[line.Taxes[order[a]].Type].order < taxRules[line.Taxes[order[b]].Type].order
+	})
+
+	isc := decimal.Zero
+	for _, j := range order {
+		tax := &line.Taxes[j]
+		if err := taxRules[tax.Type].compute(line, tax, isc); err != nil {
+			return err
+		}
+		tax.TaxableAmount = tax.TaxableAmount.Round(decimal.AmountPlaces)
+		tax.Amount = tax.Amount.Round(decimal.AmountPlaces)
+		if tax.Type == models.TaxTypeISC {
+			isc = isc.Add(tax.Amount)
+		}
+	}
+
+	return nil
+}
+
+// computeIGV calcula el IGV sobre el valor de venta más el ISC. Las líneas
+// exoneradas, inafectas y de exportación no pagan IGV; las de arroz pilado
+// (17) pagan el IVAP en su lugar, con su propia tasa.
+func computeIGV(line *models.DocumentLine, tax *models.Tax, isc decimal.Decimal) error {
+	tax.TaxableAmount = line.TaxableAmount.Add(isc)
+	if !line.Affectation().Taxed() {
+		tax.Amount = decimal.Zero
+		return nil
+	}
+	if line.Affectation() == models.AffectationIVAP && !tax.Rate.Equal(models.IVAPRate) {
+		return fmt.Errorf("line %s: igv_affectation 17 is taxed with IVAP at %s%%", line.ID, models.IVAPRate)
+	}
+	tax.Amount = tax.TaxableAmount.Mul(tax.Rate).Div(decimal.Hundred)
+	return nil
+}
+
+// computeISC calcula el ISC según su sistema (catálogo 08): un porcentaje
+// del valor de venta, un monto fijo por unidad o un porcentaje del precio
+// de venta al público sin IGV
+func computeISC(line *models.DocumentLine, tax *models.Tax, _ decimal.Decimal) error {
+	if tax.ISCSystem == "" {
+		tax.ISCSystem = models.ISCSystemValue
+	}
+
+	switch tax.ISCSystem {
+	case models.ISCSystemValue:
+		tax.TaxableAmount = line.TaxableAmount
+		tax.Amount = tax.TaxableAmount.Mul(tax.Rate).Div(decimal.Hundred)
+	case models.ISCSystemFixedAmount:
+		tax.TaxableAmount = line.TaxableAmount
+		tax.Amount = line.Quantity.Mul(tax.Rate)
+	case models.ISCSystemRetailPrice:
+		if tax.ReferencePrice == nil || tax.ReferencePrice.Sign() <= 0 {
+			return fmt.Errorf("line %s: ISC system 03 requires reference_price", line.ID)
+		}
+		// El precio de venta al público incluye el IGV
+		igvFactor := decimal.Hundred.Add(lineIGVRate(line)).Div(decimal.Hundred)
+		tax.TaxableAmount = tax.ReferencePrice.Mul(line.Quantity).Div(igvFactor).Round(decimal.AmountPlaces)
+		tax.Amount = tax.TaxableAmount.Mul(tax.Rate).Div(decimal.Hundred)
+	default:
+		return fmt.Errorf("line %s: unknown isc_system %s (catalog 08)", line.ID, tax.ISCSystem)
+	}
+
+	return nil
+}
+
+// computeICBPER calcula el impuesto a las bolsas de plástico: un monto fijo
+// por bolsa
+func computeICBPER(line *models.DocumentLine, tax *models.Tax, _ decimal.Decimal) error {
+	if !line.Quantity.Exact(0) {
+		return fmt.Errorf("line %s: ICBP quantity must be a whole number of bags", line.ID)
+	}
+	tax.TaxableAmount = decimal.Zero
+	tax.Amount = line.Quantity.Mul(tax.Rate)
+	return nil
+}
+
+func lineIGVRate(line *models.DocumentLine) decimal.Decimal {
+	for _, tax := range line.Taxes {
+		if tax.Type == models.TaxTypeIGV {
+			return tax.Rate
+		}
+	}
+	return decimal.Zero
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+	"infac/pkg/ubl"
+)
+
+// ivapRequest arma una boleta de dos sacos de arroz pilado a 100.00 con
+// IVAP a la tasa indicada
+func ivapRequest(t *testing.T, rate string) *models.CreateDocumentRequest {
+	t.Helper()
+
+	req := testRequest(t, models.DocumentTypeBoleta)
+	req.Lines[0].IGVAffectation = models.AffectationIVAP
+	req.Lines[0].Taxes[0].Rate = decimal.MustParse(rate)
+	req.PaymentTerms.Amount = decimal.MustParse("208")
+	return req
+}
+
+// Las ventas con IVAP son onerosas: suman al importe a pagar y se informan
+// con el tributo 1016
+func TestIVAPLine(t *testing.T) {
+	s, _ := newTestService(t)
+
+	doc, err := s.buildDocument(ivapRequest(t, "4"))
+	if err != nil {
+		t.Fatalf("buildDocument: %v", err)
+	}
+	totals := map[string]decimal.Decimal{
+		"total_ivap":   doc.TotalIVAP,
+		"total_taxed":  doc.TotalTaxed,
+		"total_free":   doc.TotalFree,
+		"total_taxes":  doc.TotalTaxes,
+		"total_amount": doc.TotalAmount,
+	}
+	want := map[string]string{
+		"total_ivap": "200.00", "total_taxed": "0.00", "total_free": "0.00", "total_taxes": "8.00", "total_amount": "208.00",
+	}
+	for name, value := range totals {
+		if value.String() != want[name] {
+			t.Errorf("%s = %s, want %s", name, value, want[name])
+		}
+	}
+	if err := doc.CheckTotals(); err != nil {
+		t.Errorf("CheckTotals: %v", err)
+	}
+
+	invoice, err := ubl.GenerateInvoiceXML(doc, &doc.Issuer)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceXML: %v", err)
+	}
+	subtotals := invoice.TaxTotal[0].TaxSubtotal
+	if len(subtotals) != 1 {
+		t.Fatalf("document TaxSubtotal = %+v, want only IVAP", subtotals)
+	}
+	scheme := subtotals[0].TaxCategory.TaxScheme
+	if scheme.ID.Value != "1016" || scheme.Name != "IVAP" || subtotals[0].TaxAmount.Value.String() != "8.00" {
+		t.Errorf("document TaxSubtotal = %s %s %s, want 1016 IVAP 8.00", scheme.ID.Value, scheme.Name, subtotals[0].TaxAmount.Value)
+	}
+	if price := invoice.InvoiceLine[0].PricingReference.AlternativeConditionPrice[0].PriceTypeCode.Value; price != "01" {
+		t.Errorf("PriceTypeCode = %s, want 01 (onerous)", price)
+	}
+	for _, note := range invoice.Note {
+		if note.LanguageLocaleID == "1002" {
+			t.Errorf("IVAP document has the free transfer legend")
+		}
+	}
+}
+
+func TestIVAPRate(t *testing.T) {
+	s, _ := newTestService(t)
+
+	_, err := s.buildDocument(ivapRequest(t, "18"))
+	if err == nil || !strings.Contains(err.Error(), "IVAP at 4%") {
+		t.Fatalf("buildDocument = %v, want IVAP rate error", err)
+	}
+}
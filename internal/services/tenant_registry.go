@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"infac/internal/config"
+	"infac/internal/storage"
+)
+
+var (
+	ErrTenantNotFound = errors.New("issuer is not registered")
+	ErrTenantRequired = errors.New("issuer RUC is required when more than one issuer is registered")
+	ErrTenantExists   = errors.New("issuer is already registered")
+)
+
+// Tenant agrupa los servicios de un emisor. Sus documentos, correlativos,
+// tickets y archivos se guardan separados de los de otros emisores.
+type Tenant struct {
+	Issuer    config.IssuerConfig
+	Documents *DocumentService
+	Numbering *NumberingService
+	Queue     *SendQueue
+
+	Idempotency *IdempotencyService
+	// Configured indica que el emisor viene de la configuración y no puede
+	// reemplazarse por API
+	Configured bool
+
+	store       *storage.Store
+	stopPolling context.CancelFunc
+}
+
+func (t *Tenant) RUC() string {
+	return t.Issuer.RUC()
+}
+
+// TenantRegistry mantiene los emisores por RUC: el emisor de issuer (si está
+// configurado), los de issuers y los registrados por API en el
+// almacenamiento
+type TenantRegistry struct {
+	cfg     *config.Config
+	issuers storage.IssuerRepository
+
+	mu      sync.RWMutex
+	tenants map[string]*Tenant
+
+	// Contexto de los pollers; nil hasta StartPolling
+	pollCtx context.Context
+}
+
+// NewTenantRegistry crea los servicios de todos los emisores. El emisor de
+// issuer conserva las rutas de storage; los demás se guardan en
+// subdirectorios con su RUC.
+func NewTenantRegistry(cfg *config.Config, root *storage.Store) (*TenantRegistry, error) {
+	r := &TenantRegistry{
+		cfg:     cfg,
+		issuers: root.Issuers,
+		tenants: make(map[string]*Tenant),
+	}
+
+	if cfg.Issuer.DocumentNumber != "" {
+		issuer := config.IssuerConfig{
+			Company:     cfg.Issuer,
+			Username:    cfg.SUNAT.Username,
+			Password:    cfg.SUNAT.Password,
+			Certificate: cfg.Certificate,
+			Series:      cfg.Numbering.Series,
+		}
+		tenant, err := r.newTenant(issuer, cfg.SUNAT, root, "storage")
+		if err != nil {
+			return nil, err
+		}
+		tenant.Configured = true
+		r.tenants[tenant.RUC()] = tenant
+	}
+
+	for _, issuer := range cfg.Issuers {
+		if _, exists := r.tenants[issuer.RUC()]; exists {
+			return nil, fmt.Errorf("%w: %s is configured more than once", ErrTenantExists, issuer.RUC())
+		}
+		tenant, err := r.openTenant(issuer, nil)
+		if err != nil {
+			return nil, err
+		}
+		tenant.Configured = true
+		r.tenants[tenant.RUC()] = tenant
+	}
+
+	stored, err := r.issuers.ListIssuers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load issuers: %w", err)
+	}
+	for _, issuer := range stored {
+		if _, exists := r.tenants[issuer.RUC()]; exists {
+			fmt.Printf("Warning: Stored issuer %s is ignored, it is already configured\n", issuer.RUC())
+			continue
+		}
+		tenant, err := r.openTenant(*issuer, nil)
+		if err != nil {
+			return nil, err
+		}
+		r.tenants[tenant.RUC()] = tenant
+	}
+
+	if len(r.tenants) == 0 {
+		return nil, fmt.Errorf("at least one issuer must be configured (issuer or issuers)")
+	}
+
+	for _, tenant := range r.tenants {
+		if err := tenant.Queue.Start(context.Background()); err != nil {
+			return nil, fmt.Errorf("issuer %s: %w", tenant.RUC(), err)
+		}
+	}
+
+	return r, nil
+}
+
+// Register agrega o reemplaza un emisor registrado por API y lo guarda en el
+// almacenamiento. Los emisores de la configuración no se pueden reemplazar.
+func (r *TenantRegistry) Register(issuer config.IssuerConfig) (*Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Al reemplazar un emisor se conserva su almacenamiento
+	var store *storage.Store
+	existing, replacing := r.tenants[issuer.RUC()]
+	if replacing {
+		if existing.Configured {
+			return nil, fmt.Errorf("%w: %s is defined in the configuration", ErrTenantExists, issuer.RUC())
+		}
+		store = existing.store
+	}
+
+	tenant, err := r.openTenant(issuer, store)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.issuers.SaveIssuer(&issuer); err != nil {
+		return nil, err
+	}
+
+	// La cola anterior termina su envío en curso antes de que la nueva
+	// retome los jobs pendientes
+	if replacing {
+		if existing.stopPolling != nil {
+			existing.stopPolling()
+		}
+		existing.Queue.Stop()
+	}
+	if err := tenant.Queue.Start(context.Background()); err != nil {
+		return nil, err
+	}
+	r.tenants[tenant.RUC()] = tenant
+	if r.pollCtx != nil {
+		r.startPoller(tenant)
+	}
+
+	return tenant, nil
+}
+
+// Resolve devuelve el emisor indicado. Sin RUC se usa el único emisor
+// registrado.
+func (r *TenantRegistry) Resolve(ruc string) (*Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if ruc == "" {
+		if len(r.tenants) != 1 {
+			return nil, ErrTenantRequired
+		}
+		for _, tenant := range r.tenants {
+			return tenant, nil
+		}
+	}
+
+	tenant, ok := r.tenants[ruc]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotFound, ruc)
+	}
+	return tenant, nil
+}
+
+// List devuelve los emisores ordenados por RUC
+func (r *TenantRegistry) List() []*Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenants := make([]*Tenant, 0, len(r.tenants))
+	for _, tenant := range r.tenants {
+		tenants = append(tenants, tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].RUC() < tenants[j].RUC()
+	})
+
+	return tenants
+}
+
+// StartPolling lanza un TicketPoller por emisor, incluidos los que se
+// registren después
+func (r *TenantRegistry) StartPolling(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pollCtx = ctx
+	for _, tenant := range r.tenants {
+		r.startPoller(tenant)
+	}
+}
+
+func (r *TenantRegistry) startPoller(tenant *Tenant) {
+	ctx, cancel := context.WithCancel(r.pollCtx)
+	tenant.stopPolling = cancel
+
+	poller := NewTicketPoller(tenant.Documents, r.cfg.SUNAT.PollInterval, r.cfg.SUNAT.PollMaxInterval)
+	poller.Start(ctx)
+}
+
+// openTenant crea los servicios del emisor sobre su almacenamiento
+// particionado, que se abre si no se indica uno
+func (r *TenantRegistry) openTenant(issuer config.IssuerConfig, store *storage.Store) (*Tenant, error) {
+	if err := issuer.Validate(); err != nil {
+		return nil, err
+	}
+
+	if store == nil {
+		var err error
+		store, err = storage.Open(r.cfg.Storage.ForIssuer(issuer.RUC()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open storage of issuer %s: %w", issuer.RUC(), err)
+		}
+	}
+
+	return r.newTenant(issuer, issuer.SUNAT(r.cfg.SUNAT), store, filepath.Join("storage", issuer.RUC()))
+}
+
+func (r *TenantRegistry) newTenant(issuer config.IssuerConfig, sunatCfg config.SUNATConfig, store *storage.Store, filesDir string) (*Tenant, error) {
+	if err := ValidateSeries(issuer.Series); err != nil {
+		return nil, fmt.Errorf("issuer %s: %w", issuer.RUC(), err)
+	}
+
+	// Cada emisor extrae su certificado en su propio directorio: sunatlib
+	// usa siempre los mismos nombres de archivo
+	certCfg := issuer.Certificate
+	if certCfg.TempDir == "" {
+		certCfg.TempDir = filepath.Join(r.cfg.Certificate.TempDir, issuer.RUC())
+	}
+
+	for _, dir := range []string{"xml", "cdr"} {
+		if err := os.MkdirAll(filepath.Join(filesDir, dir), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+
+	company := issuer.Company
+	numbering := NewNumberingService(&company, store.Sequences, store.Documents, issuer.Series)
+	documents, err := NewDocumentService(&company, sunatCfg, certCfg, store.Documents, store.Tickets, numbering)
+	if err != nil {
+		return nil, fmt.Errorf("issuer %s: %w", issuer.RUC(), err)
+	}
+	documents.establishments = issuer.Establishments
+	documents.filesDir = filesDir
+
+	return &Tenant{
+		Issuer:    issuer,
+		Documents: documents,
+		Numbering: numbering,
+		Queue:     NewSendQueue(documents, store.Jobs, r.cfg.Queue),
+		store:     store,
+
+		Idempotency: NewIdempotencyService(store.Idempotency, r.cfg.Idempotency.TTL),
+	}, nil
+}
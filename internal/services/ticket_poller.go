@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"infac/internal/models"
+)
+
+// TicketPoller consulta periódicamente los tickets pendientes hasta que SUNAT
+// los procese. Cada ticket duplica su espera tras una consulta sin resultado,
+// hasta maxInterval.
+type TicketPoller struct {
+	service     *DocumentService
+	interval    time.Duration
+	maxInterval time.Duration
+	schedule    map[string]pollSchedule
+}
+
+type pollSchedule struct {
+	next time.Time
+	wait time.Duration
+}
+
+func NewTicketPoller(service *DocumentService, interval, maxInterval time.Duration) *TicketPoller {
+	if maxInterval < interval {
+		maxInterval = interval
+	}
+
+	return &TicketPoller{
+		service:     service,
+		interval:    interval,
+		maxInterval: maxInterval,
+		schedule:    make(map[string]pollSchedule),
+	}
+}
+
+// Start lanza el poller en segundo plano hasta que se cancele ctx
+func (p *TicketPoller) Start(ctx context.Context) {
+	go p.run(ctx)
+}
+
+func (p *TicketPoller) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	// Primera pasada inmediata para retomar los tickets de una ejecución anterior
+	p.poll(time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.poll(now)
+		}
+	}
+}
+
+func (p *TicketPoller) poll(now time.Time) {
+	tickets, err := p.service.ListTickets(models.TicketStatusPending)
+	if err != nil {
+		fmt.Printf("Warning: Failed to list pending tickets: %v\n", err)
+		return
+	}
+
+	pending := make(map[string]bool, len(tickets))
+	for _, ticket := range tickets {
+		pending[ticket.Number] = true
+
+		schedule := p.schedule[ticket.Number]
+		if now.Before(schedule.next) {
+			continue
+		}
+
+		result, err := p.service.CheckStatus(ticket.Number)
+		if err != nil {
+			fmt.Printf("Warning: Failed to check ticket %s: %v\n", ticket.Number, err)
+		} else if result.Status != models.TicketStatusPending {
+			delete(pending, ticket.Number)
+			continue
+		}
+
+		schedule.wait *= 2
+		if schedule.wait == 0 {
+			schedule.wait = p.interval
+		}
+		if schedule.wait > p.maxInterval {
+			schedule.wait = p.maxInterval
+		}
+		schedule.next = now.Add(schedule.wait)
+		p.schedule[ticket.Number] = schedule
+	}
+
+	// Se descartan los tickets que ya se resolvieron
+	for number := range p.schedule {
+		if !pending[number] {
+			delete(p.schedule, number)
+		}
+	}
+}
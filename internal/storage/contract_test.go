@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// runRepositoryContract verifica el comportamiento que el servicio espera de
+// cualquier DocumentRepository. newRepo devuelve un repositorio vacío.
+func runRepositoryContract(t *testing.T, newRepo func(t *testing.T) DocumentRepository) {
+	t.Run("SaveAndFind", func(t *testing.T) {
+		repo := newRepo(t)
+		doc := contractDocument("F001-00000001", "F001", "00000001")
+		if err := repo.Save(doc); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+
+		found, err := repo.FindByID(doc.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertSameDocument(t, doc, found)
+	})
+
+	t.Run("SaveDuplicate", func(t *testing.T) {
+		repo := newRepo(t)
+		doc := contractDocument("F001-00000001", "F001", "00000001")
+		if err := repo.Save(doc); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if err := repo.Save(doc); !errors.Is(err, ErrAlreadyExists) {
+			t.Fatalf("second Save = %v, want ErrAlreadyExists", err)
+		}
+	})
+
+	t.Run("FindMissing", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.FindByID("F001-99999999"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("FindByID = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		doc := contractDocument("F001-00000001", "F001", "00000001")
+		if err := repo.Save(doc); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+
+		doc.Status = models.StatusAccepted
+		doc.StatusHistory = append(doc.StatusHistory, models.StatusChange{
+			From: models.StatusSent, To: models.StatusAccepted, Reason: "Aceptado", At: contractTime(2),
+		})
+		doc.Lines = doc.Lines[:1]
+		doc.RelatedDocuments = nil
+		doc.CDR.Observations = nil
+		if err := repo.Update(doc); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		found, err := repo.FindByID(doc.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		assertSameDocument(t, doc, found)
+	})
+
+	t.Run("UpdateMissing", func(t *testing.T) {
+		repo := newRepo(t)
+		doc := contractDocument("F001-00000001", "F001", "00000001")
+		if err := repo.Update(doc); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Update = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepo(t)
+		doc := contractDocument("F001-00000001", "F001", "00000001")
+		if err := repo.Save(doc); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if err := repo.Delete(doc.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.FindByID(doc.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("FindByID after Delete = %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(doc.ID); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("second Delete = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		repo := newRepo(t)
+		for i := 1; i <= 5; i++ {
+			serie := "F001"
+			docType := models.DocumentTypeFactura
+			if i > 3 {
+				serie = "B001"
+				docType = models.DocumentTypeBoleta
+			}
+			number := fmt.Sprintf("%08d", i)
+			doc := contractDocument(serie+"-"+number, serie, number)
+			doc.Type = docType
+			doc.IssueDate = time.Date(2026, 10, i, 0, 0, 0, 0, time.UTC)
+			doc.CreatedAt = contractTime(i)
+			if i%2 == 0 {
+				doc.Status = models.StatusDraft
+				doc.Customer.DocumentNumber = "20100070970"
+			}
+			if err := repo.Save(doc); err != nil {
+				t.Fatalf("Save %s: %v", doc.ID, err)
+			}
+		}
+
+		from := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2026, 10, 4, 0, 0, 0, 0, time.UTC)
+		tests := []struct {
+			name   string
+			filter DocumentFilter
+			want   []string
+		}{
+			{"all", DocumentFilter{}, []string{"F001-00000001", "F001-00000002", "F001-00000003", "B001-00000004", "B001-00000005"}},
+			{"status", DocumentFilter{Status: models.StatusDraft}, []string{"F001-00000002", "B001-00000004"}},
+			{"type", DocumentFilter{Type: models.DocumentTypeBoleta}, []string{"B001-00000004", "B001-00000005"}},
+			{"serie", DocumentFilter{Serie: "F001"}, []string{"F001-00000001", "F001-00000002", "F001-00000003"}},
+			{"customer", DocumentFilter{CustomerRUC: "20100070970"}, []string{"F001-00000002", "B001-00000004"}},
+			{"dates", DocumentFilter{From: &from, To: &to}, []string{"F001-00000002", "F001-00000003", "B001-00000004"}},
+			{"limit", DocumentFilter{Limit: 2}, []string{"F001-00000001", "F001-00000002"}},
+			{"after", DocumentFilter{After: &Cursor{CreatedAt: contractTime(2), ID: "F001-00000002"}, Limit: 2}, []string{"F001-00000003", "B001-00000004"}},
+			{"combined", DocumentFilter{Type: models.DocumentTypeFactura, Status: models.StatusAccepted}, []string{"F001-00000001", "F001-00000003"}},
+		}
+		for _, tt := range tests {
+			docs, err := repo.List(tt.filter)
+			if err != nil {
+				t.Fatalf("%s: List: %v", tt.name, err)
+			}
+			var got []string
+			for _, doc := range docs {
+				got = append(got, doc.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("%s: List = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("ListReturnsChildren", func(t *testing.T) {
+		repo := newRepo(t)
+		doc := contractDocument("F001-00000001", "F001", "00000001")
+		if err := repo.Save(doc); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		docs, err := repo.List(DocumentFilter{})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(docs) != 1 {
+			t.Fatalf("List returned %d documents, want 1", len(docs))
+		}
+		assertSameDocument(t, doc, docs[0])
+	})
+}
+
+func contractTime(minute int) time.Time {
+	return time.Date(2026, 10, 16, 10, minute, 0, 0, time.UTC)
+}
+
+// contractDocument arma un documento aceptado con todas las partes que el
+// repositorio debe conservar
+func contractDocument(id, serie, number string) *models.Document {
+	d := decimal.MustParse
+	due := time.Date(2026, 11, 15, 0, 0, 0, 0, time.UTC)
+	responseDate := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	reference := d("3.50")
+	factor := d("0.10")
+
+	return &models.Document{
+		ID:            id,
+		Serie:         serie,
+		Number:        number,
+		Type:          models.DocumentTypeFactura,
+		IssueDate:     time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		DueDate:       &due,
+		CurrencyCode:  "PEN",
+		OperationType: models.OperationType("1001"),
+		Issuer: models.Company{
+			DocumentType: "6", DocumentNumber: "20612790168", Name: "EMISOR SAC",
+			Address: "AV. EJEMPLO 123", District: "LIMA", Province: "LIMA", Department: "LIMA", Country: "PE",
+		},
+		Customer: models.Company{DocumentType: "6", DocumentNumber: "20100070971", Name: "CLIENTE SAC"},
+		Lines: []models.DocumentLine{
+			{
+				ID: "1", Quantity: d("2"), UnitCode: "NIU", Description: "Producto", UnitPrice: d("500.50"),
+				TotalPrice: d("901.00"), TaxableAmount: d("901.00"), IGVAffectation: models.AffectationTaxed,
+				AllowanceCharges: []models.AllowanceCharge{
+					{Code: models.DiscountLineAffectsBase, Factor: &factor, Amount: d("100.10"), BaseAmount: d("1001.00")},
+				},
+				Taxes: []models.Tax{
+					{Type: models.TaxTypeIGV, Code: "1000", Rate: d("18"), Amount: d("162.18"), TaxableAmount: d("901.00")},
+				},
+				ProductCode: "P001",
+			},
+			{
+				ID: "2", Quantity: d("1.5"), UnitCode: "NIU", Description: "Licor", UnitPrice: d("10"),
+				TotalPrice: d("15.00"), TaxableAmount: d("15.00"), IGVAffectation: models.AffectationExonerated,
+				Taxes: []models.Tax{
+					{Type: models.TaxTypeISC, Code: "2000", Rate: d("1.00"), Amount: d("1.50"), TaxableAmount: d("15.00"),
+						ISCSystem: models.ISCSystemRetailPrice, ReferencePrice: &reference},
+				},
+			},
+		},
+		SubTotal:           d("916.00"),
+		TotalTaxes:         d("163.68"),
+		TotalAmount:        d("1079.68"),
+		AllowanceCharges:   []models.AllowanceCharge{{Code: models.DiscountGlobal, Amount: d("5.00"), BaseAmount: d("916.00")}},
+		TaxExclusiveAmount: d("916.00"),
+		TaxInclusiveAmount: d("1079.68"),
+		TotalAllowances:    d("5.00"),
+		TotalTaxed:         d("901.00"),
+		TotalIVAP:          d("25.00"),
+		TotalExonerated:    d("15.00"),
+		PaymentTerms: &models.PaymentTerms{
+			PaymentMeansCode: models.PaymentMeansCredit, DueDate: due, Amount: d("950.00"),
+			Installments: []models.Installment{{Amount: d("950.00"), DueDate: due}},
+		},
+		Detraction: &models.Detraction{
+			Code: "037", Percent: d("12"), Amount: d("129.56"), Account: "00-000-000000", PaymentMeansCode: "001",
+		},
+		ExchangeRate:     &models.ExchangeRate{TargetCurrencyCode: "USD", Rate: d("0.267")},
+		Prepayments:      []models.Prepayment{{DocumentType: models.DocumentTypeFactura, Serie: "F001", Number: "00000000", Amount: d("118.00"), TaxableAmount: d("100.00")}},
+		TotalPrepaid:     d("118.00"),
+		RelatedDocuments: []models.RelatedDocument{{DocumentType: models.DocumentTypeFactura, Serie: "F001", Number: "00000000"}},
+		Status:           models.StatusAccepted,
+		StatusHistory: []models.StatusChange{
+			{To: models.StatusDraft, Reason: "Documento creado", At: contractTime(0)},
+			{From: models.StatusDraft, To: models.StatusPending, Reason: "Envío por SUNAT", At: contractTime(1)},
+		},
+		SUNATStatus: "0",
+		CDR: &models.CDR{
+			ResponseCode: "0", Description: "La Factura ha sido aceptada", Notes: "nota",
+			DocumentID: id, ResponseDate: &responseDate,
+			Observations: []models.CDRObservation{{Code: "4252", Description: "Observación"}},
+		},
+		SummaryTicket: "1760600000000",
+		Modified:      true,
+		Channel:       "sunat",
+		CreatedAt:     contractTime(0),
+		UpdatedAt:     contractTime(1),
+	}
+}
+
+// assertSameDocument compara los documentos por su JSON, que es lo que la
+// API devuelve
+func assertSameDocument(t *testing.T, want, got *models.Document) {
+	t.Helper()
+	wantJSON, err := json.MarshalIndent(want, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(wantJSON) != string(gotJSON) {
+		t.Errorf("stored document differs\nwant: %s\ngot:  %s", wantJSON, gotJSON)
+	}
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"infac/internal/models"
+)
+
+var ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+
+// IdempotencyRepository guarda las respuestas de las requests con
+// Idempotency-Key para repetirlas ante un reintento del cliente
+type IdempotencyRepository interface {
+	// SaveIdempotencyRecord crea o reemplaza el registro de la clave
+	SaveIdempotencyRecord(record *models.IdempotencyRecord) error
+	FindIdempotencyRecord(key string) (*models.IdempotencyRecord, error)
+	// DeleteIdempotencyRecords elimina los registros creados antes de la
+	// fecha indicada y devuelve cuántos se eliminaron
+	DeleteIdempotencyRecords(before time.Time) (int, error)
+}
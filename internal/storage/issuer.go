@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"errors"
+
+	"infac/internal/config"
+)
+
+var ErrIssuerNotFound = errors.New("issuer not found")
+
+// IssuerRepository guarda los emisores registrados por API. Es común a todos
+// los emisores, a diferencia de documentos, correlativos y tickets.
+type IssuerRepository interface {
+	// SaveIssuer crea o reemplaza el emisor con el mismo RUC
+	SaveIssuer(issuer *config.IssuerConfig) error
+	FindIssuer(ruc string) (*config.IssuerConfig, error)
+	ListIssuers() ([]*config.IssuerConfig, error)
+}
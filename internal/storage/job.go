@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"errors"
+
+	"infac/internal/models"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// JobRepository guarda los envíos de la cola para retomarlos tras un reinicio
+type JobRepository interface {
+	// SaveJob crea o reemplaza el job
+	SaveJob(job *models.SendJob) error
+	FindJob(id string) (*models.SendJob, error)
+	// ListJobs devuelve los jobs en el estado indicado ("" = todos)
+	ListJobs(status models.JobStatus) ([]*models.SendJob, error)
+}
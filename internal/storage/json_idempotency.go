@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"infac/internal/models"
+)
+
+// JSONIdempotencyRepository guarda cada registro como un archivo JSON. El
+// nombre del archivo es el hash de la clave, que la elige el cliente.
+type JSONIdempotencyRepository struct {
+	basePath string
+	mu       sync.RWMutex
+}
+
+func NewJSONIdempotencyRepository(basePath string) (*JSONIdempotencyRepository, error) {
+	if err := os.MkdirAll(basePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &JSONIdempotencyRepository{basePath: basePath}, nil
+}
+
+func (r *JSONIdempotencyRepository) SaveIdempotencyRecord(record *models.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return writeJSONFile(r.pathFor(record.Key), record)
+}
+
+func (r *JSONIdempotencyRepository) FindIdempotencyRecord(key string) (*models.IdempotencyRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.read(r.pathFor(key))
+}
+
+func (r *JSONIdempotencyRepository) DeleteIdempotencyRecords(before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries, err := os.ReadDir(r.basePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	deleted := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(r.basePath, entry.Name())
+		record, err := r.read(path)
+		if err != nil {
+			return deleted, err
+		}
+		if !record.CreatedAt.Before(before) {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			return deleted, fmt.Errorf("failed to delete idempotency record: %w", err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+func (r *JSONIdempotencyRepository) pathFor(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(r.basePath, hex.EncodeToString(sum[:])+".json")
+}
+
+func (r *JSONIdempotencyRepository) read(path string) (*models.IdempotencyRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to read idempotency record: %w", err)
+	}
+
+	var record models.IdempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency record %s: %w", filepath.Base(path), err)
+	}
+
+	return &record, nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"infac/internal/config"
+)
+
+// JSONIssuerRepository guarda todos los emisores en un único archivo JSON
+// indexado por RUC
+type JSONIssuerRepository struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewJSONIssuerRepository(path string) (*JSONIssuerRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &JSONIssuerRepository{path: path}, nil
+}
+
+func (r *JSONIssuerRepository) SaveIssuer(issuer *config.IssuerConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	issuers, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	issuers[issuer.RUC()] = issuer
+	return writeJSONFile(r.path, issuers)
+}
+
+func (r *JSONIssuerRepository) FindIssuer(ruc string) (*config.IssuerConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	issuers, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, ok := issuers[ruc]
+	if !ok {
+		return nil, ErrIssuerNotFound
+	}
+	return issuer, nil
+}
+
+func (r *JSONIssuerRepository) ListIssuers() ([]*config.IssuerConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	issuers, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*config.IssuerConfig, 0, len(issuers))
+	for _, issuer := range issuers {
+		result = append(result, issuer)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].RUC() < result[j].RUC()
+	})
+
+	return result, nil
+}
+
+func (r *JSONIssuerRepository) load() (map[string]*config.IssuerConfig, error) {
+	issuers := make(map[string]*config.IssuerConfig)
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return issuers, nil
+		}
+		return nil, fmt.Errorf("failed to read issuers: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &issuers); err != nil {
+		return nil, fmt.Errorf("failed to decode issuers: %w", err)
+	}
+
+	return issuers, nil
+}
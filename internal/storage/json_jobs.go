@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"infac/internal/models"
+)
+
+// JSONJobRepository guarda cada job de envío como un archivo JSON
+type JSONJobRepository struct {
+	basePath string
+	mu       sync.RWMutex
+}
+
+func NewJSONJobRepository(basePath string) (*JSONJobRepository, error) {
+	if err := os.MkdirAll(basePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &JSONJobRepository{basePath: basePath}, nil
+}
+
+func (r *JSONJobRepository) SaveJob(job *models.SendJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, err := r.pathFor(job.ID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSONFile(path, job)
+}
+
+func (r *JSONJobRepository) FindJob(id string) (*models.SendJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	path, err := r.pathFor(id)
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+
+	return r.read(path)
+}
+
+func (r *JSONJobRepository) ListJobs(status models.JobStatus) ([]*models.SendJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries, err := os.ReadDir(r.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	var jobs []*models.SendJob
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		job, err := r.read(filepath.Join(r.basePath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		if status == "" || job.Status == status {
+			jobs = append(jobs, job)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+func (r *JSONJobRepository) pathFor(id string) (string, error) {
+	if !validIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid job ID: %q", id)
+	}
+	return filepath.Join(r.basePath, id+".json"), nil
+}
+
+func (r *JSONJobRepository) read(path string) (*models.SendJob, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to read job: %w", err)
+	}
+
+	var job models.SendJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job %s: %w", filepath.Base(path), err)
+	}
+
+	return &job, nil
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"infac/internal/models"
+)
+
+var validIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// JSONRepository guarda cada documento como un archivo JSON dentro de basePath
+type JSONRepository struct {
+	basePath string
+	mu       sync.RWMutex
+}
+
+func NewJSONRepository(basePath string) (*JSONRepository, error) {
+	if err := os.MkdirAll(basePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &JSONRepository{basePath: basePath}, nil
+}
+
+func (r *JSONRepository) Save(doc *models.Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, err := r.pathFor(doc.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return ErrAlreadyExists
+	}
+
+	return r.write(path, doc)
+}
+
+func (r *JSONRepository) FindByID(id string) (*models.Document, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	path, err := r.pathFor(id)
+	if err != nil {
+		// Un ID inválido nunca pudo haberse guardado
+		return nil, ErrNotFound
+	}
+
+	return r.read(path)
+}
+
+func (r *JSONRepository) Update(doc *models.Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, err := r.pathFor(doc.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	return r.write(path, doc)
+}
+
+func (r *JSONRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, err := r.pathFor(id)
+	if err != nil {
+		// Un ID inválido nunca pudo haberse guardado
+		return ErrNotFound
+	}
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	return nil
+}
+
+func (r *JSONRepository) List(filter DocumentFilter) ([]*models.Document, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries, err := os.ReadDir(r.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	var docs []*models.Document
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		doc, err := r.read(filepath.Join(r.basePath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		if !filter.Matches(doc) {
+			continue
+		}
+		if filter.After != nil && !filter.After.Precedes(doc) {
+			continue
+		}
+
+		docs = append(docs, doc)
+	}
+
+	// Orden estable por fecha de creación para que el listado sea predecible
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].CreatedAt.Equal(docs[j].CreatedAt) {
+			return docs[i].ID < docs[j].ID
+		}
+		return docs[i].CreatedAt.Before(docs[j].CreatedAt)
+	})
+
+	if filter.Limit > 0 && len(docs) > filter.Limit {
+		docs = docs[:filter.Limit]
+	}
+
+	return docs, nil
+}
+
+func (r *JSONRepository) pathFor(id string) (string, error) {
+	if !validIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid document id: %q", id)
+	}
+	return filepath.Join(r.basePath, id+".json"), nil
+}
+
+func (r *JSONRepository) read(path string) (*models.Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+
+	var doc models.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document %s: %w", filepath.Base(path), err)
+	}
+
+	return &doc, nil
+}
+
+func (r *JSONRepository) write(path string, doc *models.Document) error {
+	return writeJSONFile(path, doc)
+}
+
+// writeJSONFile escribe primero en un archivo temporal y luego lo renombra,
+// así un corte a mitad de escritura nunca deja un JSON truncado
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package storage
+
+import "testing"
+
+func TestJSONRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) DocumentRepository {
+		repo, err := NewJSONRepository(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewJSONRepository: %v", err)
+		}
+		return repo
+	})
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONSequenceRepository guarda todos los correlativos en un único archivo JSON
+type JSONSequenceRepository struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewJSONSequenceRepository(path string) (*JSONSequenceRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &JSONSequenceRepository{path: path}, nil
+}
+
+func (r *JSONSequenceRepository) Next(key SequenceKey) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sequences, err := r.load()
+	if err != nil {
+		return 0, err
+	}
+
+	sequences[key.String()]++
+	if err := r.store(sequences); err != nil {
+		return 0, err
+	}
+
+	return sequences[key.String()], nil
+}
+
+func (r *JSONSequenceRepository) Current(key SequenceKey) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sequences, err := r.load()
+	if err != nil {
+		return 0, err
+	}
+
+	return sequences[key.String()], nil
+}
+
+func (r *JSONSequenceRepository) Reserve(key SequenceKey, n int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sequences, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	if sequences[key.String()] >= n {
+		return nil
+	}
+
+	sequences[key.String()] = n
+	return r.store(sequences)
+}
+
+func (r *JSONSequenceRepository) load() (map[string]int64, error) {
+	sequences := make(map[string]int64)
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return sequences, nil
+		}
+		return nil, fmt.Errorf("failed to read sequences: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &sequences); err != nil {
+		return nil, fmt.Errorf("failed to decode sequences: %w", err)
+	}
+
+	return sequences, nil
+}
+
+func (r *JSONSequenceRepository) store(sequences map[string]int64) error {
+	return writeJSONFile(r.path, sequences)
+}
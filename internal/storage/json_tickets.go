@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"infac/internal/models"
+)
+
+// JSONTicketRepository guarda cada ticket como un archivo JSON
+type JSONTicketRepository struct {
+	basePath string
+	mu       sync.RWMutex
+}
+
+func NewJSONTicketRepository(basePath string) (*JSONTicketRepository, error) {
+	if err := os.MkdirAll(basePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &JSONTicketRepository{basePath: basePath}, nil
+}
+
+func (r *JSONTicketRepository) SaveTicket(ticket *models.Ticket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, err := r.pathFor(ticket.Number)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("ticket %s already exists", ticket.Number)
+	}
+
+	return r.write(path, ticket)
+}
+
+func (r *JSONTicketRepository) FindTicket(number string) (*models.Ticket, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	path, err := r.pathFor(number)
+	if err != nil {
+		return nil, ErrTicketNotFound
+	}
+
+	return r.read(path)
+}
+
+func (r *JSONTicketRepository) UpdateTicket(ticket *models.Ticket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, err := r.pathFor(ticket.Number)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return ErrTicketNotFound
+	}
+
+	return r.write(path, ticket)
+}
+
+func (r *JSONTicketRepository) ListTickets(status models.TicketStatus) ([]*models.Ticket, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries, err := os.ReadDir(r.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	var tickets []*models.Ticket
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		ticket, err := r.read(filepath.Join(r.basePath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		if status == "" || ticket.Status == status {
+			tickets = append(tickets, ticket)
+		}
+	}
+
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].CreatedAt.Before(tickets[j].CreatedAt)
+	})
+
+	return tickets, nil
+}
+
+func (r *JSONTicketRepository) pathFor(number string) (string, error) {
+	if !validIDPattern.MatchString(number) {
+		return "", fmt.Errorf("invalid ticket number: %q", number)
+	}
+	return filepath.Join(r.basePath, number+".json"), nil
+}
+
+func (r *JSONTicketRepository) read(path string) (*models.Ticket, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to read ticket: %w", err)
+	}
+
+	var ticket models.Ticket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, fmt.Errorf("failed to decode ticket %s: %w", filepath.Base(path), err)
+	}
+
+	return &ticket, nil
+}
+
+func (r *JSONTicketRepository) write(path string, ticket *models.Ticket) error {
+	return writeJSONFile(path, ticket)
+}
@@ -0,0 +1,71 @@
+-- Esquema inicial de comprobantes.
+-- Los montos se guardan como TEXT para no perder precisión en la conversión.
+CREATE TABLE documents (
+    id                       TEXT PRIMARY KEY,
+    serie                    TEXT NOT NULL,
+    number                   TEXT NOT NULL,
+    type                     TEXT NOT NULL,
+    issue_date               TEXT NOT NULL,
+    due_date                 TEXT,
+    currency_code            TEXT NOT NULL,
+    issuer                   TEXT NOT NULL,
+    customer                 TEXT NOT NULL,
+    customer_document_number TEXT NOT NULL DEFAULT '',
+    sub_total                TEXT NOT NULL,
+    total_taxes              TEXT NOT NULL,
+    total_amount             TEXT NOT NULL,
+    payment_means_code       TEXT,
+    payment_due_date         TEXT,
+    payment_amount           TEXT,
+    status                   TEXT NOT NULL,
+    sunat_status             TEXT NOT NULL DEFAULT '',
+    created_at               TEXT NOT NULL,
+    updated_at               TEXT NOT NULL
+);
+
+CREATE INDEX idx_documents_status ON documents (status);
+CREATE INDEX idx_documents_serie ON documents (serie);
+CREATE INDEX idx_documents_issue_date ON documents (issue_date);
+CREATE INDEX idx_documents_created_at ON documents (created_at, id);
+
+CREATE TABLE document_lines (
+    document_id    TEXT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+    line_no        INTEGER NOT NULL,
+    id             TEXT NOT NULL,
+    quantity       TEXT NOT NULL,
+    unit_code      TEXT NOT NULL,
+    description    TEXT NOT NULL,
+    unit_price     TEXT NOT NULL,
+    total_price    TEXT NOT NULL,
+    taxable_amount TEXT NOT NULL,
+    product_code   TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (document_id, line_no)
+);
+
+CREATE TABLE line_taxes (
+    document_id TEXT NOT NULL,
+    line_no     INTEGER NOT NULL,
+    position    INTEGER NOT NULL,
+    type        TEXT NOT NULL,
+    code        TEXT NOT NULL,
+    rate        TEXT NOT NULL,
+    amount      TEXT NOT NULL,
+    PRIMARY KEY (document_id, line_no, position),
+    FOREIGN KEY (document_id, line_no) REFERENCES document_lines (document_id, line_no) ON DELETE CASCADE
+);
+
+CREATE TABLE related_documents (
+    document_id   TEXT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+    position      INTEGER NOT NULL,
+    document_type TEXT NOT NULL,
+    serie         TEXT NOT NULL,
+    number        TEXT NOT NULL,
+    PRIMARY KEY (document_id, position)
+);
+
+CREATE TABLE cdrs (
+    document_id   TEXT PRIMARY KEY REFERENCES documents (id) ON DELETE CASCADE,
+    response_code TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    notes         TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,10 @@
+-- Historial de transiciones de estado de cada documento
+CREATE TABLE status_history (
+    document_id TEXT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status   TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    changed_at  TEXT NOT NULL,
+    PRIMARY KEY (document_id, position)
+);
@@ -0,0 +1,8 @@
+-- Correlativos por RUC, tipo de documento y serie
+CREATE TABLE sequences (
+    ruc           TEXT NOT NULL,
+    document_type TEXT NOT NULL,
+    serie         TEXT NOT NULL,
+    last_number   INTEGER NOT NULL,
+    PRIMARY KEY (ruc, document_type, serie)
+);
@@ -0,0 +1,26 @@
+-- Tickets de resúmenes diarios y comunicaciones de baja
+CREATE TABLE tickets (
+    number            TEXT PRIMARY KEY,
+    summary_id        TEXT NOT NULL,
+    type              TEXT NOT NULL,
+    issue_date        TEXT NOT NULL,
+    reference_date    TEXT NOT NULL,
+    status            TEXT NOT NULL,
+    cdr_response_code TEXT,
+    cdr_description   TEXT,
+    cdr_notes         TEXT,
+    created_at        TEXT NOT NULL,
+    updated_at        TEXT NOT NULL
+);
+
+CREATE INDEX idx_tickets_status ON tickets (status);
+
+CREATE TABLE ticket_documents (
+    ticket_number TEXT NOT NULL REFERENCES tickets (number) ON DELETE CASCADE,
+    position      INTEGER NOT NULL,
+    document_id   TEXT NOT NULL,
+    PRIMARY KEY (ticket_number, position)
+);
+
+ALTER TABLE documents ADD COLUMN void_ticket TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN void_reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Resumen Diario: estado informado por documento y último resumen del documento
+ALTER TABLE ticket_documents ADD COLUMN condition_code TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE documents ADD COLUMN summary_ticket TEXT NOT NULL DEFAULT '';
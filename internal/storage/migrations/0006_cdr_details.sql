@@ -0,0 +1,22 @@
+-- Detalle del CDR: documento referido, fecha de respuesta y observaciones
+ALTER TABLE cdrs ADD COLUMN document_ref TEXT NOT NULL DEFAULT '';
+ALTER TABLE cdrs ADD COLUMN response_date TEXT;
+
+CREATE TABLE cdr_observations (
+    document_id TEXT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    code        TEXT NOT NULL,
+    description TEXT NOT NULL,
+    PRIMARY KEY (document_id, position)
+);
+
+ALTER TABLE tickets ADD COLUMN cdr_document_ref TEXT;
+ALTER TABLE tickets ADD COLUMN cdr_response_date TEXT;
+
+CREATE TABLE ticket_cdr_observations (
+    ticket_number TEXT NOT NULL REFERENCES tickets (number) ON DELETE CASCADE,
+    position      INTEGER NOT NULL,
+    code          TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    PRIMARY KEY (ticket_number, position)
+);
@@ -0,0 +1,3 @@
+-- Canal (SUNAT u OSE) por el que se envió cada documento y resumen
+ALTER TABLE documents ADD COLUMN channel TEXT NOT NULL DEFAULT '';
+ALTER TABLE tickets ADD COLUMN channel TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- Emisores registrados por API
+CREATE TABLE issuers (
+    ruc        TEXT PRIMARY KEY,
+    data       TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
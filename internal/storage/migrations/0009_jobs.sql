@@ -0,0 +1,16 @@
+-- Cola de envío de documentos
+CREATE TABLE jobs (
+    id              TEXT PRIMARY KEY,
+    document_id     TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    attempts        INTEGER NOT NULL,
+    max_attempts    INTEGER NOT NULL,
+    next_attempt_at TEXT,
+    last_error      TEXT NOT NULL DEFAULT '',
+    created_at      TEXT NOT NULL,
+    updated_at      TEXT NOT NULL,
+    completed_at    TEXT
+);
+
+CREATE INDEX idx_jobs_status ON jobs (status);
+CREATE INDEX idx_jobs_document ON jobs (document_id);
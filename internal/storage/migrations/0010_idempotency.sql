@@ -0,0 +1,10 @@
+-- Respuestas de las requests con Idempotency-Key
+CREATE TABLE idempotency_keys (
+    key          TEXT PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    status_code  INTEGER NOT NULL,
+    response     BLOB NOT NULL,
+    created_at   TEXT NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_created ON idempotency_keys (created_at);
@@ -0,0 +1,11 @@
+-- Afectación al IGV de cada línea (catálogo 07) y valor de venta del
+-- documento por tipo de afectación. Los documentos anteriores son gravados.
+ALTER TABLE document_lines ADD COLUMN igv_affectation TEXT NOT NULL DEFAULT '10';
+
+ALTER TABLE documents ADD COLUMN total_taxed TEXT NOT NULL DEFAULT '0';
+ALTER TABLE documents ADD COLUMN total_exonerated TEXT NOT NULL DEFAULT '0';
+ALTER TABLE documents ADD COLUMN total_unaffected TEXT NOT NULL DEFAULT '0';
+ALTER TABLE documents ADD COLUMN total_export TEXT NOT NULL DEFAULT '0';
+ALTER TABLE documents ADD COLUMN total_free TEXT NOT NULL DEFAULT '0';
+
+UPDATE documents SET total_taxed = sub_total;
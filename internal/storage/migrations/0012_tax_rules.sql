@@ -0,0 +1,11 @@
+-- Base imponible de cada tributo (el IGV incluye el ISC en su base) y
+-- sistema de cálculo del ISC (catálogo 08). Los tributos anteriores se
+-- calcularon sobre el valor de venta de la línea.
+ALTER TABLE line_taxes ADD COLUMN taxable_amount TEXT NOT NULL DEFAULT '0';
+ALTER TABLE line_taxes ADD COLUMN isc_system TEXT NOT NULL DEFAULT '';
+ALTER TABLE line_taxes ADD COLUMN reference_price TEXT;
+
+UPDATE line_taxes SET taxable_amount = (
+    SELECT l.taxable_amount FROM document_lines l
+    WHERE l.document_id = line_taxes.document_id AND l.line_no = line_taxes.line_no
+);
@@ -0,0 +1,21 @@
+-- Descuentos y cargos (catálogo 53) de las líneas y del documento. Los
+-- globales se guardan con line_no -1. Los documentos anteriores no tienen
+-- descuentos ni cargos: sus importes con y sin impuestos son el importe
+-- total y el valor de venta.
+ALTER TABLE documents ADD COLUMN tax_exclusive_amount TEXT NOT NULL DEFAULT '0';
+ALTER TABLE documents ADD COLUMN tax_inclusive_amount TEXT NOT NULL DEFAULT '0';
+ALTER TABLE documents ADD COLUMN total_allowances TEXT NOT NULL DEFAULT '0';
+ALTER TABLE documents ADD COLUMN total_charges TEXT NOT NULL DEFAULT '0';
+
+UPDATE documents SET tax_exclusive_amount = sub_total, tax_inclusive_amount = total_amount;
+
+CREATE TABLE allowance_charges (
+    document_id TEXT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+    line_no     INTEGER NOT NULL,
+    position    INTEGER NOT NULL,
+    code        TEXT NOT NULL,
+    factor      TEXT,
+    amount      TEXT NOT NULL,
+    base_amount TEXT NOT NULL,
+    PRIMARY KEY (document_id, line_no, position)
+);
@@ -0,0 +1,9 @@
+-- Cuotas de las ventas al crédito. Los documentos anteriores tienen un único
+-- vencimiento en payment_due_date.
+CREATE TABLE installments (
+    document_id TEXT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    amount      TEXT NOT NULL,
+    due_date    TEXT NOT NULL,
+    PRIMARY KEY (document_id, position)
+);
@@ -0,0 +1,8 @@
+-- Tipo de operación (catálogo 51) y detracción de las operaciones sujetas.
+-- Los documentos anteriores son ventas internas sin detracción.
+ALTER TABLE documents ADD COLUMN operation_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN detraction_code TEXT;
+ALTER TABLE documents ADD COLUMN detraction_percent TEXT;
+ALTER TABLE documents ADD COLUMN detraction_amount TEXT;
+ALTER TABLE documents ADD COLUMN detraction_account TEXT;
+ALTER TABLE documents ADD COLUMN detraction_payment_means TEXT;
@@ -0,0 +1,5 @@
+-- Exportaciones: país de destino, Incoterm y tipo de cambio declarado
+ALTER TABLE documents ADD COLUMN destination_country TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN incoterm TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN exchange_rate_currency TEXT;
+ALTER TABLE documents ADD COLUMN exchange_rate TEXT;
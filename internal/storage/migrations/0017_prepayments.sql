@@ -0,0 +1,15 @@
+-- Anticipos: comprobantes emitidos por un anticipo y anticipos deducidos en
+-- el comprobante final
+ALTER TABLE documents ADD COLUMN advance INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE documents ADD COLUMN total_prepaid TEXT NOT NULL DEFAULT '0';
+
+CREATE TABLE prepayments (
+    document_id    TEXT NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+    position       INTEGER NOT NULL,
+    document_type  TEXT NOT NULL,
+    serie          TEXT NOT NULL,
+    number         TEXT NOT NULL,
+    amount         TEXT NOT NULL,
+    taxable_amount TEXT NOT NULL,
+    PRIMARY KEY (document_id, position)
+);
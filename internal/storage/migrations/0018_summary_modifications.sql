@@ -0,0 +1,3 @@
+-- Documentos informados en un Resumen Diario y corregidos después, que se
+-- informan con estado 2 (modificar) en el siguiente resumen
+ALTER TABLE documents ADD COLUMN modified INTEGER NOT NULL DEFAULT 0;
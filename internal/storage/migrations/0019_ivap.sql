@@ -0,0 +1,3 @@
+-- Valor de venta de las líneas con IVAP (afectación 17), que se informa
+-- aparte de las gravadas con IGV
+ALTER TABLE documents ADD COLUMN total_ivap TEXT NOT NULL DEFAULT '0';
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"infac/internal/models"
+)
+
+var (
+	ErrNotFound      = errors.New("document not found")
+	ErrAlreadyExists = errors.New("document already exists")
+)
+
+// DocumentRepository abstrae la persistencia de comprobantes para que el
+// servicio no dependa de un backend concreto (archivos JSON, SQL, etc.)
+type DocumentRepository interface {
+	Save(doc *models.Document) error
+	FindByID(id string) (*models.Document, error)
+	Update(doc *models.Document) error
+	Delete(id string) error
+	List(filter DocumentFilter) ([]*models.Document, error)
+}
+
+// DocumentFilter define los criterios de búsqueda para List.
+// Los campos vacíos no filtran. Los resultados se ordenan por fecha de
+// creación e ID; After y Limit permiten paginar sobre ese orden.
+type DocumentFilter struct {
+	Status      models.DocumentStatus
+	Type        models.DocumentType
+	Serie       string
+	CustomerRUC string
+	From        *time.Time // Fecha de emisión desde (inclusive)
+	To          *time.Time // Fecha de emisión hasta (inclusive)
+
+	After *Cursor // Devolver solo documentos posteriores al cursor
+	Limit int     // 0 = sin límite
+}
+
+// Cursor identifica una posición en el listado ordenado por (CreatedAt, ID)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CursorFor devuelve el cursor que apunta justo después del documento
+func CursorFor(doc *models.Document) *Cursor {
+	return &Cursor{CreatedAt: doc.CreatedAt, ID: doc.ID}
+}
+
+// Encode serializa el cursor en un token opaco apto para URLs
+func (c *Cursor) Encode() string {
+	raw := fmt.Sprintf("%d|%s", c.CreatedAt.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor interpreta un token generado por Cursor.Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var unixNano int64
+	if _, err := fmt.Sscanf(nanos, "%d", &unixNano); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &Cursor{CreatedAt: time.Unix(0, unixNano).UTC(), ID: id}, nil
+}
+
+// Precedes indica si el cursor está antes del documento en el orden del listado
+func (c *Cursor) Precedes(doc *models.Document) bool {
+	if doc.CreatedAt.Equal(c.CreatedAt) {
+		return doc.ID > c.ID
+	}
+	return doc.CreatedAt.After(c.CreatedAt)
+}
+
+// Matches indica si el documento cumple el filtro (sin considerar paginación)
+func (f DocumentFilter) Matches(doc *models.Document) bool {
+	if f.Status != "" && doc.Status != f.Status {
+		return false
+	}
+	if f.Type != "" && doc.Type != f.Type {
+		return false
+	}
+	if f.Serie != "" && doc.Serie != f.Serie {
+		return false
+	}
+	if f.CustomerRUC != "" && doc.Customer.DocumentNumber != f.CustomerRUC {
+		return false
+	}
+	if f.From != nil && doc.IssueDate.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && doc.IssueDate.After(*f.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package storage
+
+import "fmt"
+
+// SequenceKey identifica un correlativo: cada RUC lleva su propia numeración
+// por tipo de documento y serie
+type SequenceKey struct {
+	RUC          string
+	DocumentType string
+	Serie        string
+}
+
+func (k SequenceKey) String() string {
+	return fmt.Sprintf("%s|%s|%s", k.RUC, k.DocumentType, k.Serie)
+}
+
+// SequenceRepository asigna correlativos de forma atómica
+type SequenceRepository interface {
+	// Next incrementa el correlativo y devuelve el nuevo valor
+	Next(key SequenceKey) (int64, error)
+	// Current devuelve el último correlativo asignado (0 si no hay ninguno)
+	Current(key SequenceKey) (int64, error)
+	// Reserve garantiza que el correlativo sea al menos n, para números
+	// asignados fuera del sistema
+	Reserve(key SequenceKey, n int64) error
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"infac/internal/models"
+)
+
+func (r *SQLiteRepository) SaveIdempotencyRecord(record *models.IdempotencyRecord) error {
+	_, err := r.db.Exec(`INSERT INTO idempotency_keys (key, request_hash, status_code, response, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET request_hash = excluded.request_hash, status_code = excluded.status_code,
+			response = excluded.response, created_at = excluded.created_at`,
+		record.Key, record.RequestHash, record.StatusCode, record.Response, formatTime(record.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) FindIdempotencyRecord(key string) (*models.IdempotencyRecord, error) {
+	var (
+		record    models.IdempotencyRecord
+		createdAt string
+	)
+	err := r.db.QueryRow(`SELECT key, request_hash, status_code, response, created_at
+		FROM idempotency_keys WHERE key = ?`, key).
+		Scan(&record.Key, &record.RequestHash, &record.StatusCode, &record.Response, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency record: %w", err)
+	}
+
+	record.CreatedAt, _ = parseTime(createdAt)
+	return &record, nil
+}
+
+func (r *SQLiteRepository) DeleteIdempotencyRecords(before time.Time) (int, error) {
+	result, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE created_at < ?`, formatTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete idempotency records: %w", err)
+	}
+
+	deleted, _ := result.RowsAffected()
+	return int(deleted), nil
+}
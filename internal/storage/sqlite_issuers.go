@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"infac/internal/config"
+)
+
+// Los emisores se guardan como JSON: su estructura sigue a la configuración
+// y no se consulta por sus campos
+
+func (r *SQLiteRepository) SaveIssuer(issuer *config.IssuerConfig) error {
+	data, err := json.Marshal(issuer)
+	if err != nil {
+		return fmt.Errorf("failed to encode issuer: %w", err)
+	}
+
+	now := formatTime(time.Now())
+	_, err = r.db.Exec(`INSERT INTO issuers (ruc, data, created_at, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (ruc) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		issuer.RUC(), string(data), now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save issuer %s: %w", issuer.RUC(), err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) FindIssuer(ruc string) (*config.IssuerConfig, error) {
+	var data string
+	err := r.db.QueryRow(`SELECT data FROM issuers WHERE ruc = ?`, ruc).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIssuerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuer %s: %w", ruc, err)
+	}
+
+	var issuer config.IssuerConfig
+	if err := json.Unmarshal([]byte(data), &issuer); err != nil {
+		return nil, fmt.Errorf("failed to decode issuer %s: %w", ruc, err)
+	}
+	return &issuer, nil
+}
+
+func (r *SQLiteRepository) ListIssuers() ([]*config.IssuerConfig, error) {
+	rows, err := r.db.Query(`SELECT data FROM issuers ORDER BY ruc`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query issuers: %w", err)
+	}
+	defer rows.Close()
+
+	var issuers []*config.IssuerConfig
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan issuer: %w", err)
+		}
+
+		var issuer config.IssuerConfig
+		if err := json.Unmarshal([]byte(data), &issuer); err != nil {
+			return nil, fmt.Errorf("failed to decode issuer: %w", err)
+		}
+		issuers = append(issuers, &issuer)
+	}
+
+	return issuers, rows.Err()
+}
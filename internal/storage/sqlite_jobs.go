@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"infac/internal/models"
+)
+
+const jobColumns = `id, document_id, status, attempts, max_attempts, next_attempt_at, last_error,
+	created_at, updated_at, completed_at`
+
+func (r *SQLiteRepository) SaveJob(job *models.SendJob) error {
+	_, err := r.db.Exec(`INSERT INTO jobs (`+jobColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET status = excluded.status, attempts = excluded.attempts,
+			max_attempts = excluded.max_attempts, next_attempt_at = excluded.next_attempt_at,
+			last_error = excluded.last_error, updated_at = excluded.updated_at,
+			completed_at = excluded.completed_at`,
+		job.ID, job.DocumentID, string(job.Status), job.Attempts, job.MaxAttempts,
+		formatOptionalTime(job.NextAttemptAt), job.LastError,
+		formatTime(job.CreatedAt), formatTime(job.UpdatedAt), formatOptionalTime(job.CompletedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save job %s: %w", job.ID, err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) FindJob(id string) (*models.SendJob, error) {
+	jobs, err := r.queryJobs(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return jobs[0], nil
+}
+
+func (r *SQLiteRepository) ListJobs(status models.JobStatus) ([]*models.SendJob, error) {
+	if status == "" {
+		return r.queryJobs(`SELECT ` + jobColumns + ` FROM jobs ORDER BY created_at`)
+	}
+	return r.queryJobs(`SELECT `+jobColumns+` FROM jobs WHERE status = ? ORDER BY created_at`, string(status))
+}
+
+func (r *SQLiteRepository) queryJobs(query string, args ...interface{}) ([]*models.SendJob, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.SendJob
+	for rows.Next() {
+		var (
+			job                        models.SendJob
+			status, createdAt, updated string
+			nextAttemptAt, completedAt sql.NullString
+		)
+		if err := rows.Scan(&job.ID, &job.DocumentID, &status, &job.Attempts, &job.MaxAttempts,
+			&nextAttemptAt, &job.LastError, &createdAt, &updated, &completedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+
+		job.Status = models.JobStatus(status)
+		job.NextAttemptAt = parseOptionalTime(nextAttemptAt)
+		job.CompletedAt = parseOptionalTime(completedAt)
+		job.CreatedAt, _ = parseTime(createdAt)
+		job.UpdatedAt, _ = parseTime(updated)
+		jobs = append(jobs, &job)
+	}
+
+	return jobs, rows.Err()
+}
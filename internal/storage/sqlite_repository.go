@@ -0,0 +1,768 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+
+	_ "modernc.org/sqlite"
+)
+
+// Formato fijo en UTC para que las fechas se puedan comparar como texto
+const sqlTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// SQLiteRepository persiste los documentos en una base SQLite embebida con
+// un esquema normalizado (documentos, líneas, impuestos, relacionados y CDR)
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteRepository(path string) (*SQLiteRepository, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteRepository{db: db}, nil
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *SQLiteRepository) Save(doc *models.Document) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		exists, err := documentExists(tx, doc.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrAlreadyExists
+		}
+
+		return insertDocument(tx, doc)
+	})
+}
+
+func (r *SQLiteRepository) FindByID(id string) (*models.Document, error) {
+	docs, err := r.queryDocuments(`SELECT `+documentColumns+` FROM documents WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrNotFound
+	}
+	return docs[0], nil
+}
+
+func (r *SQLiteRepository) Update(doc *models.Document) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		exists, err := documentExists(tx, doc.ID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+
+		// Las tablas hijas se eliminan en cascada y se vuelven a insertar
+		if _, err := tx.Exec(`DELETE FROM documents WHERE id = ?`, doc.ID); err != nil {
+			return fmt.Errorf("failed to update document: %w", err)
+		}
+
+		return insertDocument(tx, doc)
+	})
+}
+
+func (r *SQLiteRepository) Delete(id string) error {
+	result, err := r.db.Exec(`DELETE FROM documents WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) List(filter DocumentFilter) ([]*models.Document, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, string(filter.Status))
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, string(filter.Type))
+	}
+	if filter.Serie != "" {
+		conditions = append(conditions, "serie = ?")
+		args = append(args, filter.Serie)
+	}
+	if filter.CustomerRUC != "" {
+		conditions = append(conditions, "customer_document_number = ?")
+		args = append(args, filter.CustomerRUC)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "issue_date >= ?")
+		args = append(args, formatTime(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "issue_date <= ?")
+		args = append(args, formatTime(*filter.To))
+	}
+	if filter.After != nil {
+		after := formatTime(filter.After.CreatedAt)
+		conditions = append(conditions, "(created_at > ? OR (created_at = ? AND id > ?))")
+		args = append(args, after, after, filter.After.ID)
+	}
+
+	query := `SELECT ` + documentColumns + ` FROM documents`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at, id"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	return r.queryDocuments(query, args...)
+}
+
+func (r *SQLiteRepository) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const documentColumns = `id, serie, number, type, issue_date, due_date, currency_code, issuer, customer,
+	sub_total, total_taxes, total_amount, payment_means_code, payment_due_date, payment_amount,
+	status, sunat_status, created_at, updated_at, void_ticket, void_reason,
+	summary_ticket, channel, total_taxed, total_exonerated, total_unaffected, total_export, total_free,
+	tax_exclusive_amount, tax_inclusive_amount, total_allowances, total_charges, operation_type,
+	detraction_code, detraction_percent, detraction_amount, detraction_account, detraction_payment_means,
+	destination_country, incoterm, exchange_rate_currency, exchange_rate, advance, total_prepaid, modified, total_ivap`
+
+func documentExists(tx *sql.Tx, id string) (bool, error) {
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM documents WHERE id = ?`, id).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check document: %w", err)
+	}
+	return count > 0, nil
+}
+
+func insertDocument(tx *sql.Tx, doc *models.Document) error {
+	issuer, err := json.Marshal(doc.Issuer)
+	if err != nil {
+		return fmt.Errorf("failed to encode issuer: %w", err)
+	}
+	customer, err := json.Marshal(doc.Customer)
+	if err != nil {
+		return fmt.Errorf("failed to encode customer: %w", err)
+	}
+
+	var dueDate, paymentMeansCode, paymentDueDate, paymentAmount sql.NullString
+	if doc.DueDate != nil {
+		dueDate = sql.NullString{String: formatTime(*doc.DueDate), Valid: true}
+	}
+	if doc.PaymentTerms != nil {
+		paymentMeansCode = sql.NullString{String: doc.PaymentTerms.PaymentMeansCode, Valid: true}
+		paymentDueDate = sql.NullString{String: formatTime(doc.PaymentTerms.DueDate), Valid: true}
+		paymentAmount = sql.NullString{String: formatAmount(doc.PaymentTerms.Amount), Valid: true}
+	}
+
+	var detractionCode, detractionPercent, detractionAmount, detractionAccount, detractionPaymentMeans sql.NullString
+	if doc.Detraction != nil {
+		detractionCode = sql.NullString{String: string(doc.Detraction.Code), Valid: true}
+		detractionPercent = sql.NullString{String: formatAmount(doc.Detraction.Percent), Valid: true}
+		detractionAmount = sql.NullString{String: formatAmount(doc.Detraction.Amount), Valid: true}
+		detractionAccount = sql.NullString{String: doc.Detraction.Account, Valid: true}
+		detractionPaymentMeans = sql.NullString{String: doc.Detraction.PaymentMeansCode, Valid: true}
+	}
+
+	var exchangeRateCurrency, exchangeRate sql.NullString
+	if doc.ExchangeRate != nil {
+		exchangeRateCurrency = sql.NullString{String: doc.ExchangeRate.TargetCurrencyCode, Valid: true}
+		exchangeRate = sql.NullString{String: formatAmount(doc.ExchangeRate.Rate), Valid: true}
+	}
+
+	_, err = tx.Exec(`INSERT INTO documents (`+documentColumns+`, customer_document_number)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		doc.ID, doc.Serie, doc.Number, string(doc.Type), formatTime(doc.IssueDate), dueDate,
+		doc.CurrencyCode, string(issuer), string(customer),
+		formatAmount(doc.SubTotal), formatAmount(doc.TotalTaxes), formatAmount(doc.TotalAmount),
+		paymentMeansCode, paymentDueDate, paymentAmount,
+		string(doc.Status), doc.SUNATStatus, formatTime(doc.CreatedAt), formatTime(doc.UpdatedAt),
+		doc.VoidTicket, doc.VoidReason, doc.SummaryTicket, doc.Channel,
+		formatAmount(doc.TotalTaxed), formatAmount(doc.TotalExonerated), formatAmount(doc.TotalUnaffected),
+		formatAmount(doc.TotalExport), formatAmount(doc.TotalFree),
+		formatAmount(doc.TaxExclusiveAmount), formatAmount(doc.TaxInclusiveAmount),
+		formatAmount(doc.TotalAllowances), formatAmount(doc.TotalCharges), string(doc.OperationType),
+		detractionCode, detractionPercent, detractionAmount, detractionAccount, detractionPaymentMeans,
+		doc.DestinationCountry, doc.Incoterm, exchangeRateCurrency, exchangeRate,
+		doc.Advance, formatAmount(doc.TotalPrepaid), doc.Modified, formatAmount(doc.TotalIVAP),
+		doc.Customer.DocumentNumber,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert document: %w", err)
+	}
+
+	for i, line := range doc.Lines {
+		_, err := tx.Exec(`INSERT INTO document_lines
+			(document_id, line_no, id, quantity, unit_code, description, unit_price, total_price, taxable_amount, product_code, igv_affectation)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			doc.ID, i, line.ID, formatAmount(line.Quantity), line.UnitCode, line.Description,
+			formatAmount(line.UnitPrice), formatAmount(line.TotalPrice), formatAmount(line.TaxableAmount),
+			line.ProductCode, string(line.Affectation()),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert line %d: %w", i+1, err)
+		}
+
+		for j, tax := range line.Taxes {
+			var referencePrice sql.NullString
+			if tax.ReferencePrice != nil {
+				referencePrice = sql.NullString{String: formatAmount(*tax.ReferencePrice), Valid: true}
+			}
+			_, err := tx.Exec(`INSERT INTO line_taxes
+				(document_id, line_no, position, type, code, rate, amount, taxable_amount, isc_system, reference_price)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				doc.ID, i, j, string(tax.Type), tax.Code, formatAmount(tax.Rate), formatAmount(tax.Amount),
+				formatAmount(tax.TaxableAmount), string(tax.ISCSystem), referencePrice,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert tax for line %d: %w", i+1, err)
+			}
+		}
+
+		if err := insertAllowanceCharges(tx, doc.ID, i, line.AllowanceCharges); err != nil {
+			return fmt.Errorf("failed to insert allowances and charges for line %d: %w", i+1, err)
+		}
+	}
+
+	if err := insertAllowanceCharges(tx, doc.ID, -1, doc.AllowanceCharges); err != nil {
+		return fmt.Errorf("failed to insert allowances and charges: %w", err)
+	}
+
+	if doc.PaymentTerms != nil {
+		for i, installment := range doc.PaymentTerms.Installments {
+			_, err := tx.Exec(`INSERT INTO installments (document_id, position, amount, due_date) VALUES (?, ?, ?, ?)`,
+				doc.ID, i, formatAmount(installment.Amount), formatTime(installment.DueDate),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert installment %d: %w", i+1, err)
+			}
+		}
+	}
+
+	for i, related := range doc.RelatedDocuments {
+		_, err := tx.Exec(`INSERT INTO related_documents (document_id, position, document_type, serie, number)
+			VALUES (?, ?, ?, ?, ?)`,
+			doc.ID, i, string(related.DocumentType), related.Serie, related.Number,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert related document: %w", err)
+		}
+	}
+
+	for i, p := range doc.Prepayments {
+		_, err := tx.Exec(`INSERT INTO prepayments (document_id, position, document_type, serie, number, amount, taxable_amount)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			doc.ID, i, string(p.DocumentType), p.Serie, p.Number, formatAmount(p.Amount), formatAmount(p.TaxableAmount),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert prepayment %d: %w", i+1, err)
+		}
+	}
+
+	for i, change := range doc.StatusHistory {
+		_, err := tx.Exec(`INSERT INTO status_history (document_id, position, from_status, to_status, reason, changed_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			doc.ID, i, string(change.From), string(change.To), change.Reason, formatTime(change.At),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert status history: %w", err)
+		}
+	}
+
+	if doc.CDR != nil {
+		_, err := tx.Exec(`INSERT INTO cdrs (document_id, response_code, description, notes, document_ref, response_date)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			doc.ID, doc.CDR.ResponseCode, doc.CDR.Description, doc.CDR.Notes,
+			doc.CDR.DocumentID, formatOptionalTime(doc.CDR.ResponseDate),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert CDR: %w", err)
+		}
+
+		for i, observation := range doc.CDR.Observations {
+			_, err := tx.Exec(`INSERT INTO cdr_observations (document_id, position, code, description) VALUES (?, ?, ?, ?)`,
+				doc.ID, i, observation.Code, observation.Description,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert CDR observation: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// insertAllowanceCharges guarda los descuentos y cargos de una línea o, con
+// lineNo -1, los globales
+func insertAllowanceCharges(tx *sql.Tx, documentID string, lineNo int, items []models.AllowanceCharge) error {
+	for i, ac := range items {
+		var factor sql.NullString
+		if ac.Factor != nil {
+			factor = sql.NullString{String: formatAmount(*ac.Factor), Valid: true}
+		}
+		_, err := tx.Exec(`INSERT INTO allowance_charges (document_id, line_no, position, code, factor, amount, base_amount)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			documentID, lineNo, i, string(ac.Code), factor, formatAmount(ac.Amount), formatAmount(ac.BaseAmount),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryDocuments ejecuta la consulta sobre documents y luego carga las tablas
+// hijas de cada resultado
+func (r *SQLiteRepository) queryDocuments(query string, args ...interface{}) ([]*models.Document, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+
+	var docs []*models.Document
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	for _, doc := range docs {
+		if err := r.loadChildren(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	return docs, nil
+}
+
+func scanDocument(rows *sql.Rows) (*models.Document, error) {
+	var (
+		doc                                                                  models.Document
+		docType, status, issueDate, createdAt, updatedAt                     string
+		issuer, customer, subTotal, totalTaxes, totalAmount                  string
+		dueDate, paymentMeansCode, paymentDueDate, paymentAmount             sql.NullString
+		totalTaxed, totalExonerated, totalUnaffected, totalExport, totalFree string
+		taxExclusive, taxInclusive, totalAllowances, totalCharges            string
+		totalPrepaid, totalIVAP                                              string
+		operationType                                                        string
+		detractionCode, detractionPercent, detractionAmount                  sql.NullString
+		detractionAccount, detractionPaymentMeans                            sql.NullString
+		exchangeRateCurrency, exchangeRate                                   sql.NullString
+	)
+
+	err := rows.Scan(&doc.ID, &doc.Serie, &doc.Number, &docType, &issueDate, &dueDate, &doc.CurrencyCode,
+		&issuer, &customer, &subTotal, &totalTaxes, &totalAmount,
+		&paymentMeansCode, &paymentDueDate, &paymentAmount,
+		&status, &doc.SUNATStatus, &createdAt, &updatedAt, &doc.VoidTicket, &doc.VoidReason,
+		&doc.SummaryTicket, &doc.Channel,
+		&totalTaxed, &totalExonerated, &totalUnaffected, &totalExport, &totalFree,
+		&taxExclusive, &taxInclusive, &totalAllowances, &totalCharges, &operationType,
+		&detractionCode, &detractionPercent, &detractionAmount, &detractionAccount, &detractionPaymentMeans,
+		&doc.DestinationCountry, &doc.Incoterm, &exchangeRateCurrency, &exchangeRate,
+		&doc.Advance, &totalPrepaid, &doc.Modified, &totalIVAP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan document: %w", err)
+	}
+
+	doc.Type = models.DocumentType(docType)
+	doc.Status = models.DocumentStatus(status)
+	doc.OperationType = models.OperationType(operationType)
+
+	if err := json.Unmarshal([]byte(issuer), &doc.Issuer); err != nil {
+		return nil, fmt.Errorf("failed to decode issuer of %s: %w", doc.ID, err)
+	}
+	if err := json.Unmarshal([]byte(customer), &doc.Customer); err != nil {
+		return nil, fmt.Errorf("failed to decode customer of %s: %w", doc.ID, err)
+	}
+
+	if doc.IssueDate, err = parseTime(issueDate); err != nil {
+		return nil, err
+	}
+	if doc.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if doc.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	if dueDate.Valid {
+		t, err := parseTime(dueDate.String)
+		if err != nil {
+			return nil, err
+		}
+		doc.DueDate = &t
+	}
+
+	if doc.SubTotal, err = parseAmount(subTotal); err != nil {
+		return nil, err
+	}
+	if doc.TotalTaxes, err = parseAmount(totalTaxes); err != nil {
+		return nil, err
+	}
+	if doc.TotalAmount, err = parseAmount(totalAmount); err != nil {
+		return nil, err
+	}
+	buckets := []struct {
+		total *decimal.Decimal
+		value string
+	}{
+		{&doc.TotalTaxed, totalTaxed},
+		{&doc.TotalIVAP, totalIVAP},
+		{&doc.TotalExonerated, totalExonerated},
+		{&doc.TotalUnaffected, totalUnaffected},
+		{&doc.TotalExport, totalExport},
+		{&doc.TotalFree, totalFree},
+		{&doc.TaxExclusiveAmount, taxExclusive},
+		{&doc.TaxInclusiveAmount, taxInclusive},
+		{&doc.TotalAllowances, totalAllowances},
+		{&doc.TotalCharges, totalCharges},
+		{&doc.TotalPrepaid, totalPrepaid},
+	}
+	for _, bucket := range buckets {
+		if *bucket.total, err = parseAmount(bucket.value); err != nil {
+			return nil, err
+		}
+	}
+
+	if paymentMeansCode.Valid {
+		terms := &models.PaymentTerms{PaymentMeansCode: paymentMeansCode.String}
+		if terms.DueDate, err = parseTime(paymentDueDate.String); err != nil {
+			return nil, err
+		}
+		if terms.Amount, err = parseAmount(paymentAmount.String); err != nil {
+			return nil, err
+		}
+		doc.PaymentTerms = terms
+	}
+
+	if detractionCode.Valid {
+		detraction := &models.Detraction{
+			Code:             models.DetractionCode(detractionCode.String),
+			Account:          detractionAccount.String,
+			PaymentMeansCode: detractionPaymentMeans.String,
+		}
+		if detraction.Percent, err = parseAmount(detractionPercent.String); err != nil {
+			return nil, err
+		}
+		if detraction.Amount, err = parseAmount(detractionAmount.String); err != nil {
+			return nil, err
+		}
+		doc.Detraction = detraction
+	}
+
+	if exchangeRate.Valid {
+		rate := &models.ExchangeRate{TargetCurrencyCode: exchangeRateCurrency.String}
+		if rate.Rate, err = parseAmount(exchangeRate.String); err != nil {
+			return nil, err
+		}
+		doc.ExchangeRate = rate
+	}
+
+	return &doc, nil
+}
+
+func (r *SQLiteRepository) loadChildren(doc *models.Document) error {
+	rows, err := r.db.Query(`SELECT id, quantity, unit_code, description, unit_price, total_price, taxable_amount, product_code, igv_affectation
+		FROM document_lines WHERE document_id = ? ORDER BY line_no`, doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query lines: %w", err)
+	}
+
+	for rows.Next() {
+		var line models.DocumentLine
+		var quantity, unitPrice, totalPrice, taxableAmount, affectation string
+		if err := rows.Scan(&line.ID, &quantity, &line.UnitCode, &line.Description,
+			&unitPrice, &totalPrice, &taxableAmount, &line.ProductCode, &affectation); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan line: %w", err)
+		}
+		line.Quantity, _ = parseAmount(quantity)
+		line.UnitPrice, _ = parseAmount(unitPrice)
+		line.TotalPrice, _ = parseAmount(totalPrice)
+		line.TaxableAmount, _ = parseAmount(taxableAmount)
+		line.IGVAffectation = models.IGVAffectation(affectation)
+		doc.Lines = append(doc.Lines, line)
+	}
+	rows.Close()
+
+	rows, err = r.db.Query(`SELECT line_no, type, code, rate, amount, taxable_amount, isc_system, reference_price
+		FROM line_taxes WHERE document_id = ? ORDER BY line_no, position`, doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query taxes: %w", err)
+	}
+
+	for rows.Next() {
+		var lineNo int
+		var tax models.Tax
+		var taxType, rate, amount, taxableAmount, iscSystem string
+		var referencePrice sql.NullString
+		if err := rows.Scan(&lineNo, &taxType, &tax.Code, &rate, &amount, &taxableAmount, &iscSystem, &referencePrice); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan tax: %w", err)
+		}
+		tax.Type = models.TaxType(taxType)
+		tax.Rate, _ = parseAmount(rate)
+		tax.Amount, _ = parseAmount(amount)
+		tax.TaxableAmount, _ = parseAmount(taxableAmount)
+		tax.ISCSystem = models.ISCSystem(iscSystem)
+		if referencePrice.Valid {
+			price, _ := parseAmount(referencePrice.String)
+			tax.ReferencePrice = &price
+		}
+		if lineNo < len(doc.Lines) {
+			doc.Lines[lineNo].Taxes = append(doc.Lines[lineNo].Taxes, tax)
+		}
+	}
+	rows.Close()
+
+	rows, err = r.db.Query(`SELECT line_no, code, factor, amount, base_amount
+		FROM allowance_charges WHERE document_id = ? ORDER BY line_no, position`, doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query allowances and charges: %w", err)
+	}
+
+	for rows.Next() {
+		var lineNo int
+		var ac models.AllowanceCharge
+		var code, amount, baseAmount string
+		var factor sql.NullString
+		if err := rows.Scan(&lineNo, &code, &factor, &amount, &baseAmount); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan allowance or charge: %w", err)
+		}
+		ac.Code = models.AllowanceChargeCode(code)
+		ac.Amount, _ = parseAmount(amount)
+		ac.BaseAmount, _ = parseAmount(baseAmount)
+		if factor.Valid {
+			value, _ := parseAmount(factor.String)
+			ac.Factor = &value
+		}
+		switch {
+		case lineNo < 0:
+			doc.AllowanceCharges = append(doc.AllowanceCharges, ac)
+		case lineNo < len(doc.Lines):
+			doc.Lines[lineNo].AllowanceCharges = append(doc.Lines[lineNo].AllowanceCharges, ac)
+		}
+	}
+	rows.Close()
+
+	if doc.PaymentTerms != nil {
+		rows, err = r.db.Query(`SELECT amount, due_date FROM installments WHERE document_id = ? ORDER BY position`, doc.ID)
+		if err != nil {
+			return fmt.Errorf("failed to query installments: %w", err)
+		}
+
+		for rows.Next() {
+			var installment models.Installment
+			var amount, dueDate string
+			if err := rows.Scan(&amount, &dueDate); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan installment: %w", err)
+			}
+			installment.Amount, _ = parseAmount(amount)
+			installment.DueDate, _ = parseTime(dueDate)
+			doc.PaymentTerms.Installments = append(doc.PaymentTerms.Installments, installment)
+		}
+		rows.Close()
+	}
+
+	rows, err = r.db.Query(`SELECT document_type, serie, number
+		FROM related_documents WHERE document_id = ? ORDER BY position`, doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query related documents: %w", err)
+	}
+
+	for rows.Next() {
+		var related models.RelatedDocument
+		var docType string
+		if err := rows.Scan(&docType, &related.Serie, &related.Number); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan related document: %w", err)
+		}
+		related.DocumentType = models.DocumentType(docType)
+		doc.RelatedDocuments = append(doc.RelatedDocuments, related)
+	}
+	rows.Close()
+
+	rows, err = r.db.Query(`SELECT document_type, serie, number, amount, taxable_amount
+		FROM prepayments WHERE document_id = ? ORDER BY position`, doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query prepayments: %w", err)
+	}
+
+	for rows.Next() {
+		var p models.Prepayment
+		var docType, amount, taxable string
+		if err := rows.Scan(&docType, &p.Serie, &p.Number, &amount, &taxable); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan prepayment: %w", err)
+		}
+		p.DocumentType = models.DocumentType(docType)
+		p.Amount, _ = parseAmount(amount)
+		p.TaxableAmount, _ = parseAmount(taxable)
+		doc.Prepayments = append(doc.Prepayments, p)
+	}
+	rows.Close()
+
+	rows, err = r.db.Query(`SELECT from_status, to_status, reason, changed_at
+		FROM status_history WHERE document_id = ? ORDER BY position`, doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query status history: %w", err)
+	}
+
+	for rows.Next() {
+		var change models.StatusChange
+		var from, to, changedAt string
+		if err := rows.Scan(&from, &to, &change.Reason, &changedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan status history: %w", err)
+		}
+		change.From = models.DocumentStatus(from)
+		change.To = models.DocumentStatus(to)
+		change.At, _ = parseTime(changedAt)
+		doc.StatusHistory = append(doc.StatusHistory, change)
+	}
+	rows.Close()
+
+	var cdr models.CDR
+	var responseDate sql.NullString
+	err = r.db.QueryRow(`SELECT response_code, description, notes, document_ref, response_date
+		FROM cdrs WHERE document_id = ?`, doc.ID).
+		Scan(&cdr.ResponseCode, &cdr.Description, &cdr.Notes, &cdr.DocumentID, &responseDate)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to query CDR: %w", err)
+	}
+	cdr.ResponseDate = parseOptionalTime(responseDate)
+
+	cdr.Observations, err = r.queryObservations(`SELECT code, description FROM cdr_observations
+		WHERE document_id = ? ORDER BY position`, doc.ID)
+	if err != nil {
+		return err
+	}
+	doc.CDR = &cdr
+
+	return nil
+}
+
+func (r *SQLiteRepository) queryObservations(query string, args ...interface{}) ([]models.CDRObservation, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CDR observations: %w", err)
+	}
+	defer rows.Close()
+
+	var observations []models.CDRObservation
+	for rows.Next() {
+		var observation models.CDRObservation
+		if err := rows.Scan(&observation.Code, &observation.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan CDR observation: %w", err)
+		}
+		observations = append(observations, observation)
+	}
+
+	return observations, rows.Err()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(sqlTimeLayout)
+}
+
+func formatOptionalTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
+
+func parseOptionalTime(value sql.NullString) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t, err := parseTime(value.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(sqlTimeLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid stored time %q: %w", value, err)
+	}
+	return t, nil
+}
+
+func formatAmount(v decimal.Decimal) string {
+	return v.String()
+}
+
+func parseAmount(value string) (decimal.Decimal, error) {
+	v, err := decimal.Parse(value)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid stored amount %q: %w", value, err)
+	}
+	return v, nil
+}
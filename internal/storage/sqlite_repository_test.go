@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestSQLiteRepository(t *testing.T, path string) *SQLiteRepository {
+	t.Helper()
+	repo, err := NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestSQLiteRepositoryContract(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) DocumentRepository {
+		return newTestSQLiteRepository(t, filepath.Join(t.TempDir(), "infac.db"))
+	})
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Fatalf("migration %s has version %d, want %d", m.name, m.version, i+1)
+		}
+	}
+	latest := migrations[len(migrations)-1].version
+
+	path := filepath.Join(t.TempDir(), "infac.db")
+	repo := newTestSQLiteRepository(t, path)
+	if got := schemaVersion(t, repo.db); got != latest {
+		t.Fatalf("schema version = %d, want %d", got, latest)
+	}
+	repo.Close()
+
+	// Reabrir la base no vuelve a aplicar las migraciones
+	repo = newTestSQLiteRepository(t, path)
+	var applied int
+	if err := repo.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(migrations) {
+		t.Fatalf("schema_migrations has %d rows, want %d", applied, len(migrations))
+	}
+}
+
+// Una base creada con el esquema inicial se actualiza y sus documentos se
+// siguen leyendo con los valores por defecto de las columnas nuevas
+func TestSQLiteMigratesLegacyDatabase(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "infac.db")
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TEXT NOT NULL)`,
+		migrations[0].sql,
+		`INSERT INTO schema_migrations VALUES (1, '0001_init.sql', '2024-01-01T00:00:00Z')`,
+		`INSERT INTO documents (id, serie, number, type, issue_date, currency_code, issuer, customer,
+			customer_document_number, sub_total, total_taxes, total_amount, status, created_at, updated_at)
+			VALUES ('F001-00000001', 'F001', '00000001', '01', '2024-01-02T00:00:00.000000000Z', 'PEN',
+			'{"document_number":"20612790168"}', '{"document_number":"20100070970"}', '20100070970',
+			'100.00', '18.00', '118.00', 'accepted', '2024-01-02T00:00:00.000000000Z', '2024-01-02T00:00:00.000000000Z')`,
+		`INSERT INTO document_lines (document_id, line_no, id, quantity, unit_code, description, unit_price, total_price, taxable_amount)
+			VALUES ('F001-00000001', 0, '1', '1', 'NIU', 'Item', '100', '100.00', '100.00')`,
+		`INSERT INTO line_taxes (document_id, line_no, position, type, code, rate, amount)
+			VALUES ('F001-00000001', 0, 0, 'IGV', '1000', '18', '18.00')`,
+		`INSERT INTO cdrs (document_id, response_code, description) VALUES ('F001-00000001', '0', 'Aceptada')`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("legacy schema: %v", err)
+		}
+	}
+	db.Close()
+
+	repo := newTestSQLiteRepository(t, path)
+	if got, want := schemaVersion(t, repo.db), migrations[len(migrations)-1].version; got != want {
+		t.Fatalf("schema version = %d, want %d", got, want)
+	}
+
+	doc, err := repo.FindByID("F001-00000001")
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if doc.TotalAmount.String() != "118.00" || !doc.IssueDate.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("legacy document = %s %s", doc.TotalAmount, doc.IssueDate)
+	}
+	if len(doc.Lines) != 1 || len(doc.Lines[0].Taxes) != 1 || doc.Lines[0].Taxes[0].Amount.String() != "18.00" {
+		t.Errorf("legacy lines = %+v", doc.Lines)
+	}
+	if doc.CDR == nil || doc.CDR.ResponseCode != "0" {
+		t.Errorf("legacy CDR = %+v", doc.CDR)
+	}
+	if err := doc.CheckTotals(); err != nil {
+		t.Errorf("CheckTotals: %v", err)
+	}
+}
+
+func schemaVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Los correlativos en SQLite comparten la base de datos de los documentos,
+// así SQLiteRepository implementa también SequenceRepository
+
+func (r *SQLiteRepository) Next(key SequenceKey) (int64, error) {
+	var next int64
+	err := r.db.QueryRow(`INSERT INTO sequences (ruc, document_type, serie, last_number) VALUES (?, ?, ?, 1)
+		ON CONFLICT (ruc, document_type, serie) DO UPDATE SET last_number = last_number + 1
+		RETURNING last_number`,
+		key.RUC, key.DocumentType, key.Serie,
+	).Scan(&next)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate number for %s: %w", key, err)
+	}
+
+	return next, nil
+}
+
+func (r *SQLiteRepository) Current(key SequenceKey) (int64, error) {
+	var current int64
+	err := r.db.QueryRow(`SELECT last_number FROM sequences WHERE ruc = ? AND document_type = ? AND serie = ?`,
+		key.RUC, key.DocumentType, key.Serie,
+	).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read sequence %s: %w", key, err)
+	}
+
+	return current, nil
+}
+
+func (r *SQLiteRepository) Reserve(key SequenceKey, n int64) error {
+	_, err := r.db.Exec(`INSERT INTO sequences (ruc, document_type, serie, last_number) VALUES (?, ?, ?, ?)
+		ON CONFLICT (ruc, document_type, serie) DO UPDATE SET last_number = MAX(last_number, excluded.last_number)`,
+		key.RUC, key.DocumentType, key.Serie, n,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reserve number for %s: %w", key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"infac/internal/models"
+)
+
+const ticketColumns = `number, summary_id, type, issue_date, reference_date, status,
+	cdr_response_code, cdr_description, cdr_notes, created_at, updated_at,
+	cdr_document_ref, cdr_response_date, channel`
+
+func (r *SQLiteRepository) SaveTicket(ticket *models.Ticket) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		return insertTicket(tx, ticket)
+	})
+}
+
+func (r *SQLiteRepository) FindTicket(number string) (*models.Ticket, error) {
+	tickets, err := r.queryTickets(`SELECT `+ticketColumns+` FROM tickets WHERE number = ?`, number)
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, ErrTicketNotFound
+	}
+	return tickets[0], nil
+}
+
+func (r *SQLiteRepository) UpdateTicket(ticket *models.Ticket) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM tickets WHERE number = ?`, ticket.Number)
+		if err != nil {
+			return fmt.Errorf("failed to update ticket: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return ErrTicketNotFound
+		}
+
+		return insertTicket(tx, ticket)
+	})
+}
+
+func (r *SQLiteRepository) ListTickets(status models.TicketStatus) ([]*models.Ticket, error) {
+	if status == "" {
+		return r.queryTickets(`SELECT ` + ticketColumns + ` FROM tickets ORDER BY created_at`)
+	}
+	return r.queryTickets(`SELECT `+ticketColumns+` FROM tickets WHERE status = ? ORDER BY created_at`, string(status))
+}
+
+func insertTicket(tx *sql.Tx, ticket *models.Ticket) error {
+	var responseCode, description, notes, documentRef, responseDate sql.NullString
+	if ticket.CDR != nil {
+		responseCode = sql.NullString{String: ticket.CDR.ResponseCode, Valid: true}
+		description = sql.NullString{String: ticket.CDR.Description, Valid: true}
+		notes = sql.NullString{String: ticket.CDR.Notes, Valid: true}
+		documentRef = sql.NullString{String: ticket.CDR.DocumentID, Valid: true}
+		responseDate = formatOptionalTime(ticket.CDR.ResponseDate)
+	}
+
+	_, err := tx.Exec(`INSERT INTO tickets (`+ticketColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ticket.Number, ticket.SummaryID, string(ticket.Type),
+		formatTime(ticket.IssueDate), formatTime(ticket.ReferenceDate), string(ticket.Status),
+		responseCode, description, notes,
+		formatTime(ticket.CreatedAt), formatTime(ticket.UpdatedAt),
+		documentRef, responseDate, ticket.Channel,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert ticket: %w", err)
+	}
+
+	if ticket.CDR != nil {
+		for i, observation := range ticket.CDR.Observations {
+			_, err := tx.Exec(`INSERT INTO ticket_cdr_observations (ticket_number, position, code, description)
+				VALUES (?, ?, ?, ?)`,
+				ticket.Number, i, observation.Code, observation.Description)
+			if err != nil {
+				return fmt.Errorf("failed to insert CDR observation: %w", err)
+			}
+		}
+	}
+
+	for i, documentID := range ticket.DocumentIDs {
+		_, err := tx.Exec(`INSERT INTO ticket_documents (ticket_number, position, document_id, condition_code)
+			VALUES (?, ?, ?, ?)`,
+			ticket.Number, i, documentID, string(ticket.Conditions[documentID]))
+		if err != nil {
+			return fmt.Errorf("failed to insert ticket document: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *SQLiteRepository) queryTickets(query string, args ...interface{}) ([]*models.Ticket, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tickets: %w", err)
+	}
+
+	var tickets []*models.Ticket
+	for rows.Next() {
+		var (
+			ticket                                       models.Ticket
+			ticketType, status, issueDate, referenceDate string
+			createdAt, updatedAt                         string
+			responseCode, description, notes             sql.NullString
+			documentRef, responseDate                    sql.NullString
+		)
+		if err := rows.Scan(&ticket.Number, &ticket.SummaryID, &ticketType, &issueDate, &referenceDate, &status,
+			&responseCode, &description, &notes, &createdAt, &updatedAt, &documentRef, &responseDate, &ticket.Channel); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan ticket: %w", err)
+		}
+
+		ticket.Type = models.SummaryType(ticketType)
+		ticket.Status = models.TicketStatus(status)
+		ticket.IssueDate, _ = parseTime(issueDate)
+		ticket.ReferenceDate, _ = parseTime(referenceDate)
+		ticket.CreatedAt, _ = parseTime(createdAt)
+		ticket.UpdatedAt, _ = parseTime(updatedAt)
+		if responseCode.Valid {
+			ticket.CDR = &models.CDR{
+				ResponseCode: responseCode.String,
+				Description:  description.String,
+				Notes:        notes.String,
+				DocumentID:   documentRef.String,
+				ResponseDate: parseOptionalTime(responseDate),
+			}
+		}
+
+		tickets = append(tickets, &ticket)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	for _, ticket := range tickets {
+		documentIDs, err := r.db.Query(`SELECT document_id, condition_code FROM ticket_documents
+			WHERE ticket_number = ? ORDER BY position`, ticket.Number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query ticket documents: %w", err)
+		}
+		for documentIDs.Next() {
+			var id, condition string
+			if err := documentIDs.Scan(&id, &condition); err != nil {
+				documentIDs.Close()
+				return nil, err
+			}
+			ticket.DocumentIDs = append(ticket.DocumentIDs, id)
+			if condition != "" {
+				if ticket.Conditions == nil {
+					ticket.Conditions = make(map[string]models.SummaryCondition)
+				}
+				ticket.Conditions[id] = models.SummaryCondition(condition)
+			}
+		}
+		documentIDs.Close()
+
+		if ticket.CDR != nil {
+			ticket.CDR.Observations, err = r.queryObservations(`SELECT code, description FROM ticket_cdr_observations
+				WHERE ticket_number = ? ORDER BY position`, ticket.Number)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return tickets, nil
+}
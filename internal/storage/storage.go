@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"fmt"
+
+	"infac/internal/config"
+)
+
+// Store agrupa los repositorios del backend configurado
+type Store struct {
+	Documents DocumentRepository
+	Sequences SequenceRepository
+	Tickets   TicketRepository
+	Issuers   IssuerRepository
+	Jobs      JobRepository
+
+	Idempotency IdempotencyRepository
+}
+
+// Open crea los repositorios indicados en storage.type
+func Open(cfg config.StorageConfig) (*Store, error) {
+	switch cfg.Type {
+	case "", "json":
+		documents, err := NewJSONRepository(cfg.JSON.Path)
+		if err != nil {
+			return nil, err
+		}
+		sequences, err := NewJSONSequenceRepository(cfg.JSON.SequencesPath)
+		if err != nil {
+			return nil, err
+		}
+		tickets, err := NewJSONTicketRepository(cfg.JSON.TicketsPath)
+		if err != nil {
+			return nil, err
+		}
+		issuers, err := NewJSONIssuerRepository(cfg.JSON.IssuersPath)
+		if err != nil {
+			return nil, err
+		}
+		jobs, err := NewJSONJobRepository(cfg.JSON.JobsPath)
+		if err != nil {
+			return nil, err
+		}
+		idempotency, err := NewJSONIdempotencyRepository(cfg.JSON.IdempotencyPath)
+		if err != nil {
+			return nil, err
+		}
+		return &Store{Documents: documents, Sequences: sequences, Tickets: tickets, Issuers: issuers, Jobs: jobs,
+			Idempotency: idempotency}, nil
+	case "sqlite":
+		repo, err := NewSQLiteRepository(cfg.SQLite.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &Store{Documents: repo, Sequences: repo, Tickets: repo, Issuers: repo, Jobs: repo,
+			Idempotency: repo}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", cfg.Type)
+	}
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"errors"
+
+	"infac/internal/models"
+)
+
+var ErrTicketNotFound = errors.New("ticket not found")
+
+// TicketRepository guarda los tickets de resúmenes y comunicaciones de baja
+type TicketRepository interface {
+	SaveTicket(ticket *models.Ticket) error
+	FindTicket(number string) (*models.Ticket, error)
+	UpdateTicket(ticket *models.Ticket) error
+	// ListTickets devuelve los tickets en el estado indicado ("" = todos)
+	ListTickets(status models.TicketStatus) ([]*models.Ticket, error)
+}
@@ -0,0 +1,314 @@
+// Package decimal implementa números decimales exactos para los importes,
+// cantidades y tasas de los comprobantes, con las reglas de redondeo de SUNAT
+package decimal
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxPlaces es la mayor cantidad de decimales que admite SUNAT: valores
+	// unitarios y cantidades
+	MaxPlaces = 10
+	// AmountPlaces son los decimales de los importes de línea y los totales
+	AmountPlaces = 2
+
+	// Parse acota el tamaño de los números que lee para que un exponente o
+	// un texto enorme no consuma memoria ni CPU: hasta maxIntegerDigits
+	// dígitos enteros y MaxPlaces decimales significativos
+	maxIntegerDigits = 30
+	maxExponent      = maxIntegerDigits + MaxPlaces
+	// maxDigits admite ceros a la izquierda o finales en la mantisa
+	maxDigits = 2 * maxExponent
+)
+
+// Decimal es un número decimal exacto: coef × 10^-places. places también
+// determina cuántos decimales se muestran (236.00 tiene 2). El valor cero
+// es 0.
+type Decimal struct {
+	coef   *big.Int
+	places int32
+}
+
+var (
+	Zero    = Decimal{}
+	Hundred = NewFromInt(100)
+)
+
+// New crea el decimal value × 10^-places: New(23600, 2) es 236.00
+func New(value int64, places int32) Decimal {
+	return Decimal{coef: big.NewInt(value), places: places}
+}
+
+func NewFromInt(value int64) Decimal {
+	return New(value, 0)
+}
+
+// Parse lee un número decimal como "236", "-0.18" o "1.5e3", de hasta 30
+// dígitos enteros y MaxPlaces decimales; los ceros finales que excedan
+// MaxPlaces se descartan
+func Parse(s string) (Decimal, error) {
+	value := strings.TrimSpace(s)
+	mantissa, exponent := value, int64(0)
+	if i := strings.IndexAny(value, "eE"); i != -1 {
+		var err error
+		mantissa = value[:i]
+		exponent, err = strconv.ParseInt(value[i+1:], 10, 32)
+		if err != nil {
+			return Zero, fmt.Errorf("invalid decimal %q", s)
+		}
+		if exponent > maxExponent || exponent < -maxExponent {
+			return Zero, fmt.Errorf("invalid decimal %q: exponent out of range", s)
+		}
+	}
+
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	digits := intPart + fracPart
+	if digits == "" || digits == "-" || digits == "+" || strings.ContainsAny(fracPart, "+-") {
+		return Zero, fmt.Errorf("invalid decimal %q", s)
+	}
+	if len(strings.TrimLeft(digits, "+-")) > maxDigits {
+		return Zero, fmt.Errorf("invalid decimal %q: too many digits", s)
+	}
+
+	coef, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Zero, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	places := int64(len(fracPart)) - exponent
+	if places < 0 {
+		coef.Mul(coef, pow10(int32(-places)))
+		places = 0
+	}
+	d := Decimal{coef: coef, places: int32(places)}
+	if d.places > MaxPlaces {
+		if d = d.trim(MaxPlaces); d.places > MaxPlaces {
+			return Zero, fmt.Errorf("invalid decimal %q: more than %d decimals", s, MaxPlaces)
+		}
+	}
+	if new(big.Int).Abs(d.coef).Cmp(pow10(maxIntegerDigits+d.places)) >= 0 {
+		return Zero, fmt.Errorf("invalid decimal %q: more than %d integer digits", s, maxIntegerDigits)
+	}
+
+	return d, nil
+}
+
+// MustParse es Parse para constantes; falla si el texto no es un número
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d Decimal) int() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// rescale devuelve el coeficiente con places decimales (places >= d.places)
+func (d Decimal) rescale(places int32) *big.Int {
+	coef := new(big.Int).Set(d.int())
+	if places > d.places {
+		coef.Mul(coef, pow10(places-d.places))
+	}
+	return coef
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	places := max(d.places, o.places)
+	return Decimal{coef: new(big.Int).Add(d.rescale(places), o.rescale(places)), places: places}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	return d.Add(o.Neg())
+}
+
+// Mul multiplica de forma exacta hasta MaxPlaces decimales; si el producto
+// tiene más, se redondea a MaxPlaces
+func (d Decimal) Mul(o Decimal) Decimal {
+	product := Decimal{coef: new(big.Int).Mul(d.int(), o.int()), places: d.places + o.places}
+	if product.places > MaxPlaces {
+		return product.Round(MaxPlaces)
+	}
+	return product
+}
+
+// Div divide redondeando a MaxPlaces decimales. Los ceros finales se
+// descartan hasta los decimales del dividendo.
+func (d Decimal) Div(o Decimal) Decimal {
+	if o.IsZero() {
+		panic("decimal: division by zero")
+	}
+
+	// d/o = (d.coef × 10^(o.places + MaxPlaces)) / (o.coef × 10^d.places) × 10^-MaxPlaces
+	num := new(big.Int).Mul(d.int(), pow10(o.places+MaxPlaces))
+	den := new(big.Int).Mul(o.int(), pow10(d.places))
+	quotient := Decimal{coef: divRound(num, den), places: MaxPlaces}
+
+	return quotient.trim(min(d.places, MaxPlaces))
+}
+
+// Round redondea a places decimales al valor más cercano; las mitades se
+// alejan de cero (redondeo aritmético). Con menos decimales, los completa
+// con ceros: Round(2) de 236 es 236.00.
+func (d Decimal) Round(places int32) Decimal {
+	if places >= d.places {
+		return Decimal{coef: d.rescale(places), places: places}
+	}
+	return Decimal{coef: divRound(d.int(), pow10(d.places-places)), places: places}
+}
+
+// Exact indica si el número no tiene más de places decimales significativos
+func (d Decimal) Exact(places int32) bool {
+	return d.Round(places).Cmp(d) == 0
+}
+
+// trim descarta los ceros finales sin bajar de minPlaces decimales
+func (d Decimal) trim(minPlaces int32) Decimal {
+	coef := new(big.Int).Set(d.int())
+	places := d.places
+	ten := big.NewInt(10)
+	rem := new(big.Int)
+	for places > minPlaces {
+		q, r := new(big.Int).QuoRem(coef, ten, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		coef = q
+		places--
+	}
+	return Decimal{coef: coef, places: places}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.int()), places: d.places}
+}
+
+// Cmp compara los valores: -1 si d < o, 0 si son iguales y +1 si d > o
+func (d Decimal) Cmp(o Decimal) int {
+	places := max(d.places, o.places)
+	return d.rescale(places).Cmp(o.rescale(places))
+}
+
+func (d Decimal) Equal(o Decimal) bool {
+	return d.Cmp(o) == 0
+}
+
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// IntPart devuelve la parte entera, truncada hacia cero
+func (d Decimal) IntPart() int64 {
+	return new(big.Int).Quo(d.int(), pow10(d.places)).Int64()
+}
+
+// Places devuelve los decimales con que se muestra el número
+func (d Decimal) Places() int32 {
+	return d.places
+}
+
+// String muestra el número con sus decimales: "236.00", "-0.5"
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.places > 0 {
+		if pad := int(d.places) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		cut := len(digits) - int(d.places)
+		digits = digits[:cut] + "." + digits[cut:]
+	}
+	if d.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON escribe el número sin comillas para que la API siga
+// respondiendo números
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON acepta un número o un texto con el número, sin pasar por
+// float64
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		unquoted, err := strconv.Unquote(string(data))
+		if err != nil {
+			return fmt.Errorf("invalid decimal %s", data)
+		}
+		data = []byte(unquoted)
+	}
+
+	parsed, err := Parse(string(data))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalText permite usar Decimal como contenido de un elemento XML
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(data []byte) error {
+	parsed, err := Parse(string(data))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Sum suma los valores
+func Sum(values ...Decimal) Decimal {
+	total := Zero
+	for _, value := range values {
+		total = total.Add(value)
+	}
+	return total
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// divRound divide num entre den redondeando la mitad lejos de cero
+func divRound(num, den *big.Int) *big.Int {
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return quo
+	}
+
+	// |2·rem| >= |den| redondea hacia afuera
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(new(big.Int).Abs(den)) >= 0 {
+		if (num.Sign() < 0) != (den.Sign() < 0) {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return quo
+}
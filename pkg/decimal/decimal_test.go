@@ -0,0 +1,91 @@
+package decimal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"236", "236"},
+		{"236.00", "236.00"},
+		{"-0.18", "-0.18"},
+		{"1.5e3", "1500"},
+		{"15e-1", "1.5"},
+		{"0.1234567890", "0.1234567890"},
+		{"1.500000000000000", "1.5000000000"},
+		{"123456789012345678901234567890", "123456789012345678901234567890"},
+		{"1e29", "100000000000000000000000000000"},
+		{"1e-10", "0.0000000001"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsOutOfRange(t *testing.T) {
+	inputs := []string{
+		"1e20000000",
+		"1e-20000000",
+		"-1e2147483647",
+		"1e30",
+		"1234567890123456789012345678901",
+		"0.12345678901",
+		"1e-11",
+		"0." + strings.Repeat("0", 100) + "1",
+		strings.Repeat("9", 1<<20),
+		"",
+		"abc",
+		"1.2.3",
+		"1e",
+	}
+	for _, in := range inputs {
+		start := time.Now()
+		if d, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %s, want error", in, d)
+		}
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("Parse(%q) took %s", in, elapsed)
+		}
+	}
+}
+
+func TestUnmarshalJSONRejectsHugeExponent(t *testing.T) {
+	var d Decimal
+	if err := json.Unmarshal([]byte(`1e20000000`), &d); err == nil {
+		t.Fatalf("Unmarshal accepted %s", d)
+	}
+	if err := json.Unmarshal([]byte(`"1e20000000"`), &d); err == nil {
+		t.Fatalf("Unmarshal accepted %s", d)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int32
+		want   string
+	}{
+		{"2.345", 2, "2.35"},
+		{"-2.345", 2, "-2.35"},
+		{"2.344", 2, "2.34"},
+		{"236", 2, "236.00"},
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.in).Round(tt.places); got.String() != tt.want {
+			t.Errorf("Round(%s, %d) = %s, want %s", tt.in, tt.places, got, tt.want)
+		}
+	}
+}
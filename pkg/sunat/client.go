@@ -0,0 +1,351 @@
+// Package sunat implementa las operaciones SOAP del billService de SUNAT
+// (sendBill, sendSummary, getStatus y getStatusCdr) y los adaptadores de los
+// OSE que exponen el mismo servicio
+package sunat
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client envía comprobantes y resúmenes al billService de SUNAT o de un OSE
+type Client struct {
+	RUC      string
+	Username string
+	Password string
+	Endpoint string
+	// ConsultEndpoint atiende getStatusCdr; vacío usa Endpoint
+	ConsultEndpoint string
+	Provider        Provider
+	HTTPClient      *http.Client
+}
+
+func NewClient(ruc, username, password, endpoint string) *Client {
+	return &Client{
+		RUC:        ruc,
+		Username:   username,
+		Password:   password,
+		Endpoint:   endpoint,
+		Provider:   DirectProvider,
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Name identifica el servicio al que envía el cliente
+func (c *Client) Name() string {
+	return c.Provider.Name
+}
+
+// Fault es un SOAP Fault devuelto por SUNAT. Code contiene el código de
+// retorno (por ejemplo "0151") sin el prefijo soap-env:Client.
+type Fault struct {
+	Code    string
+	Message string
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("SUNAT fault %s: %s", f.Code, f.Message)
+}
+
+// StatusError es una respuesta HTTP de error sin SOAP Fault
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d from SUNAT", e.StatusCode)
+}
+
+// Retryable indica si vale la pena reenviar tras el error: fallas de red,
+// timeouts, respuestas 5xx y las excepciones de indisponibilidad del
+// catálogo. Los demás SOAP Fault y las respuestas 4xx se repetirían igual.
+func Retryable(err error) bool {
+	var fault *Fault
+	if errors.As(err, &fault) {
+		return LookupCode(fault.Code).Retryable
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	return true
+}
+
+// FaultDuplicate es la respuesta de sendBill a un comprobante que SUNAT ya
+// registró; su CDR se obtiene con GetStatusCdr
+const FaultDuplicate = "1033"
+
+// IsDuplicate indica si el error es el rechazo de un comprobante ya
+// registrado
+func IsDuplicate(err error) bool {
+	var fault *Fault
+	return errors.As(err, &fault) && fault.Code == FaultDuplicate
+}
+
+// SendBill envía un comprobante ya comprimido y devuelve el CDR comprimido
+func (c *Client) SendBill(fileName string, zipContent []byte) ([]byte, error) {
+	body := fmt.Sprintf(`<ser:sendBill>
+      <fileName>%s</fileName>
+      <contentFile>%s</contentFile>
+    </ser:sendBill>`, escape(fileName), base64.StdEncoding.EncodeToString(zipContent))
+
+	values, err := c.call(body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, element := range c.Provider.CDRElements {
+		if content := values[element]; content != "" {
+			cdr, err := base64.StdEncoding.DecodeString(content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode CDR content: %w", err)
+			}
+			return cdr, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s response does not contain a CDR", c.Name())
+}
+
+// SendSummary envía un resumen diario (RC) o una comunicación de baja (RA)
+// ya comprimida y devuelve el ticket asignado por SUNAT
+func (c *Client) SendSummary(fileName string, zipContent []byte) (string, error) {
+	body := fmt.Sprintf(`<ser:sendSummary>
+      <fileName>%s</fileName>
+      <contentFile>%s</contentFile>
+    </ser:sendSummary>`, escape(fileName), base64.StdEncoding.EncodeToString(zipContent))
+
+	values, err := c.call(body)
+	if err != nil {
+		return "", err
+	}
+
+	ticket := values["ticket"]
+	if ticket == "" {
+		return "", fmt.Errorf("SUNAT response does not contain a ticket")
+	}
+
+	return ticket, nil
+}
+
+// Códigos de estado de getStatus
+const (
+	StatusProcessed       = "0"  // Procesado correctamente
+	StatusInProgress      = "98" // En proceso
+	StatusProcessedErrors = "99" // Procesado con errores
+)
+
+// TicketStatus es la respuesta de getStatus. Content contiene el CDR
+// comprimido cuando SUNAT ya procesó el ticket.
+type TicketStatus struct {
+	Code    string
+	Content []byte
+}
+
+// GetStatus consulta el estado de un ticket devuelto por sendSummary
+func (c *Client) GetStatus(ticket string) (*TicketStatus, error) {
+	body := fmt.Sprintf(`<ser:getStatus>
+      <ticket>%s</ticket>
+    </ser:getStatus>`, escape(ticket))
+
+	values, err := c.call(body)
+	if err != nil {
+		return nil, err
+	}
+
+	code, ok := values["statusCode"]
+	if !ok {
+		return nil, fmt.Errorf("SUNAT response does not contain a status code")
+	}
+
+	status := &TicketStatus{Code: code}
+	if content := values["content"]; content != "" {
+		status.Content, err = base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode CDR content: %w", err)
+		}
+	}
+
+	return status, nil
+}
+
+// Códigos de estado de getStatusCdr
+const (
+	CDRStatusExists   = "0004" // La constancia existe
+	CDRStatusNotFound = "0011" // El comprobante no existe
+)
+
+// CDRStatus es la respuesta de getStatusCdr. Content contiene el CDR
+// comprimido cuando la constancia existe.
+type CDRStatus struct {
+	Code    string
+	Message string
+	Content []byte
+}
+
+// GetStatusCdr consulta el CDR de un comprobante del emisor ya enviado
+func (c *Client) GetStatusCdr(docType, serie, number string) (*CDRStatus, error) {
+	body := fmt.Sprintf(`<ser:getStatusCdr>
+      <rucComprobante>%s</rucComprobante>
+      <tipoComprobante>%s</tipoComprobante>
+      <serieComprobante>%s</serieComprobante>
+      <numeroComprobante>%s</numeroComprobante>
+    </ser:getStatusCdr>`, escape(c.RUC), escape(docType), escape(serie), escape(number))
+
+	endpoint := c.ConsultEndpoint
+	if endpoint == "" {
+		endpoint = c.Endpoint
+	}
+
+	values, err := c.post(endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	code, ok := values["statusCode"]
+	if !ok {
+		return nil, fmt.Errorf("SUNAT response does not contain a status code")
+	}
+
+	status := &CDRStatus{Code: code, Message: values["statusMessage"]}
+	if content := values["content"]; content != "" {
+		status.Content, err = base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode CDR content: %w", err)
+		}
+	}
+
+	return status, nil
+}
+
+// call envía el cuerpo dentro del sobre SOAP con WS-Security y devuelve el
+// texto de cada elemento de la respuesta indexado por su nombre local
+func (c *Client) call(body string) (map[string]string, error) {
+	return c.post(c.Endpoint, body)
+}
+
+func (c *Client) post(endpoint, body string) (map[string]string, error) {
+	envelope := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:ser="http://service.sunat.gob.pe" xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
+  <soapenv:Header>
+    <wsse:Security>
+      <wsse:UsernameToken>
+        <wsse:Username>%s</wsse:Username>
+        <wsse:Password>%s</wsse:Password>
+      </wsse:UsernameToken>
+    </wsse:Security>
+  </soapenv:Header>
+  <soapenv:Body>
+    %s
+  </soapenv:Body>
+</soapenv:Envelope>`, escape(c.username()), escape(c.Password), body)
+
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(envelope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", "")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	values, err := parseResponse(data)
+	if err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, &StatusError{StatusCode: resp.StatusCode}
+		}
+		return nil, fmt.Errorf("failed to parse SUNAT response (HTTP %d): %w", resp.StatusCode, err)
+	}
+
+	if faultString, ok := values["faultstring"]; ok {
+		return nil, &Fault{Code: faultCode(values["faultcode"]), Message: faultString}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	return values, nil
+}
+
+// escape convierte un valor en texto XML válido para el sobre: una contraseña
+// o un nombre de archivo con <, & o comillas no puede alterar su estructura
+func escape(value string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
+
+// username devuelve el usuario en el formato que espera el proveedor: RUC
+// seguido del usuario SOL, salvo que el proveedor lo entregue completo
+func (c *Client) username() string {
+	if c.Provider.RawUsername {
+		return c.Username
+	}
+	return c.RUC + c.Username
+}
+
+// parseResponse recorre el XML y guarda el texto de los elementos hoja
+func parseResponse(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var current string
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			current = t.Name.Local
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if t.Name.Local == current {
+				if _, exists := values[current]; !exists {
+					values[current] = strings.TrimSpace(text.String())
+				}
+			}
+			current = ""
+		}
+	}
+
+	return values, nil
+}
+
+// faultCode extrae el código numérico de "soap-env:Client.0151"
+func faultCode(code string) string {
+	if i := strings.LastIndex(code, "."); i != -1 {
+		return code[i+1:]
+	}
+	if i := strings.LastIndex(code, ":"); i != -1 {
+		return code[i+1:]
+	}
+	return code
+}
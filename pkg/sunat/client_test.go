@@ -0,0 +1,72 @@
+package sunat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"infac/pkg/sunat/fake"
+)
+
+const testRUC = "20612790168"
+
+func newTestClient(t *testing.T, username, password string) (*Client, *fake.Server) {
+	t.Helper()
+
+	server := fake.NewServer()
+	server.Username, server.Password = username, password
+	httpServer := server.StartHTTPTest()
+	t.Cleanup(httpServer.Close)
+
+	return NewClient(testRUC, username, password, httpServer.URL), server
+}
+
+// Las credenciales con caracteres especiales de XML llegan intactas
+func TestClientEscapesCredentials(t *testing.T) {
+	client, server := newTestClient(t, `MOD<DATOS>`, `p&ss"wo'rd</wsse:Password>`)
+
+	_, err := client.GetStatus("123")
+	var fault *Fault
+	if !errors.As(err, &fault) || fault.Code != "0127" {
+		t.Fatalf("GetStatus = %v, want fault 0127 (ticket not found)", err)
+	}
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Username != testRUC+`MOD<DATOS>` {
+		t.Errorf("requests = %+v", requests)
+	}
+}
+
+func TestClientEscapesParameters(t *testing.T) {
+	client, server := newTestClient(t, "MODDATOS", "moddatos")
+
+	ticket := `1</ticket><ticket>2`
+	if _, err := client.GetStatus(ticket); err == nil {
+		t.Fatal("GetStatus succeeded for an unknown ticket")
+	}
+	_, err := client.SendBill(`F001&1.zip`, []byte("zip"))
+	var fault *Fault
+	if !errors.As(err, &fault) || fault.Code != "0151" {
+		t.Errorf("SendBill = %v, want fault 0151 (invalid file name)", err)
+	}
+	if _, err := client.GetStatusCdr("01", "F<01", "1"); err != nil {
+		t.Errorf("GetStatusCdr: %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("server received %d requests, want 3", len(requests))
+	}
+	if requests[0].Ticket != ticket {
+		t.Errorf("ticket = %q, want %q", requests[0].Ticket, ticket)
+	}
+	if requests[1].FileName != `F001&1.zip` {
+		t.Errorf("file name = %q", requests[1].FileName)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	got := escape(`<a href="x">&'`)
+	if strings.ContainsAny(got, `<>"'`) || strings.Contains(got, "&'") {
+		t.Errorf("escape = %q", got)
+	}
+}
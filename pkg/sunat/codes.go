@@ -0,0 +1,104 @@
+package sunat
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Category clasifica los códigos de retorno de SUNAT según su rango
+type Category string
+
+const (
+	// CategoryException (0100-1999): el comprobante no llegó a procesarse y
+	// se puede volver a enviar una vez corregido
+	CategoryException Category = "exception"
+	// CategoryRejection (2000-3999): el comprobante fue rechazado
+	CategoryRejection Category = "rejection"
+	// CategoryObservation (4000 en adelante): el comprobante fue aceptado
+	// con observaciones
+	CategoryObservation Category = "observation"
+)
+
+// Code es un código de retorno de SUNAT con su clasificación. Field indica
+// qué corregir: un campo del request (customer.document_number) o una
+// clave de la configuración (sunat.username).
+type Code struct {
+	Code        string   `json:"code"`
+	Description string   `json:"description"`
+	Category    Category `json:"category"`
+	Retryable   bool     `json:"retryable"`
+	Field       string   `json:"field,omitempty"`
+}
+
+//go:embed codes.csv
+var codesCSV string
+
+var codes = loadCodes()
+
+// loadCodes lee el catálogo embebido: code,retryable,field,description
+func loadCodes() map[string]Code {
+	records, err := csv.NewReader(strings.NewReader(codesCSV)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("invalid SUNAT code catalog: %v", err))
+	}
+
+	catalog := make(map[string]Code, len(records))
+	for _, record := range records[1:] {
+		retryable, err := strconv.ParseBool(record[1])
+		if err != nil {
+			panic(fmt.Sprintf("invalid SUNAT code catalog: code %s: %v", record[0], err))
+		}
+		catalog[record[0]] = Code{
+			Code:        record[0],
+			Description: record[3],
+			Category:    categoryOf(record[0]),
+			Retryable:   retryable,
+			Field:       record[2],
+		}
+	}
+	return catalog
+}
+
+// LookupCode devuelve el código del catálogo. Un código que no está en el
+// catálogo se clasifica por su rango, sin descripción.
+func LookupCode(code string) Code {
+	if c, ok := codes[code]; ok {
+		return c
+	}
+	return Code{Code: code, Category: categoryOf(code)}
+}
+
+// categoryOf clasifica el código por su rango. El código 0 del CDR es una
+// aceptación y se trata como observación.
+func categoryOf(code string) Category {
+	n, err := strconv.Atoi(code)
+	switch {
+	case err != nil, n < 2000 && n != 0:
+		return CategoryException
+	case n < 4000 && n != 0:
+		return CategoryRejection
+	default:
+		return CategoryObservation
+	}
+}
+
+// Hint sugiere qué hacer ante el código
+func (c Code) Hint() string {
+	switch {
+	case c.Retryable:
+		return "El servicio no está disponible; reintente el envío más tarde"
+	case c.Category == CategoryObservation:
+		return "El comprobante fue aceptado; corrija la observación en los siguientes comprobantes"
+	case c.Field != "" && c.Category == CategoryRejection:
+		return fmt.Sprintf("Corrija %s y emita el comprobante nuevamente", c.Field)
+	case c.Field != "":
+		return fmt.Sprintf("Corrija %s y vuelva a enviar el comprobante", c.Field)
+	case c.Category == CategoryRejection:
+		return "El comprobante fue rechazado; corríjalo y emítalo nuevamente"
+	default:
+		return "El comprobante no fue procesado; corrija el envío y vuelva a enviarlo"
+	}
+}
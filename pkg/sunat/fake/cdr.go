@@ -0,0 +1,177 @@
+package fake
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Duration permite escribir las demoras como "30s" en los scripts JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// readZip valida el contenido del zip: un único XML con el mismo nombre que
+// el zip
+func readZip(contentFile, name string) ([]byte, *soapFault) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(contentFile))
+	if err != nil || len(data) == 0 {
+		return nil, &soapFault{"0155", "El archivo ZIP esta vacio"}
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, &soapFault{"0156", "El archivo ZIP esta corrupto"}
+	}
+
+	var xmlFile *zip.File
+	for _, file := range reader.File {
+		if strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+		if xmlFile != nil {
+			return nil, &soapFault{"0157", "El archivo ZIP contiene demasiados archivos"}
+		}
+		xmlFile = file
+	}
+	if xmlFile == nil {
+		return nil, &soapFault{"0155", "El archivo ZIP esta vacio"}
+	}
+	if xmlFile.Name != name+".xml" {
+		return nil, &soapFault{"0161", "El nombre del archivo XML no coincide con el nombre del archivo ZIP"}
+	}
+
+	f, err := xmlFile.Open()
+	if err != nil {
+		return nil, &soapFault{"0156", "El archivo ZIP esta corrupto"}
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, &soapFault{"0156", "El archivo ZIP esta corrupto"}
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, &soapFault{"0160", "El archivo XML esta vacio"}
+	}
+
+	return content, nil
+}
+
+// documentID lee el cbc:ID de la raíz del comprobante o resumen
+func documentID(content []byte) string {
+	var doc struct {
+		ID string `xml:"ID"`
+	}
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.ID)
+}
+
+// buildCDR arma el zip R-<name>.zip con la ApplicationResponse
+func buildCDR(name, documentID, description string, behavior Behavior) ([]byte, error) {
+	code := behavior.ResponseCode
+	if code == "" {
+		code = "0"
+	}
+	if !accepted(code) {
+		description = fmt.Sprintf("El comprobante %s ha sido rechazado", documentID)
+	}
+	if behavior.Description != "" {
+		description = behavior.Description
+	}
+
+	now := time.Now().In(time.FixedZone("PET", -5*60*60))
+
+	var notes strings.Builder
+	for _, observation := range behavior.Observations {
+		notes.WriteString("\n  <cbc:Note>")
+		xml.EscapeText(&notes, []byte(observation))
+		notes.WriteString("</cbc:Note>")
+	}
+
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(description))
+
+	applicationResponse := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<ar:ApplicationResponse xmlns:ar="urn:oasis:names:specification:ubl:schema:xsd:ApplicationResponse-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2" xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2">
+  <ext:UBLExtensions>
+    <ext:UBLExtension>
+      <ext:ExtensionContent/>
+    </ext:UBLExtension>
+  </ext:UBLExtensions>
+  <cbc:UBLVersionID>2.0</cbc:UBLVersionID>
+  <cbc:CustomizationID>1.0</cbc:CustomizationID>
+  <cbc:ID>%d</cbc:ID>
+  <cbc:IssueDate>%s</cbc:IssueDate>
+  <cbc:IssueTime>%s</cbc:IssueTime>
+  <cbc:ResponseDate>%s</cbc:ResponseDate>
+  <cbc:ResponseTime>%s</cbc:ResponseTime>%s
+  <cac:SenderParty>
+    <cac:PartyIdentification>
+      <cbc:ID>20131312955</cbc:ID>
+    </cac:PartyIdentification>
+  </cac:SenderParty>
+  <cac:ReceiverParty>
+    <cac:PartyIdentification>
+      <cbc:ID>6-%s</cbc:ID>
+    </cac:PartyIdentification>
+  </cac:ReceiverParty>
+  <cac:DocumentResponse>
+    <cac:Response>
+      <cbc:ReferenceID>%s</cbc:ReferenceID>
+      <cbc:ResponseCode>%s</cbc:ResponseCode>
+      <cbc:Description>%s</cbc:Description>
+    </cac:Response>
+    <cac:DocumentReference>
+      <cbc:ID>%s</cbc:ID>
+    </cac:DocumentReference>
+  </cac:DocumentResponse>
+</ar:ApplicationResponse>
+`, now.UnixNano(), now.Format("2006-01-02"), now.Format("15:04:05"), now.Format("2006-01-02"), now.Format("15:04:05"),
+		notes.String(), name[:11], documentID, code, escaped.String(), documentID)
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	if _, err := w.Create("dummy/"); err != nil {
+		return nil, err
+	}
+	f, err := w.Create("R-" + name + ".xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write([]byte(applicationResponse)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encode(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
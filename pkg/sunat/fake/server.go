@@ -0,0 +1,480 @@
+// Package fake implementa un billService de SUNAT en memoria (sendBill,
+// sendSummary, getStatus y getStatusCdr) para desarrollo y pruebas sin
+// conexión. Valida el sobre SOAP, las credenciales y el nombre de los zip, y
+// responde con CDR realistas. Las respuestas se pueden programar por nombre de
+// archivo para simular rechazos, excepciones y demoras.
+package fake
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	billNamePattern    = regexp.MustCompile(`^(\d{11})-(01|03|07|08)-([A-Z0-9]{4})-(\d{1,8})$`)
+	summaryNamePattern = regexp.MustCompile(`^(\d{11})-(RA|RC|RR)-(\d{8})-(\d{1,5})$`)
+)
+
+// Behavior define cómo responde el servidor a un envío
+type Behavior struct {
+	// ResponseCode es el código del CDR: "0" aceptado, 2000-3999 rechazo.
+	// Vacío equivale a "0".
+	ResponseCode string `json:"response_code,omitempty"`
+	// Description reemplaza la descripción generada del CDR
+	Description string `json:"description,omitempty"`
+	// Observations se agregan como cbc:Note ("4252 - mensaje")
+	Observations []string `json:"observations,omitempty"`
+	// FaultCode responde con un SOAP Fault (excepción) en vez de un CDR
+	FaultCode    string `json:"fault_code,omitempty"`
+	FaultMessage string `json:"fault_message,omitempty"`
+	// Delay retrasa la respuesta, para simular timeouts
+	Delay Duration `json:"delay,omitempty"`
+	// Polls es la cantidad de consultas getStatus que responden "98" (en
+	// proceso) antes del resultado final
+	Polls int `json:"polls,omitempty"`
+}
+
+// Rule asocia un comportamiento a los archivos cuyo nombre (sin .zip)
+// coincide con Pattern, según path.Match ("*-01-F001-*")
+type Rule struct {
+	Pattern  string   `json:"pattern"`
+	Behavior Behavior `json:"behavior"`
+	// Times limita la cantidad de usos de la regla (0 = ilimitada)
+	Times int `json:"times,omitempty"`
+}
+
+// Request es una llamada recibida, para inspeccionarla en las pruebas
+type Request struct {
+	Operation string
+	Username  string
+	FileName  string
+	Ticket    string
+	At        time.Time
+}
+
+type ticketState struct {
+	name     string
+	behavior Behavior
+	polls    int
+}
+
+// Server es un http.Handler que simula el billService
+type Server struct {
+	// Credenciales SOL aceptadas; vacías aceptan cualquier usuario
+	Username string
+	Password string
+
+	mu         sync.Mutex
+	rules      []*Rule
+	tickets    map[string]*ticketState
+	cdrs       map[string][]byte
+	requests   []Request
+	nextTicket int64
+}
+
+func NewServer() *Server {
+	return &Server{
+		tickets:    make(map[string]*ticketState),
+		cdrs:       make(map[string][]byte),
+		nextTicket: time.Now().UnixMilli(),
+	}
+}
+
+// StartHTTPTest levanta el servidor en un httptest.Server; su URL se usa
+// como endpoint del cliente
+func (s *Server) StartHTTPTest() *httptest.Server {
+	return httptest.NewServer(s)
+}
+
+// On programa la respuesta para los archivos que coinciden con pattern. Las
+// reglas se evalúan en el orden en que se agregaron.
+func (s *Server) On(pattern string, behavior Behavior) *Rule {
+	return s.AddRule(Rule{Pattern: pattern, Behavior: behavior})
+}
+
+func (s *Server) AddRule(rule Rule) *Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := rule
+	s.rules = append(s.rules, &r)
+	return &r
+}
+
+// Reset elimina reglas, tickets, CDR y llamadas registradas
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules = nil
+	s.tickets = make(map[string]*ticketState)
+	s.cdrs = make(map[string][]byte)
+	s.requests = nil
+}
+
+// Requests devuelve las llamadas recibidas
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// envelope es el sobre SOAP enviado por los clientes
+type envelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Header  struct {
+		Security struct {
+			UsernameToken struct {
+				Username string `xml:"Username"`
+				Password string `xml:"Password"`
+			} `xml:"UsernameToken"`
+		} `xml:"Security"`
+	} `xml:"Header"`
+	Body struct {
+		Content []byte `xml:",innerxml"`
+	} `xml:"Body"`
+}
+
+// operation reúne los parámetros de todas las operaciones del servicio
+type operation struct {
+	XMLName           xml.Name
+	FileName          string `xml:"fileName"`
+	ContentFile       string `xml:"contentFile"`
+	Ticket            string `xml:"ticket"`
+	RucComprobante    string `xml:"rucComprobante"`
+	TipoComprobante   string `xml:"tipoComprobante"`
+	SerieComprobante  string `xml:"serieComprobante"`
+	NumeroComprobante string `xml:"numeroComprobante"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeFault(w, "0200", "No se pudo procesar su solicitud")
+		return
+	}
+
+	var env envelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		writeFault(w, "0306", "No se puede leer (parsear) el archivo XML")
+		return
+	}
+
+	var op operation
+	if err := xml.Unmarshal(bytes.TrimSpace(env.Body.Content), &op); err != nil {
+		writeFault(w, "0200", "No se pudo procesar su solicitud")
+		return
+	}
+
+	username := strings.TrimSpace(env.Header.Security.UsernameToken.Username)
+	if fault := s.authenticate(username, env.Header.Security.UsernameToken.Password); fault != nil {
+		s.record(op, username)
+		writeFault(w, fault.code, fault.message)
+		return
+	}
+
+	s.record(op, username)
+
+	var (
+		response string
+		behavior Behavior
+		fault    *soapFault
+	)
+	switch op.XMLName.Local {
+	case "sendBill":
+		response, behavior, fault = s.sendBill(op, username)
+	case "sendSummary":
+		response, behavior, fault = s.sendSummary(op, username)
+	case "getStatus":
+		response, behavior, fault = s.getStatus(op)
+	case "getStatusCdr":
+		response, fault = s.getStatusCdr(op)
+	default:
+		fault = &soapFault{"0200", fmt.Sprintf("Operación no soportada: %s", op.XMLName.Local)}
+	}
+
+	if behavior.Delay > 0 {
+		select {
+		case <-time.After(time.Duration(behavior.Delay)):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if fault != nil {
+		writeFault(w, fault.code, fault.message)
+		return
+	}
+
+	writeResponse(w, response)
+}
+
+type soapFault struct {
+	code    string
+	message string
+}
+
+func (s *Server) authenticate(username, password string) *soapFault {
+	if len(username) <= 11 {
+		return &soapFault{"0101", "El encabezado de seguridad es incorrecto"}
+	}
+	if _, err := strconv.ParseUint(username[:11], 10, 64); err != nil {
+		return &soapFault{"0101", "El encabezado de seguridad es incorrecto"}
+	}
+	if s.Username != "" && (username[11:] != s.Username || password != s.Password) {
+		return &soapFault{"0102", "Usuario o contraseña incorrectos"}
+	}
+	return nil
+}
+
+func (s *Server) record(op operation, username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Operation: op.XMLName.Local,
+		Username:  username,
+		FileName:  op.FileName,
+		Ticket:    op.Ticket,
+		At:        time.Now(),
+	})
+}
+
+// behaviorFor devuelve la primera regla vigente que coincide con el nombre
+func (s *Server) behaviorFor(name string) Behavior {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, rule := range s.rules {
+		if matched, _ := path.Match(rule.Pattern, name); !matched {
+			continue
+		}
+		if rule.Times > 0 {
+			rule.Times--
+			if rule.Times == 0 {
+				s.rules = append(s.rules[:i:i], s.rules[i+1:]...)
+			}
+		}
+		return rule.Behavior
+	}
+
+	return Behavior{}
+}
+
+func (s *Server) sendBill(op operation, username string) (string, Behavior, *soapFault) {
+	name := strings.TrimSuffix(op.FileName, ".zip")
+	behavior := s.behaviorFor(name)
+
+	if summaryNamePattern.MatchString(name) {
+		return "", behavior, &soapFault{"0152", "No se puede enviar por este método un archivo de resumen"}
+	}
+	match := billNamePattern.FindStringSubmatch(name)
+	if match == nil || !strings.HasSuffix(op.FileName, ".zip") {
+		return "", behavior, &soapFault{"0151", "El nombre del archivo ZIP es incorrecto"}
+	}
+	if match[1] != username[:11] {
+		return "", behavior, &soapFault{"0154", "El RUC del archivo no corresponde al RUC del usuario"}
+	}
+
+	content, fault := readZip(op.ContentFile, name)
+	if fault != nil {
+		return "", behavior, fault
+	}
+	if id := documentID(content); id != fmt.Sprintf("%s-%s", match[3], match[4]) {
+		return "", behavior, &soapFault{"1036", "El número del documento no coincide con el nombre del archivo"}
+	}
+
+	if behavior.FaultCode != "" {
+		return "", behavior, &soapFault{behavior.FaultCode, faultMessage(behavior)}
+	}
+
+	s.mu.Lock()
+	_, exists := s.cdrs[name]
+	s.mu.Unlock()
+	if exists {
+		return "", behavior, &soapFault{"1033", "El comprobante fue registrado previamente con otros datos"}
+	}
+
+	cdr, err := buildCDR(name, fmt.Sprintf("%s-%s", match[3], match[4]), billDescription(match[2], match[3], match[4]), behavior)
+	if err != nil {
+		return "", behavior, &soapFault{"0200", err.Error()}
+	}
+
+	if accepted(behavior.ResponseCode) {
+		s.mu.Lock()
+		s.cdrs[name] = cdr
+		s.mu.Unlock()
+	}
+
+	return fmt.Sprintf(`<br:sendBillResponse xmlns:br="http://service.sunat.gob.pe"><applicationResponse>%s</applicationResponse></br:sendBillResponse>`,
+		encode(cdr)), behavior, nil
+}
+
+func (s *Server) sendSummary(op operation, username string) (string, Behavior, *soapFault) {
+	name := strings.TrimSuffix(op.FileName, ".zip")
+	behavior := s.behaviorFor(name)
+
+	if billNamePattern.MatchString(name) {
+		return "", behavior, &soapFault{"0153", "No se puede enviar por este método un archivo de factura, boleta o nota"}
+	}
+	match := summaryNamePattern.FindStringSubmatch(name)
+	if match == nil || !strings.HasSuffix(op.FileName, ".zip") {
+		return "", behavior, &soapFault{"0151", "El nombre del archivo ZIP es incorrecto"}
+	}
+	if match[1] != username[:11] {
+		return "", behavior, &soapFault{"0154", "El RUC del archivo no corresponde al RUC del usuario"}
+	}
+
+	content, fault := readZip(op.ContentFile, name)
+	if fault != nil {
+		return "", behavior, fault
+	}
+	if id := documentID(content); id != fmt.Sprintf("%s-%s-%s", match[2], match[3], match[4]) {
+		return "", behavior, &soapFault{"1036", "El identificador del resumen no coincide con el nombre del archivo"}
+	}
+
+	if behavior.FaultCode != "" {
+		return "", behavior, &soapFault{behavior.FaultCode, faultMessage(behavior)}
+	}
+
+	s.mu.Lock()
+	s.nextTicket++
+	ticket := strconv.FormatInt(s.nextTicket, 10)
+	s.tickets[ticket] = &ticketState{name: name, behavior: behavior}
+	s.mu.Unlock()
+
+	return fmt.Sprintf(`<br:sendSummaryResponse xmlns:br="http://service.sunat.gob.pe"><ticket>%s</ticket></br:sendSummaryResponse>`,
+		ticket), behavior, nil
+}
+
+func (s *Server) getStatus(op operation) (string, Behavior, *soapFault) {
+	s.mu.Lock()
+	state, ok := s.tickets[strings.TrimSpace(op.Ticket)]
+	if ok && state.polls < state.behavior.Polls {
+		state.polls++
+		s.mu.Unlock()
+		return statusResponse("98", nil), Behavior{}, nil
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return "", Behavior{}, &soapFault{"0127", "El ticket no existe"}
+	}
+
+	summaryID := strings.SplitN(state.name, "-", 2)[1]
+	description := fmt.Sprintf("El Resumen diario %s, ha sido aceptado", summaryID)
+	if strings.HasPrefix(summaryID, "RA") {
+		description = fmt.Sprintf("La Comunicacion de baja %s, ha sido aceptada", summaryID)
+	}
+
+	cdr, err := buildCDR(state.name, summaryID, description, state.behavior)
+	if err != nil {
+		return "", Behavior{}, &soapFault{"0200", err.Error()}
+	}
+
+	code := "0"
+	if !accepted(state.behavior.ResponseCode) {
+		code = "99"
+	}
+
+	return statusResponse(code, cdr), Behavior{}, nil
+}
+
+func (s *Server) getStatusCdr(op operation) (string, *soapFault) {
+	if op.RucComprobante == "" || op.TipoComprobante == "" || op.SerieComprobante == "" || op.NumeroComprobante == "" {
+		return "", &soapFault{"0200", "Debe indicar RUC, tipo, serie y número del comprobante"}
+	}
+
+	number, err := strconv.Atoi(op.NumeroComprobante)
+	if err != nil {
+		return "", &soapFault{"0200", "El número del comprobante es incorrecto"}
+	}
+
+	s.mu.Lock()
+	var cdr []byte
+	for name, content := range s.cdrs {
+		match := billNamePattern.FindStringSubmatch(name)
+		if match == nil || match[1] != op.RucComprobante || match[2] != op.TipoComprobante || match[3] != op.SerieComprobante {
+			continue
+		}
+		if n, _ := strconv.Atoi(match[4]); n == number {
+			cdr = content
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if cdr == nil {
+		return `<br:getStatusCdrResponse xmlns:br="http://service.sunat.gob.pe"><statusCdr><statusCode>0011</statusCode><statusMessage>El comprobante de pago electrónico no existe</statusMessage></statusCdr></br:getStatusCdrResponse>`, nil
+	}
+
+	return fmt.Sprintf(`<br:getStatusCdrResponse xmlns:br="http://service.sunat.gob.pe"><statusCdr><content>%s</content><statusCode>0004</statusCode><statusMessage>La constancia existe</statusMessage></statusCdr></br:getStatusCdrResponse>`,
+		encode(cdr)), nil
+}
+
+func statusResponse(code string, cdr []byte) string {
+	content := ""
+	if cdr != nil {
+		content = fmt.Sprintf("<content>%s</content>", encode(cdr))
+	}
+	return fmt.Sprintf(`<br:getStatusResponse xmlns:br="http://service.sunat.gob.pe"><status>%s<statusCode>%s</statusCode></status></br:getStatusResponse>`,
+		content, code)
+}
+
+// accepted indica si el código programado corresponde a una aceptación
+func accepted(code string) bool {
+	if code == "" || code == "0" {
+		return true
+	}
+	n, err := strconv.Atoi(code)
+	return err == nil && n >= 4000
+}
+
+func faultMessage(behavior Behavior) string {
+	if behavior.FaultMessage != "" {
+		return behavior.FaultMessage
+	}
+	return "Error programado en el servidor de pruebas"
+}
+
+func billDescription(docType, serie, number string) string {
+	names := map[string]string{
+		"01": "La Factura",
+		"03": "La Boleta",
+		"07": "La Nota de Credito",
+		"08": "La Nota de Debito",
+	}
+	return fmt.Sprintf("%s numero %s-%s, ha sido aceptada", names[docType], serie, number)
+}
+
+func writeResponse(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Header/><soap-env:Body>%s</soap-env:Body></soap-env:Envelope>`, body)
+}
+
+func writeFault(w http.ResponseWriter, code, message string) {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(message))
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><soap-env:Envelope xmlns:soap-env="http://schemas.xmlsoap.org/soap/envelope/"><soap-env:Body><soap-env:Fault><faultcode>soap-env:Client.%s</faultcode><faultstring>%s</faultstring></soap-env:Fault></soap-env:Body></soap-env:Envelope>`,
+		code, escaped.String())
+}
@@ -0,0 +1,92 @@
+package sunat
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Provider describe las diferencias de un servicio compatible con el
+// billService de SUNAT: SUNAT directo o un OSE
+type Provider struct {
+	Name string
+	// BillServicePath se agrega a la URL configurada cuando esta no indica
+	// una ruta
+	BillServicePath string
+	// RawUsername indica que el usuario se envía tal como lo entrega el
+	// proveedor, sin anteponer el RUC del emisor
+	RawUsername bool
+	// CDRElements son los elementos de la respuesta de sendBill en los que
+	// puede venir el CDR, en orden de preferencia
+	CDRElements []string
+}
+
+// DirectProvider es el billService de SUNAT
+var DirectProvider = Provider{
+	Name:        "sunat",
+	CDRElements: []string{"applicationResponse"},
+}
+
+// providers son los adaptadores de OSE disponibles
+var providers = map[string]Provider{
+	"nubefact": {
+		Name:            "nubefact",
+		BillServicePath: "/ol-ti-itcpfegem/billService",
+		CDRElements:     []string{"applicationResponse"},
+	},
+	"efact": {
+		Name:            "efact",
+		BillServicePath: "/ol-ti-itcpe/billService",
+		RawUsername:     true,
+		CDRElements:     []string{"applicationResponse", "content"},
+	},
+	"bizlinks": {
+		Name:            "bizlinks",
+		BillServicePath: "/ol-ti-itcpfegem/billService",
+		CDRElements:     []string{"applicationResponse"},
+	},
+	// generic usa la URL configurada tal cual
+	"generic": {
+		Name:        "generic",
+		CDRElements: []string{"applicationResponse"},
+	},
+}
+
+// LookupProvider devuelve el adaptador del OSE indicado
+func LookupProvider(name string) (Provider, error) {
+	provider, ok := providers[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(providers))
+		for n := range providers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Provider{}, fmt.Errorf("unknown OSE provider %q (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	return provider, nil
+}
+
+// Endpoint arma la URL del billService a partir de la URL configurada
+func (p Provider) Endpoint(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid %s URL %q", p.Name, baseURL)
+	}
+	if strings.Trim(u.Path, "/") == "" && p.BillServicePath != "" {
+		u.Path = p.BillServicePath
+	}
+	return u.String(), nil
+}
+
+// NewProviderClient crea un cliente con las particularidades del proveedor
+func NewProviderClient(provider Provider, ruc, username, password, baseURL string) (*Client, error) {
+	endpoint, err := provider.Endpoint(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewClient(ruc, username, password, endpoint)
+	client.Provider = provider
+	return client, nil
+}
@@ -0,0 +1,71 @@
+package ubl
+
+import (
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// AllowanceCharge es un descuento (ChargeIndicator false) o un cargo de la
+// línea o del documento
+type AllowanceCharge struct {
+	ChargeIndicator           bool                      `xml:"cbc:ChargeIndicator"`
+	AllowanceChargeReasonCode AllowanceChargeReasonCode `xml:"cbc:AllowanceChargeReasonCode"`
+	MultiplierFactorNumeric   *decimal.Decimal          `xml:"cbc:MultiplierFactorNumeric,omitempty"`
+	Amount                    MonetaryAmount            `xml:"cbc:Amount"`
+	BaseAmount                *MonetaryAmount           `xml:"cbc:BaseAmount,omitempty"`
+}
+
+type AllowanceChargeReasonCode struct {
+	ListAgencyName string `xml:"listAgencyName,attr,omitempty"`
+	ListName       string `xml:"listName,attr,omitempty"`
+	ListURI        string `xml:"listURI,attr,omitempty"`
+	Value          string `xml:",chardata"`
+}
+
+// allowanceCharges convierte los descuentos y cargos del documento o de una
+// línea al formato UBL (catálogo 53)
+func allowanceCharges(doc *models.Document, items []models.AllowanceCharge) []AllowanceCharge {
+	var result []AllowanceCharge
+	for _, ac := range items {
+		result = append(result, AllowanceCharge{
+			ChargeIndicator: ac.Code.Charge(),
+			AllowanceChargeReasonCode: AllowanceChargeReasonCode{
+				ListAgencyName: "PE:SUNAT",
+				ListName:       "Cargo/descuento",
+				ListURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo53",
+				Value:          string(ac.Code),
+			},
+			MultiplierFactorNumeric: ac.Factor,
+			Amount:                  MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: ac.Amount},
+			BaseAmount:              &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: ac.BaseAmount},
+		})
+	}
+	return result
+}
+
+// legalMonetaryTotal arma los totales del comprobante. Los documentos
+// guardados antes de los descuentos y cargos no tienen los importes con y
+// sin impuestos: se usan el valor de venta y el importe total.
+func legalMonetaryTotal(doc *models.Document) LegalMonetaryTotal {
+	taxExclusive, taxInclusive := doc.TaxExclusiveAmount, doc.TaxInclusiveAmount
+	if taxInclusive.IsZero() {
+		taxExclusive, taxInclusive = doc.SubTotal, doc.TotalAmount
+	}
+
+	total := LegalMonetaryTotal{
+		LineExtensionAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: doc.SubTotal},
+		TaxExclusiveAmount:  MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: taxExclusive},
+		TaxInclusiveAmount:  MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: taxInclusive},
+		PayableAmount:       MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: doc.TotalAmount},
+	}
+	if !doc.TotalAllowances.IsZero() {
+		total.AllowanceTotalAmount = &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: doc.TotalAllowances}
+	}
+	if !doc.TotalCharges.IsZero() {
+		total.ChargeTotalAmount = &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: doc.TotalCharges}
+	}
+	if !doc.TotalPrepaid.IsZero() {
+		total.PrepaidAmount = &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: doc.TotalPrepaid}
+	}
+	return total
+}
@@ -0,0 +1,147 @@
+package ubl
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"infac/internal/models"
+)
+
+// sunatLocation es la zona horaria de las fechas del CDR (hora de Lima)
+var sunatLocation = time.FixedZone("PET", -5*60*60)
+
+// ApplicationResponse es la Constancia de Recepción (CDR) que devuelve SUNAT
+type ApplicationResponse struct {
+	XMLName          xml.Name         `xml:"ApplicationResponse"`
+	ID               string           `xml:"ID"`
+	IssueDate        string           `xml:"IssueDate"`
+	IssueTime        string           `xml:"IssueTime"`
+	ResponseDate     string           `xml:"ResponseDate"`
+	ResponseTime     string           `xml:"ResponseTime"`
+	Note             []string         `xml:"Note"`
+	DocumentResponse DocumentResponse `xml:"DocumentResponse"`
+}
+
+type DocumentResponse struct {
+	Response          Response                  `xml:"Response"`
+	DocumentReference ResponseDocumentReference `xml:"DocumentReference"`
+}
+
+type Response struct {
+	ReferenceID  string `xml:"ReferenceID"`
+	ResponseCode string `xml:"ResponseCode"`
+	Description  string `xml:"Description"`
+}
+
+type ResponseDocumentReference struct {
+	ID string `xml:"ID"`
+}
+
+// ParseCDR descomprime el CDR devuelto por SUNAT y lee su respuesta, el
+// documento al que corresponde, la fecha de respuesta y las observaciones
+func ParseCDR(zipContent []byte) (*models.CDR, error) {
+	content, err := readCDRXML(zipContent)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseApplicationResponse(content)
+}
+
+// ParseApplicationResponse lee el XML del CDR ya descomprimido
+func ParseApplicationResponse(content []byte) (*models.CDR, error) {
+	var response ApplicationResponse
+	if err := xml.Unmarshal(content, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse application response: %w", err)
+	}
+
+	result := response.DocumentResponse.Response
+	cdr := &models.CDR{
+		ResponseCode: strings.TrimSpace(result.ResponseCode),
+		Description:  strings.TrimSpace(result.Description),
+		DocumentID:   strings.TrimSpace(response.DocumentResponse.DocumentReference.ID),
+	}
+	if cdr.ResponseCode == "" {
+		return nil, fmt.Errorf("application response does not contain a response code")
+	}
+	if cdr.DocumentID == "" {
+		cdr.DocumentID = strings.TrimSpace(result.ReferenceID)
+	}
+
+	if date := responseDate(response.ResponseDate, response.ResponseTime); date != nil {
+		cdr.ResponseDate = date
+	} else {
+		cdr.ResponseDate = responseDate(response.IssueDate, response.IssueTime)
+	}
+
+	for _, note := range response.Note {
+		cdr.Observations = append(cdr.Observations, parseObservation(note))
+	}
+
+	return cdr, nil
+}
+
+// responseDate combina la fecha y hora del CDR; nil si no hay fecha válida
+func responseDate(date, clock string) *time.Time {
+	date = strings.TrimSpace(date)
+	clock = strings.TrimSpace(clock)
+	if date == "" {
+		return nil
+	}
+
+	if clock != "" {
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", date+" "+clock, sunatLocation); err == nil {
+			return &t
+		}
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", date, sunatLocation)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// parseObservation separa "4252 - El dato ingresado..." en código y mensaje
+func parseObservation(note string) models.CDRObservation {
+	note = strings.TrimSpace(note)
+
+	code, description, found := strings.Cut(note, " - ")
+	if !found || strings.Trim(code, "0123456789") != "" {
+		return models.CDRObservation{Description: note}
+	}
+
+	return models.CDRObservation{
+		Code:        code,
+		Description: strings.TrimSpace(description),
+	}
+}
+
+// readCDRXML devuelve el XML contenido en el zip del CDR
+func readCDRXML(zipContent []byte) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(zipContent), int64(len(zipContent)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CDR zip: %w", err)
+	}
+
+	for _, file := range reader.File {
+		if !strings.HasSuffix(strings.ToLower(file.Name), ".xml") {
+			continue
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+		}
+		defer f.Close()
+
+		return io.ReadAll(f)
+	}
+
+	return nil, fmt.Errorf("CDR zip does not contain an XML file")
+}
@@ -29,6 +29,7 @@ type CreditNote struct {
 	Signature            []Signature          `xml:"cac:Signature"`
 	AccountingSupplierParty AccountingSupplierParty `xml:"cac:AccountingSupplierParty"`
 	AccountingCustomerParty AccountingCustomerParty `xml:"cac:AccountingCustomerParty"`
+	AllowanceCharge      []AllowanceCharge    `xml:"cac:AllowanceCharge,omitempty"`
 	TaxTotal             []TaxTotal           `xml:"cac:TaxTotal"`
 	LegalMonetaryTotal   LegalMonetaryTotal   `xml:"cac:LegalMonetaryTotal"`
 	CreditNoteLine       []CreditNoteLine     `xml:"cac:CreditNoteLine"`
@@ -54,6 +55,7 @@ type CreditNoteLine struct {
 	CreditedQuantity    InvoicedQuantity   `xml:"cbc:CreditedQuantity"`
 	LineExtensionAmount MonetaryAmount     `xml:"cbc:LineExtensionAmount"`
 	PricingReference    PricingReference   `xml:"cac:PricingReference,omitempty"`
+	AllowanceCharge     []AllowanceCharge  `xml:"cac:AllowanceCharge,omitempty"`
 	TaxTotal            []TaxTotal         `xml:"cac:TaxTotal,omitempty"`
 	Item                Item               `xml:"cac:Item"`
 	Price               Price              `xml:"cac:Price"`
@@ -184,62 +186,14 @@ func GenerateCreditNoteXML(doc *models.Document, issuer *models.Company) (*Credi
 		},
 	}
 	
-	// Tax Total (similar a Invoice)
-	taxTotal := TaxTotal{
-		TaxAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.TotalTaxes,
-		},
-	}
-	
-	taxGroups := make(map[string]*TaxSubtotal)
-	for _, line := range doc.Lines {
-		for _, tax := range line.Taxes {
-			key := fmt.Sprintf("%s_%s", tax.Type, tax.Code)
-			if taxGroups[key] == nil {
-				taxGroups[key] = &TaxSubtotal{
-					TaxableAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: 0},
-					TaxAmount:     MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: 0},
-					TaxCategory: TaxCategory{
-						ID: IDType{Value: getTaxCategoryID(tax.Type)},
-						Percent: tax.Rate,
-						TaxScheme: TaxScheme{
-							ID: IDType{Value: getTaxSchemeID(tax.Type)},
-							Name: string(tax.Type),
-						},
-					},
-				}
-			}
-			taxGroups[key].TaxableAmount.Value += line.TaxableAmount
-			taxGroups[key].TaxAmount.Value += tax.Amount
-		}
-	}
-	
-	for _, taxSubtotal := range taxGroups {
-		taxTotal.TaxSubtotal = append(taxTotal.TaxSubtotal, *taxSubtotal)
-	}
+	// Descuentos y cargos globales
+	creditNote.AllowanceCharge = allowanceCharges(doc, doc.AllowanceCharges)
 	
-	creditNote.TaxTotal = []TaxTotal{taxTotal}
+	// Tax Total: un grupo por tributo y tipo de afectación
+	creditNote.TaxTotal = []TaxTotal{documentTaxTotal(doc)}
 	
 	// Legal Monetary Total
-	creditNote.LegalMonetaryTotal = LegalMonetaryTotal{
-		LineExtensionAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.SubTotal,
-		},
-		TaxInclusiveAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.TotalAmount,
-		},
-		TaxExclusiveAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.SubTotal,
-		},
-		PayableAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.TotalAmount,
-		},
-	}
+	creditNote.LegalMonetaryTotal = legalMonetaryTotal(doc)
 	
 	// Credit Note Lines
 	for i, line := range doc.Lines {
@@ -270,49 +224,14 @@ func GenerateCreditNoteXML(doc *models.Document, issuer *models.Company) (*Credi
 			}
 		}
 		
+		// Descuentos y cargos de la línea
+		creditNoteLine.AllowanceCharge = allowanceCharges(doc, line.AllowanceCharges)
+		
 		// Line taxes
-		if len(line.Taxes) > 0 {
-			lineTaxTotal := TaxTotal{
-				TaxAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: 0},
-			}
-			
-			for _, tax := range line.Taxes {
-				lineTaxTotal.TaxAmount.Value += tax.Amount
-				lineTaxTotal.TaxSubtotal = append(lineTaxTotal.TaxSubtotal, TaxSubtotal{
-					TaxableAmount: MonetaryAmount{
-						CurrencyID: doc.CurrencyCode,
-						Value:      line.TaxableAmount,
-					},
-					TaxAmount: MonetaryAmount{
-						CurrencyID: doc.CurrencyCode,
-						Value:      tax.Amount,
-					},
-					TaxCategory: TaxCategory{
-						ID: IDType{Value: getTaxCategoryID(tax.Type)},
-						Percent: tax.Rate,
-						TaxScheme: TaxScheme{
-							ID: IDType{Value: getTaxSchemeID(tax.Type)},
-							Name: string(tax.Type),
-						},
-					},
-				})
-			}
-			
-			creditNoteLine.TaxTotal = []TaxTotal{lineTaxTotal}
-		}
+		creditNoteLine.TaxTotal = []TaxTotal{lineTaxTotal(doc, line)}
 		
-		// Pricing reference
-		creditNoteLine.PricingReference = PricingReference{
-			AlternativeConditionPrice: []AlternativeConditionPrice{
-				{
-					PriceAmount: MonetaryAmount{
-						CurrencyID: doc.CurrencyCode,
-						Value:      line.UnitPrice * (1 + getTotalTaxRate(line.Taxes)/100),
-					},
-					PriceTypeCode: PriceTypeCode{Value: "01"},
-				},
-			},
-		}
+		// Precio unitario y precio de referencia
+		creditNoteLine.Price, creditNoteLine.PricingReference = linePrice(doc, line)
 		
 		creditNote.CreditNoteLine = append(creditNote.CreditNoteLine, creditNoteLine)
 	}
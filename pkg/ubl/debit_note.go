@@ -29,6 +29,7 @@ type DebitNote struct {
 	Signature            []Signature          `xml:"cac:Signature"`
 	AccountingSupplierParty AccountingSupplierParty `xml:"cac:AccountingSupplierParty"`
 	AccountingCustomerParty AccountingCustomerParty `xml:"cac:AccountingCustomerParty"`
+	AllowanceCharge      []AllowanceCharge    `xml:"cac:AllowanceCharge,omitempty"`
 	TaxTotal             []TaxTotal           `xml:"cac:TaxTotal"`
 	RequestedMonetaryTotal RequestedMonetaryTotal `xml:"cac:RequestedMonetaryTotal"`
 	DebitNoteLine        []DebitNoteLine      `xml:"cac:DebitNoteLine"`
@@ -36,8 +37,11 @@ type DebitNote struct {
 
 type RequestedMonetaryTotal struct {
 	LineExtensionAmount MonetaryAmount `xml:"cbc:LineExtensionAmount,omitempty"`
-	TaxInclusiveAmount  MonetaryAmount `xml:"cbc:TaxInclusiveAmount,omitempty"`
 	TaxExclusiveAmount  MonetaryAmount `xml:"cbc:TaxExclusiveAmount,omitempty"`
+	TaxInclusiveAmount  MonetaryAmount `xml:"cbc:TaxInclusiveAmount,omitempty"`
+	AllowanceTotalAmount *MonetaryAmount `xml:"cbc:AllowanceTotalAmount,omitempty"`
+	ChargeTotalAmount    *MonetaryAmount `xml:"cbc:ChargeTotalAmount,omitempty"`
+	PrepaidAmount        *MonetaryAmount `xml:"cbc:PrepaidAmount,omitempty"`
 	PayableAmount       MonetaryAmount `xml:"cbc:PayableAmount"`
 }
 
@@ -46,6 +50,7 @@ type DebitNoteLine struct {
 	DebitedQuantity     InvoicedQuantity   `xml:"cbc:DebitedQuantity"`
 	LineExtensionAmount MonetaryAmount     `xml:"cbc:LineExtensionAmount"`
 	PricingReference    PricingReference   `xml:"cac:PricingReference,omitempty"`
+	AllowanceCharge     []AllowanceCharge  `xml:"cac:AllowanceCharge,omitempty"`
 	TaxTotal            []TaxTotal         `xml:"cac:TaxTotal,omitempty"`
 	Item                Item               `xml:"cac:Item"`
 	Price               Price              `xml:"cac:Price"`
@@ -170,62 +175,14 @@ func GenerateDebitNoteXML(doc *models.Document, issuer *models.Company) (*DebitN
 		},
 	}
 	
-	// Tax Total (similar a Invoice)
-	taxTotal := TaxTotal{
-		TaxAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.TotalTaxes,
-		},
-	}
-	
-	taxGroups := make(map[string]*TaxSubtotal)
-	for _, line := range doc.Lines {
-		for _, tax := range line.Taxes {
-			key := fmt.Sprintf("%s_%s", tax.Type, tax.Code)
-			if taxGroups[key] == nil {
-				taxGroups[key] = &TaxSubtotal{
-					TaxableAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: 0},
-					TaxAmount:     MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: 0},
-					TaxCategory: TaxCategory{
-						ID: IDType{Value: getTaxCategoryID(tax.Type)},
-						Percent: tax.Rate,
-						TaxScheme: TaxScheme{
-							ID: IDType{Value: getTaxSchemeID(tax.Type)},
-							Name: string(tax.Type),
-						},
-					},
-				}
-			}
-			taxGroups[key].TaxableAmount.Value += line.TaxableAmount
-			taxGroups[key].TaxAmount.Value += tax.Amount
-		}
-	}
-	
-	for _, taxSubtotal := range taxGroups {
-		taxTotal.TaxSubtotal = append(taxTotal.TaxSubtotal, *taxSubtotal)
-	}
+	// Descuentos y cargos globales
+	debitNote.AllowanceCharge = allowanceCharges(doc, doc.AllowanceCharges)
 	
-	debitNote.TaxTotal = []TaxTotal{taxTotal}
+	// Tax Total: un grupo por tributo y tipo de afectación
+	debitNote.TaxTotal = []TaxTotal{documentTaxTotal(doc)}
 	
 	// Requested Monetary Total
-	debitNote.RequestedMonetaryTotal = RequestedMonetaryTotal{
-		LineExtensionAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.SubTotal,
-		},
-		TaxInclusiveAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.TotalAmount,
-		},
-		TaxExclusiveAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.SubTotal,
-		},
-		PayableAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.TotalAmount,
-		},
-	}
+	debitNote.RequestedMonetaryTotal = RequestedMonetaryTotal(legalMonetaryTotal(doc))
 	
 	// Debit Note Lines
 	for i, line := range doc.Lines {
@@ -256,49 +213,14 @@ func GenerateDebitNoteXML(doc *models.Document, issuer *models.Company) (*DebitN
 			}
 		}
 		
+		// Descuentos y cargos de la línea
+		debitNoteLine.AllowanceCharge = allowanceCharges(doc, line.AllowanceCharges)
+		
 		// Line taxes
-		if len(line.Taxes) > 0 {
-			lineTaxTotal := TaxTotal{
-				TaxAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: 0},
-			}
-			
-			for _, tax := range line.Taxes {
-				lineTaxTotal.TaxAmount.Value += tax.Amount
-				lineTaxTotal.TaxSubtotal = append(lineTaxTotal.TaxSubtotal, TaxSubtotal{
-					TaxableAmount: MonetaryAmount{
-						CurrencyID: doc.CurrencyCode,
-						Value:      line.TaxableAmount,
-					},
-					TaxAmount: MonetaryAmount{
-						CurrencyID: doc.CurrencyCode,
-						Value:      tax.Amount,
-					},
-					TaxCategory: TaxCategory{
-						ID: IDType{Value: getTaxCategoryID(tax.Type)},
-						Percent: tax.Rate,
-						TaxScheme: TaxScheme{
-							ID: IDType{Value: getTaxSchemeID(tax.Type)},
-							Name: string(tax.Type),
-						},
-					},
-				})
-			}
-			
-			debitNoteLine.TaxTotal = []TaxTotal{lineTaxTotal}
-		}
+		debitNoteLine.TaxTotal = []TaxTotal{lineTaxTotal(doc, line)}
 		
-		// Pricing reference
-		debitNoteLine.PricingReference = PricingReference{
-			AlternativeConditionPrice: []AlternativeConditionPrice{
-				{
-					PriceAmount: MonetaryAmount{
-						CurrencyID: doc.CurrencyCode,
-						Value:      line.UnitPrice * (1 + getTotalTaxRate(line.Taxes)/100),
-					},
-					PriceTypeCode: PriceTypeCode{Value: "01"},
-				},
-			},
-		}
+		// Precio unitario y precio de referencia
+		debitNoteLine.Price, debitNoteLine.PricingReference = linePrice(doc, line)
 		
 		debitNote.DebitNoteLine = append(debitNote.DebitNoteLine, debitNoteLine)
 	}
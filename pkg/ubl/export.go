@@ -0,0 +1,92 @@
+package ubl
+
+import (
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// Delivery informa el país de destino de una exportación
+type Delivery struct {
+	DeliveryLocation DeliveryLocation `xml:"cac:DeliveryLocation"`
+}
+
+type DeliveryLocation struct {
+	Address DeliveryAddress `xml:"cac:Address"`
+}
+
+type DeliveryAddress struct {
+	Country Country `xml:"cac:Country"`
+}
+
+// DeliveryTerms informa las condiciones de entrega (Incoterm) de una
+// exportación de bienes
+type DeliveryTerms struct {
+	ID IDType `xml:"cbc:ID"`
+}
+
+type ExchangeRate struct {
+	SourceCurrencyCode string          `xml:"cbc:SourceCurrencyCode"`
+	TargetCurrencyCode string          `xml:"cbc:TargetCurrencyCode"`
+	CalculationRate    decimal.Decimal `xml:"cbc:CalculationRate"`
+	Date               string          `xml:"cbc:Date"`
+}
+
+func country(code string) *Country {
+	return &Country{
+		IdentificationCode: IDType{
+			ListID:         "ISO 3166-1",
+			ListAgencyName: "United Nations Economic Commission for Europe",
+			ListName:       "Country",
+			Value:          code,
+		},
+	}
+}
+
+// exportTerms arma el país de destino y el Incoterm de una exportación
+func exportTerms(doc *models.Document) (*Delivery, *DeliveryTerms) {
+	var delivery *Delivery
+	var terms *DeliveryTerms
+	if doc.DestinationCountry != "" {
+		delivery = &Delivery{
+			DeliveryLocation: DeliveryLocation{
+				Address: DeliveryAddress{Country: *country(doc.DestinationCountry)},
+			},
+		}
+	}
+	if doc.Incoterm != "" {
+		terms = &DeliveryTerms{
+			ID: IDType{SchemeAgencyName: "ICC", SchemeName: "Incoterms", Value: doc.Incoterm},
+		}
+	}
+	return delivery, terms
+}
+
+// paymentExchangeRate informa el tipo de cambio declarado de la moneda del
+// documento
+func paymentExchangeRate(doc *models.Document) *ExchangeRate {
+	if doc.ExchangeRate == nil {
+		return nil
+	}
+	return &ExchangeRate{
+		SourceCurrencyCode: doc.CurrencyCode,
+		TargetCurrencyCode: doc.ExchangeRate.TargetCurrencyCode,
+		CalculationRate:    doc.ExchangeRate.Rate,
+		Date:               doc.IssueDate.Format("2006-01-02"),
+	}
+}
+
+// customerAddress informa la dirección y el país del adquirente, que las
+// exportaciones a no domiciliados requieren
+func customerAddress(customer models.Company) *RegistrationAddress {
+	if customer.Address == "" && customer.Country == "" {
+		return nil
+	}
+	address := &RegistrationAddress{}
+	if customer.Address != "" {
+		address.AddressLine = &AddressLine{Line: customer.Address}
+	}
+	if customer.Country != "" {
+		address.Country = country(customer.Country)
+	}
+	return address
+}
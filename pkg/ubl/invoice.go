@@ -3,10 +3,10 @@ package ubl
 import (
 	"encoding/xml"
 	"fmt"
-	"math"
 	"strings"
 
 	"infac/internal/models"
+	"infac/pkg/decimal"
 )
 
 type Invoice struct {
@@ -34,10 +34,17 @@ type Invoice struct {
 	DocumentCurrencyCode DocumentCurrencyCode `xml:"cbc:DocumentCurrencyCode"`
 	LineCountNumeric     int             `xml:"cbc:LineCountNumeric"`
 
+	AdditionalDocumentReference []AdditionalDocumentReference `xml:"cac:AdditionalDocumentReference,omitempty"`
 	Signature               []Signature             `xml:"cac:Signature"`
 	AccountingSupplierParty AccountingSupplierParty `xml:"cac:AccountingSupplierParty"`
 	AccountingCustomerParty AccountingCustomerParty `xml:"cac:AccountingCustomerParty"`
+	Delivery                *Delivery               `xml:"cac:Delivery,omitempty"`
+	DeliveryTerms           *DeliveryTerms          `xml:"cac:DeliveryTerms,omitempty"`
+	PaymentMeans            []PaymentMeans          `xml:"cac:PaymentMeans,omitempty"`
 	PaymentTerms            []PaymentTerms          `xml:"cac:PaymentTerms,omitempty"`
+	PrepaidPayment          []PrepaidPayment        `xml:"cac:PrepaidPayment,omitempty"`
+	AllowanceCharge         []AllowanceCharge       `xml:"cac:AllowanceCharge,omitempty"`
+	PaymentExchangeRate     *ExchangeRate           `xml:"cac:PaymentExchangeRate,omitempty"`
 	TaxTotal                []TaxTotal              `xml:"cac:TaxTotal"`
 	LegalMonetaryTotal      LegalMonetaryTotal      `xml:"cac:LegalMonetaryTotal"`
 	InvoiceLine             []InvoiceLine           `xml:"cac:InvoiceLine"`
@@ -156,13 +163,13 @@ type PartyLegalEntity struct {
 }
 
 type RegistrationAddress struct {
-	ID               IDType          `xml:"cbc:ID,omitempty"`
-	AddressTypeCode  AddressTypeCode `xml:"cbc:AddressTypeCode,omitempty"`
-	CityName         string          `xml:"cbc:CityName,omitempty"`
-	CountrySubentity string          `xml:"cbc:CountrySubentity,omitempty"`
-	District         string          `xml:"cbc:District,omitempty"`
-	AddressLine      *AddressLine    `xml:"cac:AddressLine,omitempty"`
-	Country          *Country        `xml:"cac:Country,omitempty"`
+	ID               *IDType          `xml:"cbc:ID,omitempty"`
+	AddressTypeCode  *AddressTypeCode `xml:"cbc:AddressTypeCode,omitempty"`
+	CityName         string           `xml:"cbc:CityName,omitempty"`
+	CountrySubentity string           `xml:"cbc:CountrySubentity,omitempty"`
+	District         string           `xml:"cbc:District,omitempty"`
+	AddressLine      *AddressLine     `xml:"cac:AddressLine,omitempty"`
+	Country          *Country         `xml:"cac:Country,omitempty"`
 }
 
 type AddressTypeCode struct {
@@ -191,10 +198,18 @@ type TaxScheme struct {
 }
 
 type PaymentTerms struct {
-	ID             string         `xml:"cbc:ID"`
-	PaymentMeansID string         `xml:"cbc:PaymentMeansID"`
-	Amount         MonetaryAmount `xml:"cbc:Amount,omitempty"`
-	PaymentDueDate string         `xml:"cbc:PaymentDueDate,omitempty"`
+	ID             string           `xml:"cbc:ID"`
+	PaymentMeansID *PaymentMeansID  `xml:"cbc:PaymentMeansID,omitempty"`
+	PaymentPercent *decimal.Decimal `xml:"cbc:PaymentPercent,omitempty"`
+	Amount         *MonetaryAmount  `xml:"cbc:Amount,omitempty"`
+	PaymentDueDate string           `xml:"cbc:PaymentDueDate,omitempty"`
+}
+
+type PaymentMeansID struct {
+	SchemeName       string `xml:"schemeName,attr,omitempty"`
+	SchemeAgencyName string `xml:"schemeAgencyName,attr,omitempty"`
+	SchemeURI        string `xml:"schemeURI,attr,omitempty"`
+	Value            string `xml:",chardata"`
 }
 
 type TaxTotal struct {
@@ -202,17 +217,22 @@ type TaxTotal struct {
 	TaxSubtotal []TaxSubtotal  `xml:"cac:TaxSubtotal"`
 }
 
+// TaxSubtotal informa la base y el importe de un tributo. El ICBPER no tiene
+// base imponible: informa la cantidad de bolsas (BaseUnitMeasure).
 type TaxSubtotal struct {
-	TaxableAmount MonetaryAmount `xml:"cbc:TaxableAmount"`
-	TaxAmount     MonetaryAmount `xml:"cbc:TaxAmount"`
-	TaxCategory   TaxCategory    `xml:"cac:TaxCategory"`
+	TaxableAmount   *MonetaryAmount   `xml:"cbc:TaxableAmount,omitempty"`
+	TaxAmount       MonetaryAmount    `xml:"cbc:TaxAmount"`
+	BaseUnitMeasure *InvoicedQuantity `xml:"cbc:BaseUnitMeasure,omitempty"`
+	TaxCategory     TaxCategory       `xml:"cac:TaxCategory"`
 }
 
 type TaxCategory struct {
-	ID                     IDType                 `xml:"cbc:ID"`
-	Percent                float64                `xml:"cbc:Percent,omitempty"`
-	TaxExemptionReasonCode TaxExemptionReasonCode `xml:"cbc:TaxExemptionReasonCode,omitempty"`
-	TaxScheme              TaxScheme              `xml:"cac:TaxScheme"`
+	ID                     IDType                  `xml:"cbc:ID"`
+	Percent                *decimal.Decimal        `xml:"cbc:Percent,omitempty"`
+	PerUnitAmount          *MonetaryAmount         `xml:"cbc:PerUnitAmount,omitempty"`
+	TaxExemptionReasonCode *TaxExemptionReasonCode `xml:"cbc:TaxExemptionReasonCode,omitempty"`
+	TierRange              string                  `xml:"cbc:TierRange,omitempty"`
+	TaxScheme              TaxScheme               `xml:"cac:TaxScheme"`
 }
 
 type TaxExemptionReasonCode struct {
@@ -223,14 +243,17 @@ type TaxExemptionReasonCode struct {
 }
 
 type MonetaryAmount struct {
-	CurrencyID string  `xml:"currencyID,attr"`
-	Value      float64 `xml:",chardata"`
+	CurrencyID string          `xml:"currencyID,attr"`
+	Value      decimal.Decimal `xml:",chardata"`
 }
 
 type LegalMonetaryTotal struct {
 	LineExtensionAmount MonetaryAmount `xml:"cbc:LineExtensionAmount,omitempty"`
 	TaxExclusiveAmount  MonetaryAmount `xml:"cbc:TaxExclusiveAmount,omitempty"`
 	TaxInclusiveAmount  MonetaryAmount `xml:"cbc:TaxInclusiveAmount,omitempty"`
+	AllowanceTotalAmount *MonetaryAmount `xml:"cbc:AllowanceTotalAmount,omitempty"`
+	ChargeTotalAmount    *MonetaryAmount `xml:"cbc:ChargeTotalAmount,omitempty"`
+	PrepaidAmount        *MonetaryAmount `xml:"cbc:PrepaidAmount,omitempty"`
 	PayableAmount       MonetaryAmount `xml:"cbc:PayableAmount"`
 }
 
@@ -239,16 +262,17 @@ type InvoiceLine struct {
 	InvoicedQuantity    InvoicedQuantity `xml:"cbc:InvoicedQuantity"`
 	LineExtensionAmount MonetaryAmount   `xml:"cbc:LineExtensionAmount"`
 	PricingReference    PricingReference `xml:"cac:PricingReference,omitempty"`
+	AllowanceCharge     []AllowanceCharge `xml:"cac:AllowanceCharge,omitempty"`
 	TaxTotal            []TaxTotal       `xml:"cac:TaxTotal,omitempty"`
 	Item                Item             `xml:"cac:Item"`
 	Price               Price            `xml:"cac:Price"`
 }
 
 type InvoicedQuantity struct {
-	UnitCode               string  `xml:"unitCode,attr"`
-	UnitCodeListID         string  `xml:"unitCodeListID,attr,omitempty"`
-	UnitCodeListAgencyName string  `xml:"unitCodeListAgencyName,attr,omitempty"`
-	Value                  float64 `xml:",chardata"`
+	UnitCode               string          `xml:"unitCode,attr"`
+	UnitCodeListID         string          `xml:"unitCodeListID,attr,omitempty"`
+	UnitCodeListAgencyName string          `xml:"unitCodeListAgencyName,attr,omitempty"`
+	Value                  decimal.Decimal `xml:",chardata"`
 }
 
 type PricingReference struct {
@@ -313,7 +337,7 @@ func GenerateInvoiceXML(doc *models.Document, issuer *models.Company) (*Invoice,
 			SchemeName:       "Tipo de Operacion",
 			SchemeAgencyName: "PE:SUNAT",
 			SchemeURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo51",
-			Value:            "0101", // Venta interna
+			Value:            string(doc.Operation()),
 		},
 		ID:                   fmt.Sprintf("%s-%s", doc.Serie, doc.Number),
 		IssueDate:            doc.IssueDate.Format("2006-01-02"),
@@ -329,7 +353,7 @@ func GenerateInvoiceXML(doc *models.Document, issuer *models.Company) (*Invoice,
 			ListAgencyName: "PE:SUNAT",
 			ListName:       "Tipo de Documento", 
 			ListURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo01",
-			ListID:         "0101",
+			ListID:         string(doc.Operation()),
 			Name:           "Tipo de Operacion",
 			ListSchemeURI:  "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo51",
 			Value:          string(doc.Type),
@@ -344,14 +368,8 @@ func GenerateInvoiceXML(doc *models.Document, issuer *models.Company) (*Invoice,
 		invoice.DueDate = doc.IssueDate.Format("2006-01-02")
 	}
 
-	// Add Note with amount in letters (required by SUNAT)
-	amountInWords := convertAmountToWords(doc.TotalAmount, doc.CurrencyCode)
-	invoice.Note = []Note{
-		{
-			LanguageLocaleID: "1000",
-			Value:            amountInWords,
-		},
-	}
+	// Leyendas: importe en letras (required by SUNAT) y operaciones gratuitas
+	invoice.Note = legends(doc)
 
 	// Signature (using document-specific ID instead of hardcoded)
 	documentID := fmt.Sprintf("%s-%s", doc.Serie, doc.Number)
@@ -426,12 +444,12 @@ func GenerateInvoiceXML(doc *models.Document, issuer *models.Company) (*Invoice,
 				{
 					RegistrationName: issuer.Name,
 					RegistrationAddress: &RegistrationAddress{
-						ID: IDType{
+						ID: &IDType{
 							SchemeName:       "Ubigeos",
 							SchemeAgencyName: "PE:INEI",
 							Value:            "150101", // Ubigeo por defecto para Lima
 						},
-						AddressTypeCode: AddressTypeCode{
+						AddressTypeCode: &AddressTypeCode{
 							ListAgencyName: "PE:SUNAT",
 							ListName:       "Establecimientos anexos",
 							Value:          establishmentCode,
@@ -442,14 +460,7 @@ func GenerateInvoiceXML(doc *models.Document, issuer *models.Company) (*Invoice,
 						AddressLine: &AddressLine{
 							Line: issuer.Address,
 						},
-						Country: &Country{
-							IdentificationCode: IDType{
-								ListID:         "ISO 3166-1",
-								ListAgencyName: "United Nations Economic Commission for Europe",
-								ListName:       "Country",
-								Value:          issuer.Country,
-							},
-						},
+						Country: country(issuer.Country),
 					},
 				},
 			},
@@ -492,12 +503,16 @@ func GenerateInvoiceXML(doc *models.Document, issuer *models.Company) (*Invoice,
 			},
 			PartyLegalEntity: []PartyLegalEntity{
 				{
-					RegistrationName: customerName,
+					RegistrationName:    customerName,
+					RegistrationAddress: customerAddress(doc.Customer),
 				},
 			},
 		},
 	}
 
+	// Exportaciones: país de destino e Incoterm
+	invoice.Delivery, invoice.DeliveryTerms = exportTerms(doc)
+
 	// Payment Terms (Required by SUNAT Resolution 000193-2020)
 	if doc.PaymentTerms == nil {
 		return nil, fmt.Errorf("payment terms are required")
@@ -507,98 +522,22 @@ func GenerateInvoiceXML(doc *models.Document, issuer *models.Company) (*Invoice,
 		return nil, fmt.Errorf("payment means code is required (e.g., 'Contado' or 'Credito')")
 	}
 	
-	paymentTerms := PaymentTerms{
-		ID:             "FormaPago",
-		PaymentMeansID: doc.PaymentTerms.PaymentMeansCode,
-	}
-	
-	// Add amount and due date for credit payments
-	if doc.PaymentTerms.PaymentMeansCode == "Credito" {
-		paymentTerms.Amount = MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.PaymentTerms.Amount,
-		}
-		if !doc.PaymentTerms.DueDate.IsZero() {
-			paymentTerms.PaymentDueDate = doc.PaymentTerms.DueDate.Format("2006-01-02")
-		}
-	}
-	
-	invoice.PaymentTerms = []PaymentTerms{paymentTerms}
+	invoice.PaymentMeans, invoice.PaymentTerms = paymentTerms(doc)
 
-	// Tax Total
-	taxTotal := TaxTotal{
-		TaxAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.TotalTaxes,
-		},
-	}
+	// Anticipos deducidos
+	invoice.AdditionalDocumentReference, invoice.PrepaidPayment = prepayments(doc, issuer)
 
-	// Agrupar impuestos por tipo
-	taxGroups := make(map[string]*TaxSubtotal)
-	for _, line := range doc.Lines {
-		for _, tax := range line.Taxes {
-			key := fmt.Sprintf("%s_%s", tax.Type, tax.Code)
-			if taxGroups[key] == nil {
-				taxGroups[key] = &TaxSubtotal{
-					TaxableAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: 0},
-					TaxAmount:     MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: 0},
-					TaxCategory: TaxCategory{
-						ID: IDType{
-							SchemeID:         "UN/ECE 5305",
-							SchemeName:       "Tax Category Identifier",
-							SchemeAgencyName: "United Nations Economic Commission for Europe",
-							Value:            getTaxCategoryID(tax.Type),
-						},
-						Percent: tax.Rate,
-						TaxExemptionReasonCode: TaxExemptionReasonCode{
-							ListAgencyName: "PE:SUNAT",
-							ListName:       "Afectacion del IGV",
-							ListURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo07",
-							Value:          getTaxExemptionCode(tax.Type),
-						},
-						TaxScheme: TaxScheme{
-							ID: IDType{
-								SchemeID:         "UN/ECE 5153",
-								SchemeName:       "Codigo de tributos",
-								SchemeAgencyName: "PE:SUNAT",
-								Value:            getTaxSchemeID(tax.Type),
-							},
-							Name:        string(tax.Type),
-							TaxTypeCode: "VAT",
-						},
-					},
-				}
-			}
-			taxGroups[key].TaxableAmount.Value += line.TaxableAmount
-			taxGroups[key].TaxAmount.Value += tax.Amount
-		}
-	}
+	// Descuentos y cargos globales
+	invoice.AllowanceCharge = allowanceCharges(doc, doc.AllowanceCharges)
 
-	for _, taxSubtotal := range taxGroups {
-		taxTotal.TaxSubtotal = append(taxTotal.TaxSubtotal, *taxSubtotal)
-	}
+	// Tipo de cambio declarado
+	invoice.PaymentExchangeRate = paymentExchangeRate(doc)
 
-	invoice.TaxTotal = []TaxTotal{taxTotal}
+	// Tax Total: un grupo por tributo y tipo de afectación
+	invoice.TaxTotal = []TaxTotal{documentTaxTotal(doc)}
 
 	// Legal Monetary Total
-	invoice.LegalMonetaryTotal = LegalMonetaryTotal{
-		LineExtensionAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.SubTotal,
-		},
-		TaxInclusiveAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.TotalAmount,
-		},
-		TaxExclusiveAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.SubTotal,
-		},
-		PayableAmount: MonetaryAmount{
-			CurrencyID: doc.CurrencyCode,
-			Value:      doc.TotalAmount,
-		},
-	}
+	invoice.LegalMonetaryTotal = legalMonetaryTotal(doc)
 
 	// Invoice Lines
 	for i, line := range doc.Lines {
@@ -631,71 +570,14 @@ func GenerateInvoiceXML(doc *models.Document, issuer *models.Company) (*Invoice,
 			}
 		}
 
-		// Line taxes
-		if len(line.Taxes) > 0 {
-			lineTaxTotal := TaxTotal{
-				TaxAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: 0},
-			}
+		// Descuentos y cargos de la línea
+		invoiceLine.AllowanceCharge = allowanceCharges(doc, line.AllowanceCharges)
 
-			for _, tax := range line.Taxes {
-				lineTaxTotal.TaxAmount.Value += tax.Amount
-				lineTaxTotal.TaxSubtotal = append(lineTaxTotal.TaxSubtotal, TaxSubtotal{
-					TaxableAmount: MonetaryAmount{
-						CurrencyID: doc.CurrencyCode,
-						Value:      line.TaxableAmount,
-					},
-					TaxAmount: MonetaryAmount{
-						CurrencyID: doc.CurrencyCode,
-						Value:      tax.Amount,
-					},
-					TaxCategory: TaxCategory{
-						ID: IDType{
-							SchemeID:         "UN/ECE 5305",
-							SchemeName:       "Tax Category Identifier",
-							SchemeAgencyName: "United Nations Economic Commission for Europe",
-							Value:            getTaxCategoryID(tax.Type),
-						},
-						Percent: tax.Rate,
-						TaxExemptionReasonCode: TaxExemptionReasonCode{
-							ListAgencyName: "PE:SUNAT",
-							ListName:       "Afectacion del IGV",
-							ListURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo07",
-							Value:          getTaxExemptionCode(tax.Type),
-						},
-						TaxScheme: TaxScheme{
-							ID: IDType{
-								SchemeID:         "UN/ECE 5153",
-								SchemeName:       "Codigo de tributos",
-								SchemeAgencyName: "PE:SUNAT",
-								Value:            getTaxSchemeID(tax.Type),
-							},
-							Name:        string(tax.Type),
-							TaxTypeCode: "VAT",
-						},
-					},
-				})
-			}
-
-			invoiceLine.TaxTotal = []TaxTotal{lineTaxTotal}
-		}
+		// Line taxes
+		invoiceLine.TaxTotal = []TaxTotal{lineTaxTotal(doc, line)}
 
-		// Pricing reference para mostrar precio con impuestos
-		invoiceLine.PricingReference = PricingReference{
-			AlternativeConditionPrice: []AlternativeConditionPrice{
-				{
-					PriceAmount: MonetaryAmount{
-						CurrencyID: doc.CurrencyCode,
-						Value:      line.UnitPrice * (1 + getTotalTaxRate(line.Taxes)/100),
-					},
-					PriceTypeCode: PriceTypeCode{
-						ListName:       "Tipo de Precio",
-						ListAgencyName: "PE:SUNAT",
-						ListURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo16",
-						Value:          "01", // Precio unitario (incluye el IGV)
-					},
-				},
-			},
-		}
+		// Precio unitario y precio de referencia
+		invoiceLine.Price, invoiceLine.PricingReference = linePrice(doc, line)
 
 		invoice.InvoiceLine = append(invoice.InvoiceLine, invoiceLine)
 	}
@@ -703,15 +585,14 @@ func GenerateInvoiceXML(doc *models.Document, issuer *models.Company) (*Invoice,
 	return invoice, nil
 }
 
+// getDocumentTypeScheme devuelve el tipo de documento de identidad del
+// catálogo 06; los tipos desconocidos se informan como no domiciliado sin
+// RUC
 func getDocumentTypeScheme(docType string) string {
-	switch docType {
-	case "6": // RUC
-		return "6"
-	case "1": // DNI
-		return "1"
-	default:
-		return "0"
+	if models.ValidIdentityDocumentType(docType) {
+		return docType
 	}
+	return models.IdentityNonDomiciled
 }
 
 func getTaxSchemeID(taxType models.TaxType) string {
@@ -727,44 +608,42 @@ func getTaxSchemeID(taxType models.TaxType) string {
 	}
 }
 
-func getTotalTaxRate(taxes []models.Tax) float64 {
-	total := 0.0
-	for _, tax := range taxes {
-		total += tax.Rate
-	}
-	return total
-}
-
-func getTaxCategoryID(taxType models.TaxType) string {
+// getTaxSchemeName devuelve el nombre del tributo (catálogo 05)
+func getTaxSchemeName(taxType models.TaxType) string {
 	switch taxType {
-	case models.TaxTypeIGV:
-		return "S" // Standard rate (IGV gravado)
-	case models.TaxTypeISC:
-		return "S" // Standard rate
 	case models.TaxTypeICBP:
-		return "S" // Standard rate
+		return "ICBPER"
+	case models.TaxTypeIGV, models.TaxTypeISC:
+		return string(taxType)
 	default:
-		return "S"
+		return "OTROS"
 	}
 }
 
-func getTaxExemptionCode(taxType models.TaxType) string {
-	switch taxType {
-	case models.TaxTypeIGV:
-		return "10" // Gravado - Operación Onerosa
-	case models.TaxTypeISC:
-		return "20" // Exonerado - Operación Onerosa
-	case models.TaxTypeICBP:
-		return "20" // Exonerado - Operación Onerosa
-	default:
-		return "10"
+// priceWithTaxes es el precio unitario que paga el adquirente: el valor de
+// venta más los tributos cobrados de la línea, entre la cantidad, con hasta
+// los decimales que admite SUNAT para precios unitarios
+func priceWithTaxes(line models.DocumentLine) decimal.Decimal {
+	total := line.TotalPrice
+	for _, tax := range line.Taxes {
+		total = total.Add(tax.Amount)
+	}
+	return total.Div(line.Quantity)
+}
+
+// percent devuelve la tasa para cbc:Percent; una tasa cero no se informa
+func percent(rate decimal.Decimal) *decimal.Decimal {
+	if rate.IsZero() {
+		return nil
 	}
+	return &rate
 }
 
 // convertAmountToWords converts a numeric amount to words in Spanish
-func convertAmountToWords(amount float64, currency string) string {
-	integerPart := int(amount)
-	decimalPart := int(math.Round((amount - float64(integerPart)) * 100))
+func convertAmountToWords(amount decimal.Decimal, currency string) string {
+	amount = amount.Round(decimal.AmountPlaces)
+	integerPart := int(amount.IntPart())
+	decimalPart := int(amount.Sub(decimal.NewFromInt(int64(integerPart))).Mul(decimal.Hundred).IntPart())
 
 	var currencyWord string
 	switch currency {
@@ -0,0 +1,102 @@
+package ubl
+
+import (
+	"fmt"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// PaymentMeans informa el medio de pago de la detracción y la cuenta del
+// emisor en el Banco de la Nación
+type PaymentMeans struct {
+	ID                    string                `xml:"cbc:ID"`
+	PaymentMeansCode      PaymentMeansCode      `xml:"cbc:PaymentMeansCode"`
+	PayeeFinancialAccount PayeeFinancialAccount `xml:"cac:PayeeFinancialAccount"`
+}
+
+type PaymentMeansCode struct {
+	ListAgencyName string `xml:"listAgencyName,attr,omitempty"`
+	ListName       string `xml:"listName,attr,omitempty"`
+	ListURI        string `xml:"listURI,attr,omitempty"`
+	Value          string `xml:",chardata"`
+}
+
+type PayeeFinancialAccount struct {
+	ID string `xml:"cbc:ID"`
+}
+
+// paymentTerms arma la detracción, la retención, la percepción y la forma de
+// pago. Al crédito se informa el importe neto pendiente de pago y luego cada
+// cuota (Cuota001, Cuota002...) con su importe y vencimiento.
+func paymentTerms(doc *models.Document) ([]PaymentMeans, []PaymentTerms) {
+	var means []PaymentMeans
+	var terms []PaymentTerms
+	if doc.Detraction != nil {
+		means = append(means, PaymentMeans{
+			ID: "Detraccion",
+			PaymentMeansCode: PaymentMeansCode{
+				ListAgencyName: "PE:SUNAT",
+				ListName:       "Medio de pago",
+				ListURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo59",
+				Value:          doc.Detraction.PaymentMeansCode,
+			},
+			PayeeFinancialAccount: PayeeFinancialAccount{ID: doc.Detraction.Account},
+		})
+		percent := doc.Detraction.Percent
+		terms = append(terms, PaymentTerms{
+			ID: "Detraccion",
+			PaymentMeansID: &PaymentMeansID{
+				SchemeName:       "Codigo de detraccion",
+				SchemeAgencyName: "PE:SUNAT",
+				SchemeURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo54",
+				Value:            string(doc.Detraction.Code),
+			},
+			PaymentPercent: &percent,
+			// La detracción se informa siempre en soles
+			Amount: &MonetaryAmount{CurrencyID: "PEN", Value: doc.Detraction.Amount},
+		})
+	}
+
+	// La retención y la percepción se informan en soles. La percepción
+	// informa el importe total más la percepción.
+	if retention := doc.Retention(); retention != nil {
+		term := PaymentTerms{
+			ID:     "Retencion",
+			Amount: &MonetaryAmount{CurrencyID: "PEN", Value: doc.ToPEN(retention.Amount)},
+		}
+		if retention.Factor != nil {
+			percent := retention.Factor.Mul(decimal.Hundred)
+			term.PaymentPercent = &percent
+		}
+		terms = append(terms, term)
+	}
+	if perception := doc.Perception(); perception != nil {
+		terms = append(terms, PaymentTerms{
+			ID:     "Percepcion",
+			Amount: &MonetaryAmount{CurrencyID: "PEN", Value: doc.TotalAmount.Add(perception.Amount)},
+		})
+	}
+
+	terms = append(terms, PaymentTerms{ID: "FormaPago", PaymentMeansID: &PaymentMeansID{Value: doc.PaymentTerms.PaymentMeansCode}})
+	if doc.PaymentTerms.PaymentMeansCode != models.PaymentMeansCredit {
+		return means, terms
+	}
+
+	terms[len(terms)-1].Amount = &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: doc.PaymentTerms.Amount}
+	installments := doc.PaymentTerms.Installments
+	// Los documentos guardados antes de las cuotas tienen un único
+	// vencimiento
+	if len(installments) == 0 && !doc.PaymentTerms.DueDate.IsZero() {
+		installments = []models.Installment{{Amount: doc.PaymentTerms.Amount, DueDate: doc.PaymentTerms.DueDate}}
+	}
+	for i, installment := range installments {
+		terms = append(terms, PaymentTerms{
+			ID:             "FormaPago",
+			PaymentMeansID: &PaymentMeansID{Value: fmt.Sprintf("Cuota%03d", i+1)},
+			Amount:         &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: installment.Amount},
+			PaymentDueDate: installment.DueDate.Format("2006-01-02"),
+		})
+	}
+	return means, terms
+}
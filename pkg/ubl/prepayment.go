@@ -0,0 +1,74 @@
+package ubl
+
+import (
+	"fmt"
+
+	"infac/internal/models"
+)
+
+// AdditionalDocumentReference relaciona el comprobante de un anticipo
+// deducido. DocumentStatusCode enlaza la referencia con su PrepaidPayment.
+type AdditionalDocumentReference struct {
+	ID                 string             `xml:"cbc:ID"`
+	DocumentTypeCode   DocumentTypeCode   `xml:"cbc:DocumentTypeCode"`
+	DocumentStatusCode DocumentStatusCode `xml:"cbc:DocumentStatusCode"`
+	IssuerParty        IssuerParty        `xml:"cac:IssuerParty"`
+}
+
+type DocumentTypeCode struct {
+	ListAgencyName string `xml:"listAgencyName,attr"`
+	ListName       string `xml:"listName,attr"`
+	ListURI        string `xml:"listURI,attr"`
+	Value          string `xml:",chardata"`
+}
+
+type DocumentStatusCode struct {
+	ListName       string `xml:"listName,attr"`
+	ListAgencyName string `xml:"listAgencyName,attr"`
+	Value          string `xml:",chardata"`
+}
+
+type IssuerParty struct {
+	PartyIdentification PartyIdentification `xml:"cac:PartyIdentification"`
+}
+
+// PrepaidPayment informa el importe deducido de un anticipo
+type PrepaidPayment struct {
+	ID         IDType         `xml:"cbc:ID"`
+	PaidAmount MonetaryAmount `xml:"cbc:PaidAmount"`
+}
+
+// prepayments arma las referencias a los anticipos deducidos y sus importes
+func prepayments(doc *models.Document, issuer *models.Company) ([]AdditionalDocumentReference, []PrepaidPayment) {
+	var references []AdditionalDocumentReference
+	var payments []PrepaidPayment
+	for i, p := range doc.Prepayments {
+		id := fmt.Sprintf("%d", i+1)
+		references = append(references, AdditionalDocumentReference{
+			ID: p.DocumentID(),
+			DocumentTypeCode: DocumentTypeCode{
+				ListAgencyName: "PE:SUNAT",
+				ListName:       "Documento Relacionado",
+				ListURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo12",
+				Value:          p.ReferenceCode(),
+			},
+			DocumentStatusCode: DocumentStatusCode{ListName: "Anticipo", ListAgencyName: "PE:SUNAT", Value: id},
+			IssuerParty: IssuerParty{
+				PartyIdentification: PartyIdentification{
+					ID: IDType{
+						SchemeID:         getDocumentTypeScheme(issuer.DocumentType),
+						SchemeName:       "Documento de Identidad",
+						SchemeAgencyName: "PE:SUNAT",
+						SchemeURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo06",
+						Value:            issuer.DocumentNumber,
+					},
+				},
+			},
+		})
+		payments = append(payments, PrepaidPayment{
+			ID:         IDType{SchemeName: "Anticipo", SchemeAgencyName: "PE:SUNAT", Value: id},
+			PaidAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: p.Amount},
+		})
+	}
+	return references, payments
+}
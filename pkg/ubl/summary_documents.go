@@ -0,0 +1,317 @@
+package ubl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// SummaryDocuments es el Resumen Diario (RC) con el que se informan las
+// boletas de venta y las notas que las modifican
+type SummaryDocuments struct {
+	XMLName  xml.Name `xml:"SummaryDocuments"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	XmlnsCac string   `xml:"xmlns:cac,attr"`
+	XmlnsCbc string   `xml:"xmlns:cbc,attr"`
+	XmlnsDs  string   `xml:"xmlns:ds,attr"`
+	XmlnsExt string   `xml:"xmlns:ext,attr"`
+	XmlnsSac string   `xml:"xmlns:sac,attr"`
+
+	UBLExtensions           UBLExtensions          `xml:"ext:UBLExtensions"`
+	UBLVersionID            string                 `xml:"cbc:UBLVersionID"`
+	CustomizationID         string                 `xml:"cbc:CustomizationID"`
+	ID                      string                 `xml:"cbc:ID"`
+	ReferenceDate           string                 `xml:"cbc:ReferenceDate"`
+	IssueDate               string                 `xml:"cbc:IssueDate"`
+	Signature               []Signature            `xml:"cac:Signature"`
+	AccountingSupplierParty SummarySupplierParty   `xml:"cac:AccountingSupplierParty"`
+	SummaryDocumentsLine    []SummaryDocumentsLine `xml:"sac:SummaryDocumentsLine"`
+}
+
+type SummaryDocumentsLine struct {
+	LineID                  string                  `xml:"cbc:LineID"`
+	DocumentTypeCode        string                  `xml:"cbc:DocumentTypeCode"`
+	ID                      string                  `xml:"cbc:ID"`
+	AccountingCustomerParty SummaryCustomerParty    `xml:"cac:AccountingCustomerParty"`
+	BillingReference        *BillingReference       `xml:"cac:BillingReference,omitempty"`
+	Perception              *SummaryPerception      `xml:"sac:SUNATPerceptionSummaryDocumentReference,omitempty"`
+	Status                  SummaryStatus           `xml:"cac:Status"`
+	TotalAmount             MonetaryAmount          `xml:"sac:TotalAmount"`
+	BillingPayment          []SummaryBillingPayment `xml:"sac:BillingPayment"`
+	AllowanceCharge         *SummaryAllowanceCharge `xml:"cac:AllowanceCharge,omitempty"`
+	TaxTotal                []SummaryTaxTotal       `xml:"cac:TaxTotal"`
+}
+
+// SummaryPerception informa la percepción de la boleta: el régimen, la tasa,
+// el importe percibido, el total cobrado con la percepción y la base
+type SummaryPerception struct {
+	SystemCode         string          `xml:"sac:SUNATPerceptionSystemCode"`
+	Percent            decimal.Decimal `xml:"sac:SUNATPerceptionPercent"`
+	TotalInvoiceAmount MonetaryAmount  `xml:"cbc:TotalInvoiceAmount"`
+	TotalCashed        MonetaryAmount  `xml:"sac:SUNATTotalCashed"`
+	TaxableAmount      MonetaryAmount  `xml:"cbc:TaxableAmount"`
+}
+
+// perceptionSystemCodes relaciona los códigos de percepción del catálogo 53
+// con el régimen de percepción (catálogo 22)
+var perceptionSystemCodes = map[models.AllowanceChargeCode]string{
+	models.PerceptionInternalSale: "01",
+	models.PerceptionFuel:         "02",
+	models.PerceptionSpecialRate:  "03",
+}
+
+// SummaryAllowanceCharge informa el total de los cargos del comprobante
+type SummaryAllowanceCharge struct {
+	ChargeIndicator bool           `xml:"cbc:ChargeIndicator"`
+	Amount          MonetaryAmount `xml:"cbc:Amount"`
+}
+
+type SummaryCustomerParty struct {
+	CustomerAssignedAccountID string `xml:"cbc:CustomerAssignedAccountID"`
+	AdditionalAccountID       string `xml:"cbc:AdditionalAccountID"`
+}
+
+type SummaryStatus struct {
+	ConditionCode string `xml:"cbc:ConditionCode"`
+}
+
+// SummaryBillingPayment informa el importe del comprobante por tipo de
+// operación: 01 gravado, 02 exonerado, 03 inafecto, 04 exportación,
+// 05 gratuito
+type SummaryBillingPayment struct {
+	PaidAmount    MonetaryAmount `xml:"cbc:PaidAmount"`
+	InstructionID string         `xml:"cbc:InstructionID"`
+}
+
+type SummaryTaxTotal struct {
+	TaxAmount   MonetaryAmount     `xml:"cbc:TaxAmount"`
+	TaxSubtotal SummaryTaxSubtotal `xml:"cac:TaxSubtotal"`
+}
+
+type SummaryTaxSubtotal struct {
+	TaxAmount   MonetaryAmount     `xml:"cbc:TaxAmount"`
+	TaxCategory SummaryTaxCategory `xml:"cac:TaxCategory"`
+}
+
+type SummaryTaxCategory struct {
+	TaxScheme TaxScheme `xml:"cac:TaxScheme"`
+}
+
+// SummaryItem es un comprobante a informar con su estado
+type SummaryItem struct {
+	Document  *models.Document
+	Condition models.SummaryCondition
+}
+
+// GenerateSummaryDocumentsXML arma el resumen diario. Todos los documentos
+// deben tener la fecha de emisión del resumen (ReferenceDate).
+func GenerateSummaryDocumentsXML(ticket *models.Ticket, items []SummaryItem, issuer *models.Company) (*SummaryDocuments, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("at least one document is required")
+	}
+
+	summary := &SummaryDocuments{
+		Xmlns:    "urn:sunat:names:specification:ubl:peru:schema:xsd:SummaryDocuments-1",
+		XmlnsCac: "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2",
+		XmlnsCbc: "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2",
+		XmlnsDs:  "http://www.w3.org/2000/09/xmldsig#",
+		XmlnsExt: "urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2",
+		XmlnsSac: "urn:sunat:names:specification:ubl:peru:schema:xsd:SunatAggregateComponents-1",
+
+		UBLVersionID:            "2.0",
+		CustomizationID:         "1.1",
+		ID:                      ticket.SummaryID,
+		ReferenceDate:           ticket.ReferenceDate.Format("2006-01-02"),
+		IssueDate:               ticket.IssueDate.Format("2006-01-02"),
+		Signature:               summarySignature(ticket.SummaryID, issuer),
+		AccountingSupplierParty: summarySupplierParty(issuer),
+	}
+
+	for i, item := range items {
+		doc := item.Document
+		if !doc.IssueDate.Equal(ticket.ReferenceDate) {
+			return nil, fmt.Errorf("document %s was not issued on %s", doc.ID, summary.ReferenceDate)
+		}
+
+		line := SummaryDocumentsLine{
+			LineID:           fmt.Sprintf("%d", i+1),
+			DocumentTypeCode: string(doc.Type),
+			ID:               fmt.Sprintf("%s-%s", doc.Serie, doc.Number),
+			AccountingCustomerParty: SummaryCustomerParty{
+				CustomerAssignedAccountID: summaryCustomerID(doc.Customer),
+				AdditionalAccountID:       summaryCustomerType(doc.Customer),
+			},
+			Status: SummaryStatus{ConditionCode: string(item.Condition)},
+			TotalAmount: MonetaryAmount{
+				CurrencyID: doc.CurrencyCode,
+				Value:      doc.TotalAmount,
+			},
+			BillingPayment: summaryBillingPayments(doc),
+			TaxTotal:       summaryTaxTotals(doc),
+		}
+
+		if perception := doc.Perception(); perception != nil {
+			line.Perception = &SummaryPerception{
+				SystemCode:         perceptionSystemCodes[perception.Code],
+				Percent:            perception.Code.Factor().Mul(decimal.Hundred).Round(decimal.AmountPlaces),
+				TotalInvoiceAmount: MonetaryAmount{CurrencyID: "PEN", Value: perception.Amount},
+				TotalCashed:        MonetaryAmount{CurrencyID: "PEN", Value: doc.TotalAmount.Add(perception.Amount)},
+				TaxableAmount:      MonetaryAmount{CurrencyID: "PEN", Value: perception.BaseAmount},
+			}
+			if perception.Factor != nil {
+				line.Perception.Percent = perception.Factor.Mul(decimal.Hundred).Round(decimal.AmountPlaces)
+			}
+		}
+
+		if !doc.TotalCharges.IsZero() {
+			line.AllowanceCharge = &SummaryAllowanceCharge{
+				ChargeIndicator: true,
+				Amount:          MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: doc.TotalCharges},
+			}
+		}
+
+		// Las notas indican la boleta que modifican
+		if doc.Type == models.DocumentTypeNotaCredito || doc.Type == models.DocumentTypeNotaDebito {
+			if len(doc.RelatedDocuments) == 0 {
+				return nil, fmt.Errorf("note %s has no related document", doc.ID)
+			}
+			related := doc.RelatedDocuments[0]
+			line.BillingReference = &BillingReference{
+				InvoiceDocumentReference: InvoiceDocumentReference{
+					ID:               fmt.Sprintf("%s-%s", related.Serie, related.Number),
+					DocumentTypeCode: string(related.DocumentType),
+				},
+			}
+		}
+
+		summary.SummaryDocumentsLine = append(summary.SummaryDocumentsLine, line)
+	}
+
+	return summary, nil
+}
+
+// summaryBillingPayments informa el valor de venta por tipo de operación,
+// ajustado por los descuentos y cargos globales que afectan su base. El
+// gravado se informa siempre; los documentos guardados antes de separar los
+// totales por afectación lo informan todo como gravado.
+func summaryBillingPayments(doc *models.Document) []SummaryBillingPayment {
+	// Las ventas con IVAP se informan con las gravadas
+	taxed := doc.TotalTaxed.Add(doc.BaseAdjustment(models.AffectationTaxed)).Add(doc.TotalIVAP)
+	buckets := decimal.Sum(doc.TotalTaxed, doc.TotalIVAP, doc.TotalExonerated, doc.TotalUnaffected, doc.TotalExport, doc.TotalFree)
+	if buckets.IsZero() {
+		taxed = doc.SubTotal
+	}
+
+	payments := []SummaryBillingPayment{
+		{PaidAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: taxed}, InstructionID: "01"},
+	}
+	others := []struct {
+		amount        decimal.Decimal
+		instructionID string
+	}{
+		{doc.TotalExonerated.Add(doc.BaseAdjustment(models.AffectationExonerated)), "02"},
+		{doc.TotalUnaffected.Add(doc.BaseAdjustment(models.AffectationUnaffected)), "03"},
+		{doc.TotalExport, "04"},
+		{doc.TotalFree, "05"},
+	}
+	for _, other := range others {
+		if !other.amount.IsZero() {
+			payments = append(payments, SummaryBillingPayment{
+				PaidAmount:    MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: other.amount},
+				InstructionID: other.instructionID,
+			})
+		}
+	}
+
+	return payments
+}
+
+// summaryTaxTotals agrupa los impuestos de las líneas por tributo. El IGV se
+// informa siempre, aunque su importe sea cero; el de las líneas 17 se
+// informa como IVAP. Los impuestos de las líneas gratuitas no se cobran y
+// no se informan.
+func summaryTaxTotals(doc *models.Document) []SummaryTaxTotal {
+	igv := summaryScheme(models.DocumentLine{}, models.Tax{Type: models.TaxTypeIGV})
+	amounts := map[taxScheme]decimal.Decimal{igv: decimal.Zero}
+	for _, line := range doc.Lines {
+		if line.Affectation().Free() {
+			continue
+		}
+		for _, tax := range line.Taxes {
+			scheme := summaryScheme(line, tax)
+			amounts[scheme] = amounts[scheme].Add(tax.Amount)
+		}
+	}
+	// Con descuentos o cargos globales sobre la base gravada, el IGV se
+	// calcula sobre la base ajustada
+	if !doc.BaseAdjustment(models.AffectationTaxed).IsZero() {
+		_, amounts[igv] = doc.IGVTotals()
+	}
+
+	schemes := make([]taxScheme, 0, len(amounts))
+	for scheme := range amounts {
+		schemes = append(schemes, scheme)
+	}
+	sort.Slice(schemes, func(i, j int) bool {
+		return schemes[i].ID < schemes[j].ID
+	})
+
+	var totals []SummaryTaxTotal
+	for _, scheme := range schemes {
+		amount := MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: amounts[scheme]}
+		totals = append(totals, SummaryTaxTotal{
+			TaxAmount: amount,
+			TaxSubtotal: SummaryTaxSubtotal{
+				TaxAmount: amount,
+				TaxCategory: SummaryTaxCategory{
+					TaxScheme: TaxScheme{
+						ID:          IDType{Value: scheme.ID},
+						Name:        scheme.Name,
+						TaxTypeCode: scheme.TypeCode,
+					},
+				},
+			},
+		})
+	}
+
+	return totals
+}
+
+// summaryScheme devuelve el tributo con el que el resumen informa un
+// impuesto de la línea
+func summaryScheme(line models.DocumentLine, tax models.Tax) taxScheme {
+	if tax.Type == models.TaxTypeIGV && line.Affectation() == models.AffectationIVAP {
+		return igvScheme(models.AffectationIVAP)
+	}
+	return taxScheme{ID: getTaxSchemeID(tax.Type), Name: getTaxSchemeName(tax.Type), TypeCode: getTaxTypeCode(tax.Type), Category: "S"}
+}
+
+// summaryCustomerID devuelve el documento del adquirente; las boletas sin
+// adquirente identificado se informan con "-"
+func summaryCustomerID(customer models.Company) string {
+	if customer.DocumentNumber == "" {
+		return "-"
+	}
+	return customer.DocumentNumber
+}
+
+func summaryCustomerType(customer models.Company) string {
+	if customer.DocumentType == "" || customer.DocumentNumber == "" {
+		return "0"
+	}
+	return customer.DocumentType
+}
+
+func getTaxTypeCode(taxType models.TaxType) string {
+	switch taxType {
+	case models.TaxTypeIGV:
+		return "VAT"
+	case models.TaxTypeISC:
+		return "EXC"
+	default:
+		return "OTH"
+	}
+}
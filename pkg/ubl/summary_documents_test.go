@@ -0,0 +1,41 @@
+package ubl
+
+import (
+	"testing"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// Una boleta con una línea gravada con IGV y otra con IVAP informa los dos
+// tributos por separado y suma ambas ventas a las gravadas
+func TestSummaryIVAP(t *testing.T) {
+	d := decimal.MustParse
+	doc := &models.Document{
+		CurrencyCode: "PEN",
+		Lines: []models.DocumentLine{
+			{ID: "1", TotalPrice: d("100.00"), IGVAffectation: models.AffectationTaxed,
+				Taxes: []models.Tax{{Type: models.TaxTypeIGV, Rate: d("18"), Amount: d("18.00"), TaxableAmount: d("100.00")}}},
+			{ID: "2", TotalPrice: d("200.00"), IGVAffectation: models.AffectationIVAP,
+				Taxes: []models.Tax{{Type: models.TaxTypeIGV, Rate: d("4"), Amount: d("8.00"), TaxableAmount: d("200.00")}}},
+		},
+	}
+	if err := doc.SetTotals(); err != nil {
+		t.Fatalf("SetTotals: %v", err)
+	}
+
+	var got []string
+	for _, total := range summaryTaxTotals(doc) {
+		scheme := total.TaxSubtotal.TaxCategory.TaxScheme
+		got = append(got, scheme.ID.Value+" "+scheme.Name+" "+total.TaxAmount.Value.String())
+	}
+	want := []string{"1000 IGV 18.00", "1016 IVAP 8.00"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("summaryTaxTotals = %v, want %v", got, want)
+	}
+
+	payments := summaryBillingPayments(doc)
+	if len(payments) != 1 || payments[0].InstructionID != "01" || payments[0].PaidAmount.Value.String() != "300.00" {
+		t.Errorf("summaryBillingPayments = %+v, want 01 300.00", payments)
+	}
+}
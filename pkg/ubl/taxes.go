@@ -0,0 +1,260 @@
+package ubl
+
+import (
+	"sort"
+
+	"infac/internal/models"
+	"infac/pkg/decimal"
+)
+
+// taxScheme identifica el tributo con el que se informa un impuesto
+// (catálogo 05) y su categoría (UN/ECE 5305)
+type taxScheme struct {
+	ID       string
+	Name     string
+	TypeCode string
+	Category string
+}
+
+// igvScheme devuelve el tributo con el que se informa el IGV de la línea
+// según su afectación (catálogo 07)
+func igvScheme(affectation models.IGVAffectation) taxScheme {
+	switch {
+	case affectation.Free():
+		return taxScheme{ID: "9996", Name: "GRA", TypeCode: "FRE", Category: "Z"}
+	case affectation == models.AffectationIVAP:
+		return taxScheme{ID: "1016", Name: "IVAP", TypeCode: "VAT", Category: "S"}
+	case affectation == models.AffectationExonerated:
+		return taxScheme{ID: "9997", Name: "EXO", TypeCode: "VAT", Category: "E"}
+	case affectation == models.AffectationUnaffected:
+		return taxScheme{ID: "9998", Name: "INA", TypeCode: "FRE", Category: "O"}
+	case affectation == models.AffectationExport:
+		return taxScheme{ID: "9995", Name: "EXP", TypeCode: "FRE", Category: "G"}
+	default:
+		return taxScheme{ID: "1000", Name: "IGV", TypeCode: "VAT", Category: "S"}
+	}
+}
+
+// schemeOf devuelve el tributo de un impuesto de la línea
+func schemeOf(line models.DocumentLine, tax models.Tax) taxScheme {
+	if tax.Type == models.TaxTypeIGV {
+		return igvScheme(line.Affectation())
+	}
+	return taxScheme{ID: getTaxSchemeID(tax.Type), Name: getTaxSchemeName(tax.Type), TypeCode: getTaxTypeCode(tax.Type), Category: "S"}
+}
+
+// taxableAmount devuelve la base imponible del tributo. Los documentos
+// guardados antes de calcular la base por tributo usan el valor de venta.
+func taxableAmount(line models.DocumentLine, tax models.Tax) decimal.Decimal {
+	if tax.TaxableAmount.IsZero() {
+		return line.TaxableAmount
+	}
+	return tax.TaxableAmount
+}
+
+func (s taxScheme) TaxScheme() TaxScheme {
+	return TaxScheme{
+		ID: IDType{
+			SchemeID:         "UN/ECE 5153",
+			SchemeName:       "Codigo de tributos",
+			SchemeAgencyName: "PE:SUNAT",
+			Value:            s.ID,
+		},
+		Name:        s.Name,
+		TaxTypeCode: s.TypeCode,
+	}
+}
+
+func (s taxScheme) CategoryID() IDType {
+	return IDType{
+		SchemeID:         "UN/ECE 5305",
+		SchemeName:       "Tax Category Identifier",
+		SchemeAgencyName: "United Nations Economic Commission for Europe",
+		Value:            s.Category,
+	}
+}
+
+// lineTaxes devuelve los impuestos de la línea. Toda línea informa su
+// afectación al IGV, aunque el request no incluya el IGV.
+func lineTaxes(line models.DocumentLine) []models.Tax {
+	for _, tax := range line.Taxes {
+		if tax.Type == models.TaxTypeIGV {
+			return line.Taxes
+		}
+	}
+
+	igv := models.Tax{Type: models.TaxTypeIGV, Rate: decimal.Zero, Amount: decimal.Zero.Round(decimal.AmountPlaces)}
+	return append([]models.Tax{igv}, line.Taxes...)
+}
+
+// lineTaxTotal arma el TaxTotal de la línea con un TaxSubtotal por impuesto
+func lineTaxTotal(doc *models.Document, line models.DocumentLine) TaxTotal {
+	total := TaxTotal{TaxAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: decimal.Zero}}
+
+	for _, tax := range lineTaxes(line) {
+		scheme := schemeOf(line, tax)
+		subtotal := TaxSubtotal{
+			TaxAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: tax.Amount},
+			TaxCategory: TaxCategory{
+				ID:        scheme.CategoryID(),
+				Percent:   percent(tax.Rate),
+				TaxScheme: scheme.TaxScheme(),
+			},
+		}
+
+		switch tax.Type {
+		case models.TaxTypeICBP:
+			// Monto por bolsa y cantidad de bolsas, sin base imponible
+			subtotal.BaseUnitMeasure = &InvoicedQuantity{UnitCode: "NIU", Value: line.Quantity}
+			subtotal.TaxCategory.Percent = nil
+			subtotal.TaxCategory.PerUnitAmount = &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: tax.Rate}
+		case models.TaxTypeISC:
+			subtotal.TaxableAmount = &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: taxableAmount(line, tax)}
+			subtotal.TaxCategory.TierRange = string(tax.ISCSystem)
+		case models.TaxTypeIGV:
+			// La tasa del IGV se informa aunque la línea no esté gravada
+			rate := tax.Rate
+			subtotal.TaxableAmount = &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: taxableAmount(line, tax)}
+			subtotal.TaxCategory.Percent = &rate
+			subtotal.TaxCategory.TaxExemptionReasonCode = &TaxExemptionReasonCode{
+				ListAgencyName: "PE:SUNAT",
+				ListName:       "Afectacion del IGV",
+				ListURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo07",
+				Value:          string(line.Affectation()),
+			}
+		default:
+			subtotal.TaxableAmount = &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: taxableAmount(line, tax)}
+		}
+
+		total.TaxAmount.Value = total.TaxAmount.Value.Add(tax.Amount)
+		total.TaxSubtotal = append(total.TaxSubtotal, subtotal)
+	}
+
+	return total
+}
+
+// documentTaxTotal agrupa los impuestos de las líneas por tributo: IGV
+// (gravadas), IVAP, exoneradas, inafectas, exportación y gratuitas, más los demás
+// tributos. El importe total es el de los impuestos cobrados; el IGV de las
+// operaciones gratuitas solo se informa en su grupo (9996).
+func documentTaxTotal(doc *models.Document) TaxTotal {
+	groups := make(map[string]*TaxSubtotal)
+	for _, line := range doc.Lines {
+		for _, tax := range lineTaxes(line) {
+			if line.Affectation().Free() && tax.Type != models.TaxTypeIGV {
+				continue
+			}
+
+			scheme := schemeOf(line, tax)
+			group := groups[scheme.ID]
+			if group == nil {
+				group = &TaxSubtotal{
+					TaxAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: decimal.Zero},
+					TaxCategory: TaxCategory{
+						ID:        scheme.CategoryID(),
+						TaxScheme: scheme.TaxScheme(),
+					},
+				}
+				// El ICBPER no tiene base imponible
+				if tax.Type != models.TaxTypeICBP {
+					group.TaxableAmount = &MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: decimal.Zero}
+				}
+				groups[scheme.ID] = group
+			}
+			if group.TaxableAmount != nil {
+				group.TaxableAmount.Value = group.TaxableAmount.Value.Add(taxableAmount(line, tax))
+			}
+			group.TaxAmount.Value = group.TaxAmount.Value.Add(tax.Amount)
+		}
+	}
+
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Los descuentos y cargos globales que afectan la base imponible ajustan
+	// la base de su grupo; en las gravadas, el IGV se calcula sobre la base
+	// ajustada
+	if group := groups[igvScheme(models.AffectationTaxed).ID]; group != nil && !doc.BaseAdjustment(models.AffectationTaxed).IsZero() {
+		base, igv := doc.IGVTotals()
+		group.TaxableAmount.Value, group.TaxAmount.Value = base, igv
+	}
+	for _, affectation := range []models.IGVAffectation{models.AffectationExonerated, models.AffectationUnaffected} {
+		if group := groups[igvScheme(affectation).ID]; group != nil {
+			group.TaxableAmount.Value = group.TaxableAmount.Value.Add(doc.BaseAdjustment(affectation))
+		}
+	}
+
+	total := TaxTotal{TaxAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: doc.TotalTaxes}}
+	for _, id := range ids {
+		group := groups[id]
+		if group.TaxableAmount != nil {
+			group.TaxableAmount.Value = group.TaxableAmount.Value.Round(decimal.AmountPlaces)
+		}
+		group.TaxAmount.Value = group.TaxAmount.Value.Round(decimal.AmountPlaces)
+		total.TaxSubtotal = append(total.TaxSubtotal, *group)
+	}
+
+	return total
+}
+
+// linePrice devuelve el precio unitario de la línea y su referencia. Las
+// líneas gratuitas tienen precio cero y se informan con su valor referencial
+// unitario (tipo de precio 02); las demás, con el precio unitario que
+// incluye los impuestos (01).
+func linePrice(doc *models.Document, line models.DocumentLine) (Price, PricingReference) {
+	priceType, reference := "01", priceWithTaxes(line)
+	price := line.UnitPrice
+	if line.Affectation().Free() {
+		priceType, reference = "02", line.UnitPrice
+		price = decimal.Zero.Round(decimal.AmountPlaces)
+	}
+
+	return Price{PriceAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: price}},
+		PricingReference{
+			AlternativeConditionPrice: []AlternativeConditionPrice{
+				{
+					PriceAmount: MonetaryAmount{CurrencyID: doc.CurrencyCode, Value: reference},
+					PriceTypeCode: PriceTypeCode{
+						ListName:       "Tipo de Precio",
+						ListAgencyName: "PE:SUNAT",
+						ListURI:        "urn:pe:gob:sunat:cpe:see:gem:catalogos:catalogo16",
+						Value:          priceType,
+					},
+				},
+			},
+		}
+}
+
+// legends devuelve las leyendas del comprobante (catálogo 52): el importe en
+// letras y, si corresponden, las de transferencia gratuita, operación sujeta
+// a detracción y comprobante de percepción
+func legends(doc *models.Document) []Note {
+	notes := []Note{
+		{LanguageLocaleID: "1000", Value: convertAmountToWords(doc.TotalAmount, doc.CurrencyCode)},
+	}
+	if hasFreeLines(doc) {
+		notes = append(notes, Note{
+			LanguageLocaleID: "1002",
+			Value:            "TRANSFERENCIA GRATUITA DE UN BIEN Y/O SERVICIO PRESTADO GRATUITAMENTE",
+		})
+	}
+	if doc.Detraction != nil {
+		notes = append(notes, Note{LanguageLocaleID: "2006", Value: "Operación sujeta a detracción"})
+	}
+	if doc.Perception() != nil {
+		notes = append(notes, Note{LanguageLocaleID: "2000", Value: "COMPROBANTE DE PERCEPCIÓN"})
+	}
+	return notes
+}
+
+func hasFreeLines(doc *models.Document) bool {
+	for _, line := range doc.Lines {
+		if line.Affectation().Free() {
+			return true
+		}
+	}
+	return false
+}
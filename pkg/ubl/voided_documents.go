@@ -0,0 +1,125 @@
+package ubl
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"infac/internal/models"
+)
+
+// VoidedDocuments es la Comunicación de Baja (RA) con la que se anulan
+// facturas y notas asociadas ya aceptadas por SUNAT
+type VoidedDocuments struct {
+	XMLName  xml.Name `xml:"VoidedDocuments"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	XmlnsCac string   `xml:"xmlns:cac,attr"`
+	XmlnsCbc string   `xml:"xmlns:cbc,attr"`
+	XmlnsDs  string   `xml:"xmlns:ds,attr"`
+	XmlnsExt string   `xml:"xmlns:ext,attr"`
+	XmlnsSac string   `xml:"xmlns:sac,attr"`
+
+	UBLExtensions           UBLExtensions         `xml:"ext:UBLExtensions"`
+	UBLVersionID            string                `xml:"cbc:UBLVersionID"`
+	CustomizationID         string                `xml:"cbc:CustomizationID"`
+	ID                      string                `xml:"cbc:ID"`
+	ReferenceDate           string                `xml:"cbc:ReferenceDate"`
+	IssueDate               string                `xml:"cbc:IssueDate"`
+	Signature               []Signature           `xml:"cac:Signature"`
+	AccountingSupplierParty SummarySupplierParty  `xml:"cac:AccountingSupplierParty"`
+	VoidedDocumentsLine     []VoidedDocumentsLine `xml:"sac:VoidedDocumentsLine"`
+}
+
+// SummarySupplierParty es el formato de emisor que usan los resúmenes (RA/RC)
+type SummarySupplierParty struct {
+	CustomerAssignedAccountID string       `xml:"cbc:CustomerAssignedAccountID"`
+	AdditionalAccountID       string       `xml:"cbc:AdditionalAccountID"`
+	Party                     SummaryParty `xml:"cac:Party"`
+}
+
+type SummaryParty struct {
+	PartyLegalEntity PartyLegalEntity `xml:"cac:PartyLegalEntity"`
+}
+
+type VoidedDocumentsLine struct {
+	LineID                string `xml:"cbc:LineID"`
+	DocumentTypeCode      string `xml:"cbc:DocumentTypeCode"`
+	DocumentSerialID      string `xml:"sac:DocumentSerialID"`
+	DocumentNumberID      string `xml:"sac:DocumentNumberID"`
+	VoidReasonDescription string `xml:"sac:VoidReasonDescription"`
+}
+
+// VoidedItem es un documento a dar de baja con su motivo
+type VoidedItem struct {
+	Document *models.Document
+	Reason   string
+}
+
+// GenerateVoidedDocumentsXML arma la comunicación de baja. Todos los
+// documentos deben tener la misma fecha de emisión (ReferenceDate).
+func GenerateVoidedDocumentsXML(ticket *models.Ticket, items []VoidedItem, issuer *models.Company) (*VoidedDocuments, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("at least one document is required")
+	}
+
+	voided := &VoidedDocuments{
+		Xmlns:    "urn:sunat:names:specification:ubl:peru:schema:xsd:VoidedDocuments-1",
+		XmlnsCac: "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2",
+		XmlnsCbc: "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2",
+		XmlnsDs:  "http://www.w3.org/2000/09/xmldsig#",
+		XmlnsExt: "urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2",
+		XmlnsSac: "urn:sunat:names:specification:ubl:peru:schema:xsd:SunatAggregateComponents-1",
+
+		UBLVersionID:            "2.0",
+		CustomizationID:         "1.0",
+		ID:                      ticket.SummaryID,
+		ReferenceDate:           ticket.ReferenceDate.Format("2006-01-02"),
+		IssueDate:               ticket.IssueDate.Format("2006-01-02"),
+		Signature:               summarySignature(ticket.SummaryID, issuer),
+		AccountingSupplierParty: summarySupplierParty(issuer),
+	}
+
+	for i, item := range items {
+		voided.VoidedDocumentsLine = append(voided.VoidedDocumentsLine, VoidedDocumentsLine{
+			LineID:                fmt.Sprintf("%d", i+1),
+			DocumentTypeCode:      string(item.Document.Type),
+			DocumentSerialID:      item.Document.Serie,
+			DocumentNumberID:      item.Document.Number,
+			VoidReasonDescription: item.Reason,
+		})
+	}
+
+	return voided, nil
+}
+
+func summarySignature(id string, issuer *models.Company) []Signature {
+	return []Signature{
+		{
+			ID: id,
+			SignatoryParty: SignatoryParty{
+				PartyIdentification: PartyIdentification{
+					ID: IDType{Value: issuer.DocumentNumber},
+				},
+				PartyName: PartyName{
+					Name: issuer.Name,
+				},
+			},
+			DigitalSignatureAttachment: DigitalSignatureAttachment{
+				ExternalReference: ExternalReference{
+					URI: fmt.Sprintf("#%s", id),
+				},
+			},
+		},
+	}
+}
+
+func summarySupplierParty(issuer *models.Company) SummarySupplierParty {
+	return SummarySupplierParty{
+		CustomerAssignedAccountID: issuer.DocumentNumber,
+		AdditionalAccountID:       getDocumentTypeScheme(issuer.DocumentType),
+		Party: SummaryParty{
+			PartyLegalEntity: PartyLegalEntity{
+				RegistrationName: issuer.Name,
+			},
+		},
+	}
+}